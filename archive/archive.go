@@ -0,0 +1,138 @@
+// Package archive reads WSDL documents and schemas from zip and tar
+// archives, without unpacking them.
+//
+// A document in an archive is located by the location of the archive,
+// a file path or URL, and the path of the document in the archive,
+// separated by '#', as in contract.zip#wsdl/Service.wsdl. Relative
+// locations of imports in such documents are resolved in the archive,
+// like file paths.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// extensions of the supported archives.
+var extensions = []string{".zip", ".jar", ".tar", ".tar.gz", ".tgz"}
+
+// Split splits the location of a document in an archive into the
+// location of the archive and the path of the document in it. It
+// returns false if loc is not in an archive.
+func Split(loc string) (archive, name string, ok bool) {
+	for i := 0; i < len(loc); i++ {
+		if loc[i] != '#' {
+			continue
+		}
+		if isArchive(loc[:i]) {
+			return loc[:i], loc[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+func isArchive(loc string) bool {
+	if u, err := url.Parse(loc); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		loc = u.Path
+	}
+	loc = strings.ToLower(loc)
+	for _, ext := range extensions {
+		if strings.HasSuffix(loc, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Join returns the location of loc relative to base, a document in an
+// archive, and whether base is in an archive. Absolute URLs and paths
+// are not in the archive.
+func Join(base, loc string) (string, bool) {
+	archive, name, ok := Split(base)
+	if !ok {
+		return "", false
+	}
+	if u, err := url.Parse(loc); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		return loc, true
+	}
+	if strings.HasPrefix(loc, "/") || filepath.IsAbs(loc) {
+		return loc, true
+	}
+	if strings.HasPrefix(loc, "?") {
+		// a query alone refers to the same document
+		return archive + "#" + strings.SplitN(name, "?", 2)[0] + loc, true
+	}
+	return archive + "#" + path.Join(path.Dir(name), filepath.ToSlash(loc)), true
+}
+
+// Read returns the content of the document name in the archive, whose
+// content is data. The format of the archive is that of the extension
+// of its location.
+func Read(archive string, data []byte, name string) ([]byte, error) {
+	name = path.Clean(strings.TrimPrefix(filepath.ToSlash(name), "/"))
+	lower := strings.ToLower(archive)
+	var b []byte
+	var err error
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".jar"):
+		b, err = readZip(data, name)
+	case strings.HasSuffix(lower, ".tar"):
+		b, err = readTar(bytes.NewReader(data), name)
+	default:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(data)); err == nil {
+			b, err = readTar(r, name)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", archive, err)
+	}
+	if b == nil {
+		return nil, fmt.Errorf("%s: %s not found in archive", archive, name)
+	}
+	return b, nil
+}
+
+func readZip(data []byte, name string) ([]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range r.File {
+		if path.Clean(f.Name) != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return ioutil.ReadAll(rc)
+	}
+	return nil, nil
+}
+
+func readTar(r io.Reader, name string) ([]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeDir && path.Clean(strings.TrimPrefix(h.Name, "./")) == name {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+var files = map[string]string{
+	"wsdl/Service.wsdl":  "<definitions/>",
+	"xsd/types.xsd":      "<schema/>",
+	"xsd/Service?xsd=1":  "<schema>1</schema>",
+	"xsd/common/abc.xsd": "<schema>abc</schema>",
+}
+
+func zipData(t *testing.T) []byte {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func tarData(t *testing.T, compress bool) []byte {
+	var b bytes.Buffer
+	var tw *tar.Writer
+	var gw *gzip.Writer
+	if compress {
+		gw = gzip.NewWriter(&b)
+		tw = tar.NewWriter(gw)
+	} else {
+		tw = tar.NewWriter(&b)
+	}
+	for name, content := range files {
+		h := &tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(content))}
+		if err := tw.WriteHeader(h); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gw != nil {
+		gw.Close()
+	}
+	return b.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	archives := map[string][]byte{
+		"contract.zip":    zipData(t),
+		"contract.tar":    tarData(t, false),
+		"contract.tar.gz": tarData(t, true),
+	}
+	for a, data := range archives {
+		for name, want := range files {
+			b, err := Read(a, data, name)
+			if err != nil {
+				t.Errorf("%s: %v", a, err)
+				continue
+			}
+			if string(b) != want {
+				t.Errorf("%s#%s: want %q, have %q", a, name, want, b)
+			}
+		}
+		if _, err := Read(a, data, "missing.xsd"); err == nil {
+			t.Errorf("%s: missing file was read", a)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		Loc, Archive, Name string
+		OK                 bool
+	}{
+		{"contract.zip#wsdl/Service.wsdl", "contract.zip", "wsdl/Service.wsdl", true},
+		{"dir/Contract.TGZ#a.wsdl", "dir/Contract.TGZ", "a.wsdl", true},
+		{"http://example.com/c.tar.gz#a.wsdl", "http://example.com/c.tar.gz", "a.wsdl", true},
+		{"http://example.com/a.wsdl#frag", "", "", false},
+		{"contract.zip", "", "", false},
+	}
+	for _, c := range cases {
+		a, name, ok := Split(c.Loc)
+		if a != c.Archive || name != c.Name || ok != c.OK {
+			t.Errorf("split %q: want %q %q %v, have %q %q %v", c.Loc, c.Archive, c.Name, c.OK, a, name, ok)
+		}
+	}
+}
+
+func TestJoin(t *testing.T) {
+	cases := []struct {
+		Base, Loc, Want string
+	}{
+		{"c.zip#wsdl/Service.wsdl", "../xsd/types.xsd", "c.zip#xsd/types.xsd"},
+		{"c.zip#xsd/types.xsd", "common/abc.xsd", "c.zip#xsd/common/abc.xsd"},
+		{"c.zip#xsd/Service?xsd=1", "?xsd=2", "c.zip#xsd/Service?xsd=2"},
+		{"c.zip#Service.wsdl", "http://example.com/a.xsd", "http://example.com/a.xsd"},
+	}
+	for _, c := range cases {
+		if have, _ := Join(c.Base, c.Loc); have != c.Want {
+			t.Errorf("join %q to %q: want %q, have %q", c.Loc, c.Base, c.Want, have)
+		}
+	}
+	if _, ok := Join("dir/Service.wsdl", "a.xsd"); ok {
+		t.Error("base is not in an archive")
+	}
+}
@@ -0,0 +1,220 @@
+// Package catalog resolves the locations of documents with OASIS XML
+// Catalogs, so that WSDL documents and schemas referenced by URL can be
+// loaded from local copies.
+//
+// The uri, system, rewriteURI, rewriteSystem, uriSuffix, systemSuffix
+// and nextCatalog entries are supported, in groups or not, with xml:base.
+// System identifiers are URIs too, so both kinds of entries apply to all
+// locations. Delegate entries are ignored.
+package catalog
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Namespace is the namespace of OASIS XML Catalogs.
+const Namespace = "urn:oasis:names:tc:entity:xmlns:xml:catalog"
+
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// Catalog maps the locations of documents to other locations.
+type Catalog struct {
+	exact    map[string]string
+	rewrites []*entry
+	suffixes []*entry
+	next     []*Catalog
+}
+
+// entry maps locations that start or end with match.
+type entry struct {
+	match, target string
+}
+
+// Load loads the catalog file name, and the catalogs it refers to with
+// nextCatalog entries.
+func Load(name string) (*Catalog, error) {
+	return load(name, make(map[string]bool))
+}
+
+func load(name string, seen map[string]bool) (*Catalog, error) {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	if seen[abs] {
+		return &Catalog{}, nil
+	}
+	seen[abs] = true
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, next, err := parse(f, name)
+	if err != nil {
+		return nil, fmt.Errorf("catalog %s: %v", name, err)
+	}
+	for _, name := range next {
+		nc, err := load(name, seen)
+		if err != nil {
+			return nil, err
+		}
+		c.next = append(c.next, nc)
+	}
+	return c, nil
+}
+
+// Parse parses a catalog from r. Relative locations in the catalog are
+// relative to base, the location of the catalog. The catalogs of
+// nextCatalog entries are not loaded.
+func Parse(r io.Reader, base string) (*Catalog, error) {
+	c, _, err := parse(r, base)
+	return c, err
+}
+
+// parse parses a catalog, and returns the locations of its next
+// catalogs.
+func parse(r io.Reader, base string) (*Catalog, []string, error) {
+	c := &Catalog{exact: make(map[string]string)}
+	var next []string
+	bases := []string{base}
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			base := bases[len(bases)-1]
+			if v := attr(t, xmlNamespace, "base"); v != "" {
+				base = Join(base, v)
+			}
+			bases = append(bases, base)
+			if t.Name.Space != Namespace {
+				continue
+			}
+			switch t.Name.Local {
+			case "uri":
+				c.exact[attr(t, "", "name")] = Join(base, attr(t, "", "uri"))
+			case "system":
+				c.exact[attr(t, "", "systemId")] = Join(base, attr(t, "", "uri"))
+			case "rewriteURI":
+				c.rewrites = append(c.rewrites, &entry{attr(t, "", "uriStartString"), Join(base, attr(t, "", "rewritePrefix"))})
+			case "rewriteSystem":
+				c.rewrites = append(c.rewrites, &entry{attr(t, "", "systemIdStartString"), Join(base, attr(t, "", "rewritePrefix"))})
+			case "uriSuffix":
+				c.suffixes = append(c.suffixes, &entry{attr(t, "", "uriSuffix"), Join(base, attr(t, "", "uri"))})
+			case "systemSuffix":
+				c.suffixes = append(c.suffixes, &entry{attr(t, "", "systemSuffix"), Join(base, attr(t, "", "uri"))})
+			case "nextCatalog":
+				next = append(next, Join(base, attr(t, "", "catalog")))
+			}
+		case xml.EndElement:
+			bases = bases[:len(bases)-1]
+		}
+	}
+	return c, next, nil
+}
+
+func attr(t xml.StartElement, space, local string) string {
+	for _, a := range t.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Resolve returns the location that the catalog maps loc to, and
+// whether it does. Exact matches take precedence over the longest
+// matching rewrite prefix, then the longest matching suffix, and then
+// the next catalogs.
+func (c *Catalog) Resolve(loc string) (string, bool) {
+	if c == nil {
+		return loc, false
+	}
+	if v, ok := c.exact[loc]; ok {
+		return v, true
+	}
+	var match *entry
+	for _, e := range c.rewrites {
+		if strings.HasPrefix(loc, e.match) && (match == nil || len(e.match) > len(match.match)) {
+			match = e
+		}
+	}
+	if match != nil {
+		return match.target + loc[len(match.match):], true
+	}
+	for _, e := range c.suffixes {
+		if strings.HasSuffix(loc, e.match) && (match == nil || len(e.match) > len(match.match)) {
+			match = e
+		}
+	}
+	if match != nil {
+		return match.target, true
+	}
+	for _, nc := range c.next {
+		if v, ok := nc.Resolve(loc); ok {
+			return v, true
+		}
+	}
+	return loc, false
+}
+
+// Join returns the location of loc, a file path or URL, relative to
+// base. A trailing slash of loc is kept, for rewrite prefixes.
+func Join(base, loc string) string {
+	u, err := url.Parse(loc)
+	if err != nil || u.IsAbs() || filepath.IsAbs(loc) || base == "" {
+		return loc
+	}
+	b, err := url.Parse(base)
+	if err == nil && b.IsAbs() {
+		return b.ResolveReference(u).String()
+	}
+	v := filepath.Join(filepath.Dir(base), filepath.FromSlash(loc))
+	if strings.HasSuffix(loc, "/") {
+		v += string(filepath.Separator)
+	}
+	return v
+}
+
+// Entry maps the location Name to URI.
+type Entry struct {
+	Name, URI string
+}
+
+// Write writes a catalog of uri entries to w.
+func Write(w io.Writer, entries []Entry) error {
+	type uri struct {
+		Name string `xml:"name,attr"`
+		URI  string `xml:"uri,attr"`
+	}
+	v := struct {
+		XMLName xml.Name `xml:"urn:oasis:names:tc:entity:xmlns:xml:catalog catalog"`
+		URIs    []uri    `xml:"uri"`
+	}{}
+	for _, e := range entries {
+		v.URIs = append(v.URIs, uri{e.Name, e.URI})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,66 @@
+package catalog
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	c, err := Load("testdata/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		Loc, Want string
+	}{
+		{"http://example.com/service.wsdl", "testdata/local/service.wsdl"},
+		{"http://example.com/types.xsd", "/opt/schemas/types.xsd"},
+		{"http://example.com/a/b.xsd", "testdata/mirror/a/b.xsd"},
+		{"http://example.com/schemas/c.xsd?xsd=1", "testdata/schemas/c.xsd?xsd=1"},
+		{"http://legacy.example.com/d.xsd", "http://mirror.example.org/base/legacy/d.xsd"},
+		{"http://other.example.com/v1/soap-envelope.xsd", "testdata/std/soap-envelope.xsd"},
+		{"urn:example:common", "testdata/next/common.xsd"},
+	}
+	for _, tc := range cases {
+		have, ok := c.Resolve(tc.Loc)
+		if !ok || have != filepath.FromSlash(tc.Want) && have != tc.Want {
+			t.Errorf("resolve %q: want %q, have %q (%v)", tc.Loc, tc.Want, have, ok)
+		}
+	}
+	if have, ok := c.Resolve("http://other.example.com/x.xsd"); ok || have != "http://other.example.com/x.xsd" {
+		t.Errorf("unexpected match: %q", have)
+	}
+}
+
+func TestResolveNil(t *testing.T) {
+	var c *Catalog
+	if have, ok := c.Resolve("a.xsd"); ok || have != "a.xsd" {
+		t.Errorf("unexpected match: %q", have)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	entries := []Entry{
+		{"http://example.com/service.wsdl", "example.com/service.wsdl"},
+		{"http://example.com/service.svc?xsd=xsd0", "example.com/service.svc_xsd_xsd0.xml"},
+	}
+	if err := Write(&b, entries); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `xsd=xsd0`) {
+		t.Errorf("missing entry:\n%s", b.String())
+	}
+	c, err := Parse(&b, "out/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		want := filepath.Join("out", filepath.FromSlash(e.URI))
+		if have, ok := c.Resolve(e.Name); !ok || have != want {
+			t.Errorf("resolve %q: want %q, have %q", e.Name, want, have)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fiorix/wsdl2go/catalog"
+	"github.com/fiorix/wsdl2go/resolver"
+)
+
+// clientOptions are the command line options of the http client that
+// fetches remote documents.
+type clientOptions struct {
+	Insecure       bool
+	ClientCertFile string
+	ClientKeyFile  string
+	CACertFile     string
+	User           string
+	Token          string
+	Headers        headerFlags
+	AuthHosts      hostFlags
+	Timeout        time.Duration
+}
+
+// register defines the flags of the options in fs.
+func (o *clientOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Insecure, "yolo", o.Insecure, "accept invalid https certificates")
+	fs.StringVar(&o.ClientCertFile, "cert", o.ClientCertFile, "use client TLS cert file")
+	fs.StringVar(&o.ClientKeyFile, "key", o.ClientKeyFile, "use client TLS key file")
+	fs.StringVar(&o.CACertFile, "cacert", o.CACertFile, "trust the CA certificates of this PEM file too")
+	fs.StringVar(&o.User, "user", o.User, "user:password of HTTP basic authentication")
+	fs.StringVar(&o.Token, "token", o.Token, "bearer token of HTTP requests")
+	fs.Var(&o.Headers, "H", "'Name: value' header of HTTP requests, may be repeated")
+	fs.Var(&o.AuthHosts, "auth-host", "host that credentials and headers are sent to besides that of the input url, may be repeated")
+	fs.DurationVar(&o.Timeout, "timeout", o.Timeout, "time limit of each HTTP request, e.g. 30s")
+}
+
+// headerFlags are the values of repeated -H flags.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(v string) error {
+	if !strings.Contains(v, ":") {
+		return fmt.Errorf("invalid header %q, want 'Name: value'", v)
+	}
+	*h = append(*h, v)
+	return nil
+}
+
+// hostFlags are the values of repeated -auth-host flags.
+type hostFlags []string
+
+func (h *hostFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *hostFlags) Set(v string) error {
+	*h = append(*h, v)
+	return nil
+}
+
+// header returns the headers that the options add to requests.
+func (o *clientOptions) header() http.Header {
+	h := make(http.Header)
+	for _, v := range o.Headers {
+		kv := strings.SplitN(v, ":", 2)
+		h.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	if o.User != "" {
+		h.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(o.User)))
+	}
+	if o.Token != "" {
+		h.Set("Authorization", "Bearer "+o.Token)
+	}
+	return h
+}
+
+// headerTransport adds headers to the requests to hosts.
+type headerTransport struct {
+	hosts  map[string]bool
+	header http.Header
+	base   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.hosts[req.URL.Host] {
+		return t.base.RoundTrip(req)
+	}
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	for k, v := range t.header {
+		r.Header[k] = v
+	}
+	return t.base.RoundTrip(r)
+}
+
+// httpClient returns http client with the given options. Credentials
+// and headers are only sent to the host of src, if it is a URL, and the
+// hosts of -auth-host, so that they do not leak to the hosts of third
+// party imports. Inputs that are files or stdin send them to no host
+// but those of -auth-host.
+func httpClient(o clientOptions, src string) *http.Client {
+	tlsConfig := &tls.Config{InsecureSkipVerify: o.Insecure}
+
+	if o.ClientCertFile != "" && o.ClientKeyFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(o.ClientCertFile, o.ClientKeyFile)
+		if err != nil {
+			log.Fatalln("Failed to load x509 client key pair:", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+		tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
+	} else if o.ClientCertFile == "" && o.ClientKeyFile != "" {
+		log.Fatalln("Certificate file is required when using key file")
+	} else if o.ClientCertFile != "" && o.ClientKeyFile == "" {
+		log.Fatalln("Key file is required when using certificate file")
+	}
+
+	if o.CACertFile != "" {
+		pem, err := ioutil.ReadFile(o.CACertFile)
+		if err != nil {
+			log.Fatalln("Failed to read CA certificates:", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			log.Fatalln("No CA certificates found in", o.CACertFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	defaultTransport := http.DefaultTransport.(*http.Transport)
+	transport := &http.Transport{
+		Proxy:                 defaultTransport.Proxy,
+		DialContext:           defaultTransport.DialContext,
+		MaxIdleConns:          defaultTransport.MaxIdleConns,
+		IdleConnTimeout:       defaultTransport.IdleConnTimeout,
+		ExpectContinueTimeout: defaultTransport.ExpectContinueTimeout,
+		TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
+		TLSClientConfig:       tlsConfig,
+	}
+	cli := &http.Client{Transport: transport, Timeout: o.Timeout}
+	if h := o.header(); len(h) > 0 {
+		t := &headerTransport{hosts: make(map[string]bool), header: h, base: transport}
+		if u, err := url.Parse(src); err == nil && u.Host != "" {
+			t.hosts[u.Host] = true
+		}
+		for _, host := range o.AuthHosts {
+			t.hosts[host] = true
+		}
+		if len(t.hosts) == 0 {
+			log.Println("warning: credentials and headers are not sent, the input is not a url and no -auth-host is set")
+		}
+		cli.Transport = t
+	}
+	return cli
+}
+
+// newResolver returns the resolver of the input src and the documents
+// it refers to, which fetches them with the http client of o and reads
+// the local copies that the catalog file cat, if set, maps them to.
+func newResolver(o clientOptions, src, cat string) (*resolver.Resolver, error) {
+	r := &resolver.Resolver{Client: httpClient(o, src)}
+	if cat != "" {
+		var err error
+		if r.Catalog, err = catalog.Load(cat); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
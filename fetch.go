@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/catalog"
+	"github.com/fiorix/wsdl2go/wsdlgo"
+)
+
+// fetchWSDL downloads a WSDL document and the documents it imports and
+// includes, at any depth, into a directory, and writes a catalog.xml
+// there that maps their locations to the local copies. Generating code
+// with -catalog <dir>/catalog.xml then needs no network.
+func fetchWSDL(args []string) error {
+	var src, dir, cat string
+	var client clientOptions
+	fs := flag.NewFlagSet("fetch", flag.ExitOnError)
+	fs.StringVar(&src, "i", src, "input file, url, or archive#file")
+	fs.StringVar(&dir, "o", "wsdl", "output directory")
+	client.register(fs)
+	fs.StringVar(&cat, "catalog", cat, "XML catalog file that maps the locations of documents to local copies")
+	fs.Parse(args)
+	if src == "" || src == "-" {
+		return errors.New("fetch: missing input file or url (-i)")
+	}
+
+	r, err := newResolver(client, src, cat)
+	if err != nil {
+		return err
+	}
+	var entries []catalog.Entry
+	taken := make(map[string]bool)
+	err = wsdlgo.Fetch(src, r, func(loc string, data []byte) error {
+		name := localName(loc, taken)
+		taken[name] = true
+		dst := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(dst, data, 0644); err != nil {
+			return err
+		}
+		log.Printf("%s -> %s", loc, dst)
+		entries = append(entries, catalog.Entry{Name: loc, URI: name})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, "catalog.xml"))
+	if err != nil {
+		return err
+	}
+	if err = catalog.Write(f, entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._/-]+`)
+
+// localName returns the slash separated path, relative to the output
+// directory, of the copy of the document at loc: the host and path of
+// URLs, or the base name of files, with the query appended. Names that
+// are taken get a numeric suffix.
+func localName(loc string, taken map[string]bool) string {
+	var name, query string
+	if u, err := url.Parse(loc); err == nil && u.Host != "" {
+		name = path.Join(u.Host, u.Path)
+		query = u.RawQuery
+	} else {
+		name = filepath.Base(loc)
+	}
+	if query != "" {
+		name += "_" + query
+	}
+	name = unsafeChars.ReplaceAllString(name, "_")
+	name = strings.Replace(name, "..", "_", -1)
+	ext := path.Ext(name)
+	if ext == "" || strings.Contains(ext, "_") {
+		ext = ".xml"
+		name += ext
+	}
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; taken[name]; i++ {
+		name = base + "-" + strconv.Itoa(i) + ext
+	}
+	return name
+}
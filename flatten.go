@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+	"github.com/fiorix/wsdl2go/wsdlgo"
+)
+
+// flattenWSDL writes a WSDL document with the definitions and schemas
+// it imports and includes, at any depth, inlined, so that it can be
+// used without the documents it refers to.
+func flattenWSDL(args []string) error {
+	var src, dst, cat string
+	var client clientOptions
+	fs := flag.NewFlagSet("flatten", flag.ExitOnError)
+	fs.StringVar(&src, "i", src, "input file, url, or archive#file")
+	fs.StringVar(&dst, "o", dst, "output file, or '-' for stdout")
+	client.register(fs)
+	fs.StringVar(&cat, "catalog", cat, "XML catalog file that maps the locations of documents to local copies")
+	fs.Parse(args)
+	if src == "" || src == "-" {
+		return errors.New("flatten: missing input file or url (-i)")
+	}
+
+	r, err := newResolver(client, src, cat)
+	if err != nil {
+		return err
+	}
+	d, err := wsdlgo.Flatten(src, r)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if dst != "" && dst != "-" {
+		f, err := os.Create(dst)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return wsdl.Marshal(w, d)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fiorix/wsdl2go/lint"
+)
+
+// lintWSDL checks a WSDL document and its imports, and prints the
+// diagnostics to stdout. It fails if any of them is an error.
+func lintWSDL(args []string) error {
+	var src, format, cat string
+	var client clientOptions
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.StringVar(&src, "i", src, "input file, url, archive#file, or '-' for stdin")
+	fs.StringVar(&format, "format", "text", "output format: text or json")
+	client.register(fs)
+	fs.StringVar(&cat, "catalog", cat, "XML catalog file that maps the locations of documents to local copies")
+	fs.Parse(args)
+
+	r, err := newResolver(client, src, cat)
+	if err != nil {
+		return err
+	}
+	l := &lint.Linter{Resolver: r}
+	var diags []*lint.Diagnostic
+	if src == "" || src == "-" {
+		diags = l.Lint("<stdin>", os.Stdin)
+	} else if diags, err = l.LintFile(src); err != nil {
+		return err
+	}
+	switch format {
+	case "text":
+		err = lint.WriteText(os.Stdout, diags)
+	case "json":
+		err = lint.WriteJSON(os.Stdout, diags)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	if lint.HasErrors(diags) {
+		return errors.New("lint: errors found")
+	}
+	return nil
+}
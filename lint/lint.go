@@ -0,0 +1,119 @@
+// Package lint checks WSDL and XSD documents for the problems that
+// wsdl.Unmarshal accepts silently and the code generator only finds
+// halfway through, or not at all: unresolved references, duplicate
+// definitions, port type operations without bindings, schema constructs
+// the generator drops, and WS-I Basic Profile violations.
+//
+// Every diagnostic carries the file, line and column of the offending
+// element.
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fiorix/wsdl2go/resolver"
+)
+
+// Severity of a diagnostic.
+type Severity string
+
+// Severities of diagnostics. Errors are problems that break code
+// generation or the service contract; warnings are approximations and
+// interoperability issues.
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Diagnostic is a problem found in a document.
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+// String returns the diagnostic in the file:line:col: format of
+// compilers.
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", d.File, d.Line, d.Column, d.Severity, d.Message, d.Rule)
+}
+
+// Linter checks documents and the documents they import.
+type Linter struct {
+	// Resolver reads documents and imports. Defaults to a resolver
+	// that uses http.DefaultClient and no catalog.
+	Resolver *resolver.Resolver
+}
+
+// Lint checks the document read from r. The name is used in
+// diagnostics and as the base for relative imports.
+func (l *Linter) Lint(name string, r io.Reader) []*Diagnostic {
+	ld := &loader{resolver: l.resolver(), seen: make(map[string]bool)}
+	ld.load(name, r, "")
+	c := &checker{diags: ld.diags}
+	c.check(ld.docs)
+	sort.SliceStable(c.diags, func(i, j int) bool {
+		a, b := c.diags[i], c.diags[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return c.diags
+}
+
+// LintFile checks the document at name, a file path or URL.
+func (l *Linter) LintFile(name string) ([]*Diagnostic, error) {
+	b, err := l.resolver().Read(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.Lint(name, bytes.NewReader(b)), nil
+}
+
+func (l *Linter) resolver() *resolver.Resolver {
+	if l.Resolver == nil {
+		l.Resolver = &resolver.Resolver{}
+	}
+	return l.Resolver
+}
+
+// HasErrors reports whether any of the diagnostics is an error.
+func HasErrors(diags []*Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText writes the diagnostics to w, one per line.
+func WriteText(w io.Writer, diags []*Diagnostic) error {
+	for _, d := range diags {
+		if _, err := fmt.Fprintln(w, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the diagnostics to w as a JSON array.
+func WriteJSON(w io.Writer, diags []*Diagnostic) error {
+	if diags == nil {
+		diags = []*Diagnostic{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diags)
+}
@@ -0,0 +1,112 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintGood(t *testing.T) {
+	l := &Linter{}
+	diags, err := l.LintFile(filepath.Join("testdata", "good.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diags) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diags)
+	}
+}
+
+func TestLintBad(t *testing.T) {
+	l := &Linter{}
+	diags, err := l.LintFile(filepath.Join("testdata", "bad.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`bad.wsdl:16:7: warning: mixed content is generated as soap.AnyElement, child elements are not typed (unsupported)`,
+		`bad.wsdl:19:11: error: type "tns:Missing" is not defined in namespace "urn:bad" (unresolved-reference)`,
+		`bad.wsdl:25:7: warning: "Special" is decoded as its substitution group head "tns:Get", whose type is not abstract (unsupported)`,
+		`bad.wsdl:33:5: warning: part "extra" of message "GetOut" is bound as document-literal but does not refer to an element (wsi-R2204)`,
+		`bad.wsdl:35:3: error: message "GetOut" is already defined at testdata/bad.wsdl:31:3 (duplicate-definition)`,
+		`bad.wsdl:44:7: error: message "tns:PutIn" is not defined in namespace "urn:bad" (unresolved-reference)`,
+		`bad.wsdl:47:3: error: operation "Put" of port type "ItemPort" has no binding in "ItemBinding" (missing-binding)`,
+		"bad.wsdl:48:5: warning: soap:binding has no transport attribute (wsi-R2701)",
+		`bad.wsdl:51:19: warning: soap:body of document-literal operation "Get" has a namespace attribute (wsi-R2716)`,
+		`bad.wsdl:52:20: warning: soap:body of operation "Get" uses "encoded", only literal is allowed (wsi-R2706)`,
+		`bad.wsdl:52:20: warning: soap:body of document-literal operation "Get" binds 2 parts, at most one is allowed (wsi-R2201)`,
+		`bad.wsdl:54:5: error: operation "Delete" is not defined in port type "ItemPort" (missing-binding)`,
+		`bad.wsdl:59:5: error: binding "tns:NoBinding" is not defined in namespace "urn:bad" (unresolved-reference)`,
+		`bad.xsd:8:3: error: type "Item" is already defined at testdata/bad.wsdl:16:7 (duplicate-definition)`,
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("want %d diagnostics, have %d: %v", len(want), len(diags), diags)
+	}
+	for i, d := range diags {
+		if have := d.String(); have != filepath.Join("testdata", want[i]) {
+			t.Errorf("diagnostic %d:\nwant %s\nhave %s", i, want[i], have)
+		}
+	}
+	if !HasErrors(diags) {
+		t.Fatal("want errors")
+	}
+}
+
+func TestLintParseError(t *testing.T) {
+	l := &Linter{}
+	diags := l.Lint("broken.wsdl", strings.NewReader("<definitions>\n  <types>\n</definitions>"))
+	if len(diags) != 1 {
+		t.Fatalf("want 1 diagnostic, have %v", diags)
+	}
+	if d := diags[0]; d.Rule != RuleParse || d.Line != 3 {
+		t.Fatalf("unexpected diagnostic: %v", d)
+	}
+}
+
+func TestLintUnsupportedRoot(t *testing.T) {
+	cases := []struct {
+		Doc, Want string
+	}{
+		{
+			"<description xmlns=\"http://www.w3.org/ns/wsdl\">\n</description>",
+			"d.wsdl:1:1: warning: WSDL 2.0 documents are not checked, only WSDL 1.1 documents and schemas are (unsupported)",
+		},
+		{
+			"<?xml version=\"1.0\"?>\n<html><body/></html>",
+			"d.wsdl:2:1: error: root element <html> is neither a WSDL 1.1 definitions nor a schema (unsupported)",
+		},
+	}
+	l := &Linter{}
+	for _, c := range cases {
+		diags := l.Lint("d.wsdl", strings.NewReader(c.Doc))
+		if len(diags) != 1 || diags[0].String() != c.Want {
+			t.Errorf("want %s, have %v", c.Want, diags)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	diags := []*Diagnostic{
+		{File: "a.wsdl", Line: 1, Column: 2, Severity: Error, Rule: RuleUnresolved, Message: "oops"},
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, diags); err != nil {
+		t.Fatal(err)
+	}
+	var have []*Diagnostic
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatal(err)
+	}
+	if len(have) != 1 || *have[0] != *diags[0] {
+		t.Fatalf("unexpected output: %s", buf.Bytes())
+	}
+	buf.Reset()
+	if err := WriteJSON(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Fatalf("want empty array, have %s", buf.Bytes())
+	}
+}
@@ -0,0 +1,411 @@
+package lint
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Rules reported by the linter.
+const (
+	RuleParse       = "parse"
+	RuleImport      = "import"
+	RuleUnresolved  = "unresolved-reference"
+	RuleDuplicate   = "duplicate-definition"
+	RuleBinding     = "missing-binding"
+	RuleUnsupported = "unsupported"
+)
+
+// kind is the symbol space of a definition.
+type kind string
+
+const (
+	typeKind           kind = "type"
+	elementKind        kind = "element"
+	attributeKind      kind = "attribute"
+	groupKind          kind = "group"
+	attributeGroupKind kind = "attribute group"
+	messageKind        kind = "message"
+	portTypeKind       kind = "port type"
+	bindingKind        kind = "binding"
+)
+
+// checker runs the rules over a set of loaded documents.
+type checker struct {
+	defs  map[kind]map[xml.Name][]*node
+	diags []*Diagnostic
+}
+
+func (n *node) diag(sev Severity, rule, format string, args ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		File:     n.doc.name,
+		Line:     n.pos.Line,
+		Column:   n.pos.Column,
+		Severity: sev,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+func (c *checker) report(n *node, sev Severity, rule, format string, args ...interface{}) {
+	c.diags = append(c.diags, n.diag(sev, rule, format, args...))
+}
+
+func (c *checker) check(docs []*document) {
+	c.defs = make(map[kind]map[xml.Name][]*node)
+	for _, doc := range docs {
+		doc.root.walk(c.define)
+	}
+	c.checkDuplicates()
+	for _, doc := range docs {
+		doc.root.walk(c.checkRefs)
+		doc.root.walk(c.checkUnsupported)
+		doc.root.walk(c.checkBinding)
+	}
+}
+
+// define indexes the top level definitions of schemas and WSDL documents.
+func (c *checker) define(n *node) {
+	if n.parent == nil || n.attr("name") == "" {
+		return
+	}
+	var k kind
+	switch p := n.parent; {
+	case p.is("schema", xsdNS):
+		switch {
+		case n.is("complexType", xsdNS), n.is("simpleType", xsdNS):
+			k = typeKind
+		case n.is("element", xsdNS):
+			k = elementKind
+		case n.is("attribute", xsdNS):
+			k = attributeKind
+		case n.is("group", xsdNS):
+			k = groupKind
+		case n.is("attributeGroup", xsdNS):
+			k = attributeGroupKind
+		}
+	case p.is("definitions", wsdlNS):
+		switch {
+		case n.is("message", wsdlNS):
+			k = messageKind
+		case n.is("portType", wsdlNS):
+			k = portTypeKind
+		case n.is("binding", wsdlNS):
+			k = bindingKind
+		}
+	}
+	if k == "" {
+		return
+	}
+	if c.defs[k] == nil {
+		c.defs[k] = make(map[xml.Name][]*node)
+	}
+	name := xml.Name{Space: n.tns, Local: n.attr("name")}
+	c.defs[k][name] = append(c.defs[k][name], n)
+}
+
+func (c *checker) checkDuplicates() {
+	for k, defs := range c.defs {
+		for name, nodes := range defs {
+			for _, n := range nodes[1:] {
+				first := nodes[0]
+				c.report(n, Error, RuleDuplicate, "%s %q is already defined at %s",
+					k, name.Local, first.pos)
+			}
+		}
+	}
+}
+
+// lookup resolves the QName v of node n to a definition of kind k.
+func (c *checker) lookup(n *node, k kind, v string) (*node, error) {
+	name, err := n.qname(v)
+	if err != nil {
+		return nil, err
+	}
+	if builtinNS[name.Space] {
+		return nil, nil
+	}
+	if defs := c.defs[k][name]; len(defs) > 0 {
+		return defs[0], nil
+	}
+	if name.Space == "" {
+		return nil, fmt.Errorf("%s %q is not defined in the default namespace", k, v)
+	}
+	return nil, fmt.Errorf("%s %q is not defined in namespace %q", k, v, name.Space)
+}
+
+// ref checks that the QName in attribute attr of n refers to a
+// definition of kind k.
+func (c *checker) ref(n *node, attr string, k kind) *node {
+	v := n.attr(attr)
+	if v == "" {
+		return nil
+	}
+	def, err := c.lookup(n, k, v)
+	if err != nil {
+		c.report(n, Error, RuleUnresolved, "%s", err)
+	}
+	return def
+}
+
+func (c *checker) checkRefs(n *node) {
+	switch {
+	case n.is("element", xsdNS):
+		c.ref(n, "type", typeKind)
+		c.ref(n, "ref", elementKind)
+		c.ref(n, "substitutionGroup", elementKind)
+	case n.is("attribute", xsdNS):
+		c.ref(n, "type", typeKind)
+		c.ref(n, "ref", attributeKind)
+	case n.is("extension", xsdNS), n.is("restriction", xsdNS):
+		c.ref(n, "base", typeKind)
+	case n.is("list", xsdNS):
+		c.ref(n, "itemType", typeKind)
+	case n.is("union", xsdNS):
+		for _, v := range strings.Fields(n.attr("memberTypes")) {
+			if _, err := c.lookup(n, typeKind, v); err != nil {
+				c.report(n, Error, RuleUnresolved, "%s", err)
+			}
+		}
+	case n.is("group", xsdNS):
+		c.ref(n, "ref", groupKind)
+	case n.is("attributeGroup", xsdNS):
+		c.ref(n, "ref", attributeGroupKind)
+	case n.is("part", wsdlNS):
+		c.ref(n, "element", elementKind)
+		c.ref(n, "type", typeKind)
+	case n.is("input", wsdlNS), n.is("output", wsdlNS), n.is("fault", wsdlNS):
+		c.ref(n, "message", messageKind)
+	case n.is("binding", wsdlNS):
+		c.ref(n, "type", portTypeKind)
+	case n.is("port", wsdlNS):
+		c.ref(n, "binding", bindingKind)
+	}
+}
+
+// checkUnsupported reports the schema constructs the generator drops or
+// approximates.
+func (c *checker) checkUnsupported(n *node) {
+	switch {
+	case n.is("complexType", xsdNS) && n.attr("mixed") == "true",
+		n.is("complexContent", xsdNS) && n.attr("mixed") == "true":
+		c.report(n, Warning, RuleUnsupported, "mixed content is generated as soap.AnyElement, child elements are not typed")
+	case n.is("element", xsdNS) && n.hasAttr("substitutionGroup"):
+		head, _ := c.lookup(n, elementKind, n.attr("substitutionGroup"))
+		if head == nil {
+			return
+		}
+		if typ, _ := c.lookup(head, typeKind, head.attr("type")); typ == nil || typ.attr("abstract") != "true" {
+			c.report(n, Warning, RuleUnsupported, "%q is decoded as its substitution group head %q, whose type is not abstract",
+				n.attr("name"), n.attr("substitutionGroup"))
+		}
+	}
+}
+
+// ops returns the operation children of a port type or binding by name.
+func ops(n *node) (map[string]*node, []*node) {
+	m := make(map[string]*node)
+	var l []*node
+	for _, op := range n.children {
+		if op.is("operation", wsdlNS) {
+			m[op.attr("name")] = op
+			l = append(l, op)
+		}
+	}
+	return m, l
+}
+
+// soapChild returns the first SOAP 1.1 or 1.2 extension element of n.
+func soapChild(n *node, local string) *node {
+	for _, c := range n.children {
+		if c.is(local, soapNS, soap12NS) {
+			return c
+		}
+	}
+	return nil
+}
+
+// checkBinding checks that bindings cover their port type, and the WS-I
+// Basic Profile 1.1 rules for SOAP bindings.
+func (c *checker) checkBinding(n *node) {
+	switch {
+	case n.is("portType", wsdlNS):
+		seen := make(map[string]bool)
+		_, l := ops(n)
+		for _, op := range l {
+			name := op.attr("name")
+			if seen[name] {
+				c.report(op, Warning, "wsi-R2304", "operation %q is overloaded in port type %q", name, n.attr("name"))
+			}
+			seen[name] = true
+		}
+		if !c.bound(n) {
+			c.report(n, Warning, RuleBinding, "port type %q has no binding", n.attr("name"))
+		}
+		return
+	case !n.is("binding", wsdlNS):
+		return
+	}
+	pt, _ := c.lookup(n, portTypeKind, n.attr("type"))
+	if pt == nil {
+		return
+	}
+	ptOps, ptList := ops(pt)
+	bOps, bList := ops(n)
+	for _, op := range ptList {
+		if bOps[op.attr("name")] == nil {
+			c.report(n, Error, RuleBinding, "operation %q of port type %q has no binding in %q",
+				op.attr("name"), pt.attr("name"), n.attr("name"))
+		}
+	}
+	for _, op := range bList {
+		if ptOps[op.attr("name")] == nil {
+			c.report(op, Error, RuleBinding, "operation %q is not defined in port type %q",
+				op.attr("name"), pt.attr("name"))
+		}
+	}
+
+	sb := soapChild(n, "binding")
+	if sb == nil {
+		return
+	}
+	switch tr := strings.TrimSpace(sb.attr("transport")); tr {
+	case "":
+		c.report(sb, Warning, "wsi-R2701", "soap:binding has no transport attribute")
+	case soapHTTP:
+	default:
+		c.report(sb, Warning, "wsi-R2702", "soap:binding transport %q is not HTTP", tr)
+	}
+	style := sb.attr("style")
+	if style == "" {
+		style = "document"
+	}
+	var opStyle string
+	signatures := make(map[xml.Name]string)
+	for _, op := range bList {
+		s := style
+		if so := soapChild(op, "operation"); so != nil && so.attr("style") != "" {
+			s = so.attr("style")
+		}
+		if opStyle == "" {
+			opStyle = s
+		} else if s != opStyle {
+			c.report(op, Warning, "wsi-R2705", "operation %q uses %s style, other operations of binding %q use %s",
+				op.attr("name"), s, n.attr("name"), opStyle)
+		}
+		for _, io := range op.children {
+			if !io.is("input", wsdlNS) && !io.is("output", wsdlNS) && !io.is("fault", wsdlNS) {
+				continue
+			}
+			for _, ext := range io.children {
+				if !ext.is("body", soapNS, soap12NS) && !ext.is("header", soapNS, soap12NS) && !ext.is("fault", soapNS, soap12NS) {
+					continue
+				}
+				if use := ext.attr("use"); use != "" && use != "literal" {
+					c.report(ext, Warning, "wsi-R2706", "soap:%s of operation %q uses %q, only literal is allowed",
+						ext.name.Local, op.attr("name"), use)
+				}
+			}
+			body := soapChild(io, "body")
+			if body == nil {
+				continue
+			}
+			switch s {
+			case "document":
+				if body.hasAttr("namespace") {
+					c.report(body, Warning, "wsi-R2716", "soap:body of document-literal operation %q has a namespace attribute", op.attr("name"))
+				}
+			case "rpc":
+				if !body.hasAttr("namespace") {
+					c.report(body, Warning, "wsi-R2717", "soap:body of rpc-literal operation %q has no namespace attribute", op.attr("name"))
+				}
+			}
+			if ptOp := ptOps[op.attr("name")]; ptOp != nil && !io.is("fault", wsdlNS) {
+				c.checkParts(op, io, body, ptOp, s, signatures)
+			}
+		}
+	}
+}
+
+// checkParts checks the message parts bound to a soap:body.
+func (c *checker) checkParts(op, io, body, ptOp *node, style string, signatures map[xml.Name]string) {
+	var abstract *node
+	for _, m := range ptOp.children {
+		if m.name == io.name {
+			abstract = m
+			break
+		}
+	}
+	if abstract == nil {
+		return
+	}
+	msg, _ := c.lookup(abstract, messageKind, abstract.attr("message"))
+	if msg == nil {
+		return
+	}
+	var bound []*node
+	names := strings.Fields(body.attr("parts"))
+	for _, p := range msg.children {
+		if !p.is("part", wsdlNS) {
+			continue
+		}
+		if body.hasAttr("parts") && !contains(names, p.attr("name")) {
+			continue
+		}
+		bound = append(bound, p)
+	}
+	switch style {
+	case "document":
+		if len(bound) > 1 {
+			c.report(body, Warning, "wsi-R2201", "soap:body of document-literal operation %q binds %d parts, at most one is allowed",
+				op.attr("name"), len(bound))
+		}
+		for _, p := range bound {
+			if !p.hasAttr("element") {
+				c.report(p, Warning, "wsi-R2204", "part %q of message %q is bound as document-literal but does not refer to an element",
+					p.attr("name"), msg.attr("name"))
+				continue
+			}
+			if !io.is("input", wsdlNS) {
+				continue
+			}
+			el, err := p.qname(p.attr("element"))
+			if err != nil {
+				continue
+			}
+			if prev, ok := signatures[el]; ok && prev != op.attr("name") {
+				c.report(op, Warning, "wsi-R2710", "operations %q and %q have the same wire signature %q",
+					prev, op.attr("name"), p.attr("element"))
+			}
+			signatures[el] = op.attr("name")
+		}
+	case "rpc":
+		for _, p := range bound {
+			if !p.hasAttr("type") {
+				c.report(p, Warning, "wsi-R2203", "part %q of message %q is bound as rpc-literal but does not refer to a type",
+					p.attr("name"), msg.attr("name"))
+			}
+		}
+	}
+}
+
+// bound reports whether any binding refers to the port type pt.
+func (c *checker) bound(pt *node) bool {
+	for _, defs := range c.defs[bindingKind] {
+		for _, b := range defs {
+			if p, _ := c.lookup(b, portTypeKind, b.attr("type")); p == pt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/resolver"
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// Namespaces of the documents and constructs checked by the linter.
+const (
+	wsdlNS   = "http://schemas.xmlsoap.org/wsdl/"
+	soapNS   = "http://schemas.xmlsoap.org/wsdl/soap/"
+	soap12NS = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	xsdNS    = "http://www.w3.org/2001/XMLSchema"
+	soapEnc  = "http://schemas.xmlsoap.org/soap/encoding/"
+	soapHTTP = "http://schemas.xmlsoap.org/soap/http"
+)
+
+// builtinNS are the namespaces of built-in definitions, which are never
+// reported as unresolved.
+var builtinNS = map[string]bool{
+	xsdNS:                                  true,
+	"http://www.w3.org/2000/10/XMLSchema":  true,
+	"http://www.w3.org/1999/XMLSchema":     true,
+	"http://www.w3.org/XML/1998/namespace": true,
+	soapEnc:                                true,
+}
+
+// node is an element of a parsed document, with its position and the
+// namespace declarations in scope.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	scope    map[string]string
+	children []*node
+	parent   *node
+	doc      *document
+	pos      wsdl.Position
+
+	// tns is the target namespace of the enclosing wsdl:definitions or
+	// xs:schema.
+	tns string
+}
+
+func (n *node) attr(local string) string {
+	for _, a := range n.attrs {
+		if a.Name.Space == "" && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *node) hasAttr(local string) bool {
+	for _, a := range n.attrs {
+		if a.Name.Space == "" && a.Name.Local == local {
+			return true
+		}
+	}
+	return false
+}
+
+// is reports whether n is the element local in one of the namespaces.
+func (n *node) is(local string, ns ...string) bool {
+	if n.name.Local != local {
+		return false
+	}
+	for _, s := range ns {
+		if n.name.Space == s || (s == xsdNS && isXSD(n.name.Space)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isXSD reports whether ns is one of the XML Schema namespaces.
+func isXSD(ns string) bool {
+	switch ns {
+	case xsdNS, "http://www.w3.org/2000/10/XMLSchema", "http://www.w3.org/1999/XMLSchema":
+		return true
+	}
+	return false
+}
+
+// qname resolves a QName attribute value using the namespace declarations
+// in scope. Unprefixed names are in the default namespace.
+func (n *node) qname(v string) (xml.Name, error) {
+	prefix, local := "", v
+	if i := strings.Index(v, ":"); i >= 0 {
+		prefix, local = v[:i], v[i+1:]
+	}
+	ns, ok := n.scope[prefix]
+	if prefix == "xml" {
+		ns, ok = "http://www.w3.org/XML/1998/namespace", true
+	}
+	if !ok && prefix != "" {
+		return xml.Name{Local: local}, fmt.Errorf("undeclared namespace prefix %q in %q", prefix, v)
+	}
+	return xml.Name{Space: ns, Local: local}, nil
+}
+
+// walk calls f for n and all its descendants.
+func (n *node) walk(f func(*node)) {
+	f(n)
+	for _, c := range n.children {
+		c.walk(f)
+	}
+}
+
+// document is a loaded WSDL or XSD document.
+type document struct {
+	name string
+	root *node
+}
+
+// loader loads a document and its imports and includes.
+type loader struct {
+	resolver *resolver.Resolver
+	docs     []*document
+	seen     map[string]bool
+	diags    []*Diagnostic
+}
+
+// load parses the document name from r, and then its imports. The
+// chameleon namespace is the target namespace of the including schema,
+// for included schemas without a target namespace.
+func (l *loader) load(name string, r io.Reader, chameleon string) {
+	if l.seen[name] {
+		return
+	}
+	l.seen[name] = true
+	dec := wsdl.NewDecoder(r)
+	dec.Name = name
+	root, err := dec.DecodeNode()
+	if err != nil {
+		diag := &Diagnostic{File: name, Severity: Error, Rule: RuleParse, Message: err.Error()}
+		if pe, ok := err.(*wsdl.ParseError); ok {
+			diag.Line, diag.Column, diag.Message = pe.Pos.Line, pe.Pos.Column, pe.Message()
+		}
+		l.diags = append(l.diags, diag)
+		return
+	}
+	doc := &document{name: name}
+	doc.root = newNode(doc, root, nil, chameleon)
+	switch n := doc.root; {
+	case n.is("definitions", wsdlNS), n.is("schema", xsdNS):
+	case n.name.Space == wsdl.WSDL20Namespace && n.name.Local == "description":
+		l.diags = append(l.diags, n.diag(Warning, RuleUnsupported, "WSDL 2.0 documents are not checked, only WSDL 1.1 documents and schemas are"))
+		return
+	default:
+		l.diags = append(l.diags, n.diag(Error, RuleUnsupported, "root element <%s> is neither a WSDL 1.1 definitions nor a schema", n.name.Local))
+		return
+	}
+	l.docs = append(l.docs, doc)
+	doc.root.walk(func(n *node) {
+		var loc, ns string
+		switch {
+		case n.is("import", wsdlNS):
+			loc = n.attr("location")
+		case n.is("import", xsdNS):
+			loc = n.attr("schemaLocation")
+		case n.is("include", xsdNS):
+			loc, ns = n.attr("schemaLocation"), n.tns
+		}
+		if loc == "" {
+			return
+		}
+		loc = resolver.Resolve(name, loc)
+		if l.seen[loc] {
+			return
+		}
+		b, err := l.resolver.Read(loc)
+		if err != nil {
+			l.diags = append(l.diags, n.diag(Error, RuleImport, "cannot load %q: %v", loc, err))
+			return
+		}
+		l.load(loc, bytes.NewReader(b), ns)
+	})
+}
+
+// newNode returns the node of the element wn of doc, and of its children,
+// with the namespace declarations in scope of parent. The chameleon
+// namespace is the target namespace of schemas without one.
+func newNode(doc *document, wn *wsdl.Node, parent *node, chameleon string) *node {
+	n := &node{
+		name:   wn.Name,
+		attrs:  wn.Attrs,
+		parent: parent,
+		doc:    doc,
+		pos:    wn.Pos,
+		scope:  make(map[string]string),
+	}
+	if parent != nil {
+		for k, v := range parent.scope {
+			n.scope[k] = v
+		}
+		n.tns = parent.tns
+	}
+	for _, a := range wn.Attrs {
+		switch {
+		case a.Name.Space == "xmlns":
+			n.scope[a.Name.Local] = a.Value
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			n.scope[""] = a.Value
+		}
+	}
+	if n.is("definitions", wsdlNS) || n.is("schema", xsdNS) {
+		n.tns = n.attr("targetNamespace")
+		if n.tns == "" && n.is("schema", xsdNS) {
+			n.tns = chameleon
+		}
+	}
+	for _, c := range wn.Children {
+		n.children = append(n.children, newNode(doc, c, n, chameleon))
+	}
+	return n
+}
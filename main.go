@@ -1,15 +1,14 @@
 package main
 
 import (
-	"crypto/tls"
+	"bytes"
 	"flag"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
-	"net/url"
 	"os"
 
+	"github.com/fiorix/wsdl2go/resolver"
 	"github.com/fiorix/wsdl2go/wsdl"
 	"github.com/fiorix/wsdl2go/wsdlgo"
 )
@@ -17,26 +16,52 @@ import (
 var version = "tip"
 
 type options struct {
-	Src            string
-	Dst            string
-	Package        string
-	Namespace      string
-	Insecure       bool
-	ClientCertFile string
-	ClientKeyFile  string
-	Version        bool
+	Src       string
+	Dst       string
+	Package   string
+	Namespace string
+	Client    clientOptions
+	Mocks     bool
+	Unwrap    bool
+	Nillable  bool
+	Strict    bool
+	Catalog   string
+	DocLang   string
+	Version   bool
+}
+
+// commands are the subcommands of wsdl2go, run as
+// wsdl2go <command> [flags]. Without a command wsdl2go generates code.
+var commands = map[string]func(args []string) error{
+	"fetch":       fetchWSDL,
+	"flatten":     flattenWSDL,
+	"lint":        lintWSDL,
+	"mock-server": mockServer,
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
 	opts := options{}
 
-	flag.StringVar(&opts.Src, "i", opts.Src, "input file, url, or '-' for stdin")
+	flag.StringVar(&opts.Src, "i", opts.Src, "input file, url, archive#file, or '-' for stdin")
 	flag.StringVar(&opts.Dst, "o", opts.Dst, "output file, or '-' for stdout")
 	flag.StringVar(&opts.Namespace, "n", opts.Namespace, "override namespace")
 	flag.StringVar(&opts.Package, "p", opts.Package, "package name")
-	flag.BoolVar(&opts.Insecure, "yolo", opts.Insecure, "accept invalid https certificates")
-	flag.StringVar(&opts.ClientCertFile, "cert", opts.ClientCertFile, "use client TLS cert file")
-	flag.StringVar(&opts.ClientKeyFile, "key", opts.ClientKeyFile, "use client TLS key file")
+	opts.Client.register(flag.CommandLine)
+	flag.BoolVar(&opts.Mocks, "mock", opts.Mocks, "generate a mock of the port type interface")
+	flag.BoolVar(&opts.Unwrap, "unwrap", opts.Unwrap, "take and return the child elements of document/literal wrapped operations")
+	flag.BoolVar(&opts.Nillable, "nillable", opts.Nillable, "generate nillable elements as wrappers that tell xsi:nil from absent elements")
+	flag.BoolVar(&opts.Strict, "strict", opts.Strict, "fail if any schema construct is dropped or approximated")
+	flag.StringVar(&opts.DocLang, "doclang", opts.DocLang, "language of the documentation emitted as comments, e.g. en or pt-BR")
+	flag.StringVar(&opts.Catalog, "catalog", opts.Catalog, "XML catalog file that maps the locations of documents to local copies")
 	flag.BoolVar(&opts.Version, "version", opts.Version, "show version and exit")
 	flag.Parse()
 	if opts.Version {
@@ -56,78 +81,57 @@ func main() {
 		w = f
 	}
 
-	cli := httpClient(opts.Insecure, opts.ClientCertFile, opts.ClientKeyFile)
+	r, err := newResolver(opts.Client, opts.Src, opts.Catalog)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	err := codegen(w, opts, cli)
+	err = codegen(w, opts, r)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
-func codegen(w io.Writer, opts options, cli *http.Client) error {
-	var err error
-	var f io.ReadCloser
-	if opts.Src == "" || opts.Src == "-" {
-		f = os.Stdin
-	} else if f, err = open(opts.Src, cli); err != nil {
-		return err
+func codegen(w io.Writer, opts options, r *resolver.Resolver) error {
+	var f io.Reader = os.Stdin
+	if opts.Src != "" && opts.Src != "-" {
+		b, err := r.Read(opts.Src)
+		if err != nil {
+			return err
+		}
+		f = bytes.NewReader(b)
+	}
+	dec := wsdl.NewDecoder(f)
+	if f != os.Stdin {
+		dec.Name = opts.Src
 	}
-	d, err := wsdl.Unmarshal(f)
+	d, err := dec.Decode()
 	if err != nil {
 		return err
 	}
-	f.Close()
 
 	enc := wsdlgo.NewEncoder(w)
-	enc.SetClient(cli)
+	if opts.Src != "" && opts.Src != "-" {
+		enc.SetLocation(opts.Src)
+	}
+	enc.SetResolver(r)
 	if opts.Package != "" {
 		enc.SetPackageName(wsdlgo.PackageName(opts.Package))
 	}
 	if opts.Namespace != "" {
 		enc.SetLocalNamespace(opts.Namespace)
 	}
+	enc.SetGenerateMocks(opts.Mocks)
+	enc.SetUnwrap(opts.Unwrap)
+	enc.SetNillable(opts.Nillable)
+	enc.SetStrict(opts.Strict)
+	enc.SetDocLanguage(opts.DocLang)
 
-	return enc.Encode(d)
-}
-
-func open(name string, cli *http.Client) (io.ReadCloser, error) {
-	u, err := url.Parse(name)
-	if err != nil || u.Scheme == "" {
-		return os.Open(name)
-	}
-	resp, err := cli.Get(name)
-	if err != nil {
-		return nil, err
-	}
-	return resp.Body, err
-}
-
-// httpClient returns http client with default options
-func httpClient(insecure bool, clientCertPath, clientKeyPath string) *http.Client {
-	tlsConfig := &tls.Config{InsecureSkipVerify: insecure}
-
-	if clientCertPath != "" && clientKeyPath != "" {
-		clientCert, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
-		if err != nil {
-			log.Fatalln("Failed to load x509 client key pair:", err)
-		}
-		tlsConfig.Certificates = []tls.Certificate{clientCert}
-		tlsConfig.Renegotiation = tls.RenegotiateFreelyAsClient
-	} else if clientCertPath == "" && clientKeyPath != "" {
-		log.Fatalln("Certificate file is required when using key file")
-	} else if clientCertPath != "" && clientKeyPath == "" {
-		log.Fatalln("Key file is required when using certificate file")
+	if err = enc.Encode(d); err != nil {
+		return err
 	}
-
-	defaultTransport := http.DefaultTransport.(*http.Transport)
-	transport := &http.Transport{
-		Proxy:                 defaultTransport.Proxy,
-		DialContext:           defaultTransport.DialContext,
-		MaxIdleConns:          defaultTransport.MaxIdleConns,
-		IdleConnTimeout:       defaultTransport.IdleConnTimeout,
-		ExpectContinueTimeout: defaultTransport.ExpectContinueTimeout,
-		TLSHandshakeTimeout:   defaultTransport.TLSHandshakeTimeout,
-		TLSClientConfig:       tlsConfig,
+	for _, diag := range enc.Diagnostics() {
+		log.Printf("warning: %s", diag)
 	}
-	return &http.Client{Transport: transport}
+	return nil
 }
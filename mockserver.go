@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/fiorix/wsdl2go/mockserver"
+	"github.com/fiorix/wsdl2go/wsdl"
+	"github.com/fiorix/wsdl2go/wsdlgo"
+)
+
+// mockServer runs a local mock SOAP server for a WSDL document.
+func mockServer(args []string) error {
+	var src, addr, dir, cat string
+	var client clientOptions
+	fs := flag.NewFlagSet("mock-server", flag.ExitOnError)
+	fs.StringVar(&src, "i", src, "input file, url, archive#file, or '-' for stdin")
+	fs.StringVar(&addr, "addr", ":8080", "address to listen on")
+	fs.StringVar(&dir, "responses", dir, "directory of scripted <Operation>.xml or .json responses")
+	client.register(fs)
+	fs.StringVar(&cat, "catalog", cat, "XML catalog file that maps the locations of documents to local copies")
+	fs.Parse(args)
+
+	r, err := newResolver(client, src, cat)
+	if err != nil {
+		return err
+	}
+	var raw []byte
+	loc := src
+	if src == "" || src == "-" {
+		raw, err = ioutil.ReadAll(os.Stdin)
+		loc = ""
+	} else {
+		raw, err = r.Read(src)
+	}
+	if err != nil {
+		return err
+	}
+	d, err := wsdl.Unmarshal(bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	// the schema of the server has the definitions of the imports
+	if err = wsdlgo.Import(d, loc, r); err != nil {
+		return err
+	}
+	s := mockserver.New(d, raw)
+	s.ResponseDir = dir
+	s.Logger = log.New(os.Stderr, "", log.LstdFlags)
+	log.Printf("mock server for %s listening on %s", d.Name, addr)
+	return http.ListenAndServe(addr, s)
+}
@@ -0,0 +1,136 @@
+package mockserver
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// maxSampleDepth limits the nesting of generated samples, for recursive
+// types.
+const maxSampleDepth = 8
+
+// sampler writes sample XML documents derived from the schema.
+type sampler struct {
+	s   *schema
+	enc *xml.Encoder
+}
+
+// element writes a sample of el, using the tns prefix for qualified
+// elements. Optional and repeated elements are written once.
+func (sp *sampler) element(el *wsdl.Element, qualified bool, depth int, attrs ...xml.Attr) error {
+	el = sp.s.element(el)
+	start := xml.StartElement{Name: xml.Name{Local: el.Name}, Attr: attrs}
+	if qualified {
+		start.Name.Local = "tns:" + el.Name
+	}
+	if ct := sp.s.complexType(el); ct != nil {
+		return sp.complexType(start, ct, depth)
+	}
+	if v := schemaValue(el.Fixed, el.Default); v != "" {
+		return sp.enc.EncodeElement(v, start)
+	}
+	typ := el.Type
+	if typ == "" {
+		typ = "string"
+	}
+	return sp.simple(start, typ)
+}
+
+// schemaValue returns the fixed value of a declaration, or else its
+// default value.
+func schemaValue(fixed, def string) string {
+	if fixed != "" {
+		return fixed
+	}
+	return def
+}
+
+func (sp *sampler) complexType(start xml.StartElement, ct *wsdl.ComplexType, depth int) error {
+	fields, attrs, _ := sp.s.fields(ct)
+	for _, attr := range attrs {
+		name := attr.Name
+		if name == "" {
+			name = trimns(attr.Ref)
+		}
+		value := schemaValue(attr.Fixed, attr.Default)
+		if value == "" {
+			value = sp.value(attr.Type)
+		}
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: name},
+			Value: value,
+		})
+	}
+	if err := sp.enc.EncodeToken(start); err != nil {
+		return err
+	}
+	if base := sp.s.simpleContent(ct); base != "" {
+		if err := sp.enc.EncodeToken(xml.CharData(sp.value(base))); err != nil {
+			return err
+		}
+	}
+	if depth < maxSampleDepth {
+		for _, f := range fields {
+			if err := sp.element(f.el, sp.s.qualified, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return sp.enc.EncodeToken(start.End())
+}
+
+func (sp *sampler) simple(start xml.StartElement, typ string) error {
+	if ct, ok := sp.s.ctypes[trimns(typ)]; ok {
+		return sp.complexType(start, ct, maxSampleDepth)
+	}
+	return sp.enc.EncodeElement(sp.value(typ), start)
+}
+
+// value returns a sample value of the simple type typ.
+func (sp *sampler) value(typ string) string {
+	name := trimns(typ)
+	if st, ok := sp.s.stypes[name]; ok {
+		switch {
+		case st.Restriction != nil && len(st.Restriction.Enum) > 0:
+			return st.Restriction.Enum[0].Value
+		case st.Restriction != nil:
+			return sp.value(st.Restriction.Base)
+		case st.Union != nil:
+			members := strings.Fields(st.Union.MemberTypes)
+			if len(members) > 0 {
+				return sp.value(members[0])
+			}
+		case st.List != nil && st.List.ItemType != "":
+			return sp.value(st.List.ItemType)
+		}
+		return ""
+	}
+	switch strings.ToLower(name) {
+	case "boolean":
+		return "true"
+	case "byte", "short", "int", "integer", "long", "unsignedbyte", "unsignedshort",
+		"unsignedint", "unsignedlong", "nonnegativeinteger", "positiveinteger":
+		return "1"
+	case "negativeinteger", "nonpositiveinteger":
+		return "-1"
+	case "float", "double", "decimal":
+		return "1.5"
+	case "date":
+		return "2006-01-02"
+	case "time":
+		return "15:04:05"
+	case "datetime":
+		return "2006-01-02T15:04:05Z"
+	case "duration":
+		return "PT1H"
+	case "base64binary":
+		return "c2FtcGxl"
+	case "hexbinary":
+		return "73616d706c65"
+	case "anyuri":
+		return "http://example.com"
+	}
+	return "string"
+}
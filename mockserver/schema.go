@@ -0,0 +1,228 @@
+package mockserver
+
+import (
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// schema indexes the types and elements of a WSDL document by their
+// local names.
+type schema struct {
+	ns         string
+	qualified  bool
+	elements   map[string]*wsdl.Element
+	ctypes     map[string]*wsdl.ComplexType
+	stypes     map[string]*wsdl.SimpleType
+	groups     map[string]*wsdl.Group
+	attrGroups map[string]*wsdl.AttributeGroup
+}
+
+func newSchema(d *wsdl.Definitions) *schema {
+	s := &schema{
+		ns:         d.Schema.TargetNamespace,
+		qualified:  d.Schema.ElementFormDefault == "qualified",
+		elements:   make(map[string]*wsdl.Element),
+		ctypes:     make(map[string]*wsdl.ComplexType),
+		stypes:     make(map[string]*wsdl.SimpleType),
+		groups:     make(map[string]*wsdl.Group),
+		attrGroups: make(map[string]*wsdl.AttributeGroup),
+	}
+	if s.ns == "" {
+		s.ns = d.TargetNamespace
+	}
+	for _, el := range d.Schema.Elements {
+		s.elements[el.Name] = el
+	}
+	for _, ct := range d.Schema.ComplexTypes {
+		s.ctypes[ct.Name] = ct
+	}
+	for _, st := range d.Schema.SimpleTypes {
+		s.stypes[st.Name] = st
+	}
+	for _, g := range d.Schema.Groups {
+		s.groups[g.Name] = g
+	}
+	for _, g := range d.Schema.AttributeGroups {
+		s.attrGroups[g.Name] = g
+	}
+	return s
+}
+
+// field is an element of a complex type. The occurrences are those of
+// the particle, which refers to el for element references.
+type field struct {
+	el       *wsdl.Element
+	particle *wsdl.Element
+	optional bool
+}
+
+func (f *field) min() int {
+	if f.optional {
+		return 0
+	}
+	return f.particle.MinOccurs()
+}
+
+// max returns the maximum occurrences of the field, or -1 if unbounded.
+func (f *field) max() int {
+	switch f.particle.Max {
+	case "", "1":
+		return 1
+	case "unbounded":
+		return -1
+	}
+	n := 0
+	for _, c := range f.particle.Max {
+		if c < '0' || c > '9' {
+			return -1
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n
+}
+
+// element resolves element references.
+func (s *schema) element(el *wsdl.Element) *wsdl.Element {
+	if el.Ref == "" {
+		return el
+	}
+	if ref, ok := s.elements[trimns(el.Ref)]; ok {
+		return ref
+	}
+	return el
+}
+
+// complexType returns the complex type of el, if any.
+func (s *schema) complexType(el *wsdl.Element) *wsdl.ComplexType {
+	if el.ComplexType != nil {
+		return el.ComplexType
+	}
+	if el.Type == "" {
+		return nil
+	}
+	return s.ctypes[trimns(el.Type)]
+}
+
+// fields returns the elements and attributes of ct, including the ones
+// of its base types and referenced groups. Elements of choices and of
+// optional groups are optional.
+func (s *schema) fields(ct *wsdl.ComplexType) ([]*field, []*wsdl.Attribute, bool) {
+	return s.typeFields(ct, make(map[*wsdl.ComplexType]bool))
+}
+
+// typeFields returns the fields of ct, and of the base types of ct that
+// are not in visited, which breaks cycles of derivation.
+func (s *schema) typeFields(ct *wsdl.ComplexType, visited map[*wsdl.ComplexType]bool) ([]*field, []*wsdl.Attribute, bool) {
+	visited[ct] = true
+	var fields []*field
+	var attrs []*wsdl.Attribute
+	anyElement := false
+	visiting := make(map[*wsdl.Group]bool)
+	add := func(elements []*wsdl.Element, optional bool) {
+		for _, el := range elements {
+			fields = append(fields, &field{el: s.element(el), particle: el, optional: optional})
+		}
+	}
+	var addSequence func(seq *wsdl.Sequence, optional bool)
+	var addChoice func(c *wsdl.Choice)
+	addGroup := func(ref *wsdl.Group, optional bool) {
+		g, ok := s.groups[trimns(ref.Ref)]
+		if !ok || visiting[g] {
+			return
+		}
+		visiting[g] = true
+		optional = optional || ref.Min == "0"
+		addSequence(g.Sequence, optional)
+		addChoice(g.Choice)
+		add(g.AllElements, optional)
+		delete(visiting, g)
+	}
+	addSequence = func(seq *wsdl.Sequence, optional bool) {
+		if seq == nil {
+			return
+		}
+		add(seq.Elements, optional)
+		for _, c := range seq.Choices {
+			addChoice(c)
+		}
+		for _, g := range seq.Groups {
+			addGroup(g, optional)
+		}
+		anyElement = anyElement || len(seq.Any) > 0
+	}
+	addChoice = func(c *wsdl.Choice) {
+		if c == nil {
+			return
+		}
+		add(c.Elements, true)
+		for _, g := range c.Groups {
+			addGroup(g, true)
+		}
+		anyElement = anyElement || len(c.Any) > 0
+	}
+	if ext := s.extension(ct); ext != nil {
+		if base, ok := s.ctypes[trimns(ext.Base)]; ok && !visited[base] {
+			f, a, any := s.typeFields(base, visited)
+			fields, attrs = append(fields, f...), append(attrs, a...)
+			anyElement = anyElement || any
+		}
+		addSequence(ext.Sequence, false)
+		addChoice(ext.Choice)
+		if ext.Group != nil {
+			addGroup(ext.Group, false)
+		}
+		attrs = append(attrs, ext.Attributes...)
+		attrs = s.attributeGroups(attrs, ext.AttributeGroups, 0)
+	}
+	add(ct.AllElements, false)
+	addSequence(ct.Sequence, false)
+	addChoice(ct.Choice)
+	if ct.Group != nil {
+		addGroup(ct.Group, false)
+	}
+	attrs = append(attrs, ct.Attributes...)
+	attrs = s.attributeGroups(attrs, ct.AttributeGroups, 0)
+	return fields, attrs, anyElement
+}
+
+// attributeGroups appends the attributes of the referenced attribute
+// groups to attrs.
+func (s *schema) attributeGroups(attrs []*wsdl.Attribute, refs []*wsdl.AttributeGroup, depth int) []*wsdl.Attribute {
+	if depth > maxSampleDepth {
+		return attrs
+	}
+	for _, ref := range refs {
+		if g, ok := s.attrGroups[trimns(ref.Ref)]; ok {
+			attrs = append(attrs, g.Attributes...)
+			attrs = s.attributeGroups(attrs, g.AttributeGroups, depth+1)
+		}
+	}
+	return attrs
+}
+
+func (s *schema) extension(ct *wsdl.ComplexType) *wsdl.Extension {
+	if ct.ComplexContent != nil && ct.ComplexContent.Extension != nil {
+		return ct.ComplexContent.Extension
+	}
+	if ct.SimpleContent != nil && ct.SimpleContent.Extension != nil {
+		return ct.SimpleContent.Extension
+	}
+	return nil
+}
+
+// simpleContent returns the base type of the text content of ct.
+func (s *schema) simpleContent(ct *wsdl.ComplexType) string {
+	if ct.SimpleContent != nil && ct.SimpleContent.Extension != nil {
+		return ct.SimpleContent.Extension.Base
+	}
+	return ""
+}
+
+func trimns(s string) string {
+	n := strings.SplitN(s, ":", 2)
+	if len(n) == 2 {
+		return n[1]
+	}
+	return s
+}
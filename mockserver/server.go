@@ -0,0 +1,476 @@
+// Package mockserver provides a stand-in SOAP server driven by a WSDL
+// document, for testing clients without the real service.
+//
+// The server serves the WSDL document itself on GET requests, and
+// accepts POST requests for every operation of the WSDL binding. Requests
+// are validated against the schema, and answered with scripted responses
+// from a directory, or with sample responses derived from the schema.
+package mockserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/model"
+	"github.com/fiorix/wsdl2go/soap"
+	"github.com/fiorix/wsdl2go/wsdl"
+	"golang.org/x/net/html/charset"
+)
+
+// SOAP envelope namespaces.
+const (
+	SOAP11Namespace = "http://schemas.xmlsoap.org/soap/envelope/"
+	SOAP12Namespace = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// Server is an http.Handler that mocks the service described by a WSDL
+// document.
+type Server struct {
+	// ResponseDir is an optional directory of scripted responses. For
+	// each operation, <Operation>.xml is served as the response body (a
+	// complete SOAP envelope, or the content of the SOAP body), and
+	// <Operation>.json is read as a Script. Operations without scripted
+	// responses get sample responses derived from the schema.
+	ResponseDir string
+
+	// Logger is an optional logger for requests and validation errors.
+	Logger *log.Logger
+
+	raw    []byte
+	schema *schema
+	ops    []*operation
+}
+
+// Script is a scripted response read from <Operation>.json in the
+// response directory.
+type Script struct {
+	Status  int               `json:"status"`  // HTTP status, default 200, or that of the SOAP version for faults
+	Headers map[string]string `json:"headers"` // Optional HTTP headers
+	Body    string            `json:"body"`    // Optional XML content of the SOAP body
+	Fault   *ScriptFault      `json:"fault"`   // Optional SOAP fault
+}
+
+// ScriptFault is a SOAP fault in a Script.
+type ScriptFault struct {
+	Code   string `json:"code"`
+	String string `json:"string"`
+	Actor  string `json:"actor"`
+	Detail string `json:"detail"` // Raw XML
+}
+
+// operation is a SOAP operation of the WSDL binding.
+type operation struct {
+	name   string
+	action string
+	rpc    bool
+	input  *wsdl.Message
+	output *wsdl.Message
+}
+
+// New creates and initializes a Server for the WSDL document d, whose
+// raw XML is served on GET requests.
+func New(d *wsdl.Definitions, raw []byte) *Server {
+	s := &Server{raw: raw, schema: newSchema(d)}
+	m := model.Build(d)
+	if len(m.Bindings) == 0 {
+		return s
+	}
+	b := m.Bindings[0]
+	for _, bo := range b.Operations {
+		op := &operation{name: bo.Operation.Name, action: bo.Action, rpc: b.Style == "rpc"}
+		if bo.Operation.Input != nil {
+			op.input = bo.Operation.Input.WSDL
+		}
+		if bo.Operation.Output != nil {
+			op.output = bo.Operation.Output.WSDL
+		}
+		s.ops = append(s.ops, op)
+	}
+	return s
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write(s.raw)
+	case "POST":
+		s.serveSOAP(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) serveSOAP(w http.ResponseWriter, r *http.Request) {
+	envNS := SOAP11Namespace
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/soap+xml") {
+		envNS = SOAP12Namespace
+	}
+	body, err := readBody(r)
+	if err != nil {
+		s.fault(w, envNS, 0, soap.NewFault("soap:Client", err.Error()))
+		return
+	}
+	op := s.operation(requestAction(r), body)
+	if op == nil {
+		s.fault(w, envNS, 0, soap.NewFault("soap:Client", "no operation matches the request"))
+		return
+	}
+	s.logf("mockserver: %s", op.name)
+	if errs := s.validate(op, body); len(errs) > 0 {
+		for _, e := range errs {
+			s.logf("mockserver: %s: %s", op.name, e)
+		}
+		s.fault(w, envNS, 0, soap.NewFault("soap:Client", "invalid request: "+strings.Join(errs, "; ")))
+		return
+	}
+	if s.ResponseDir != "" {
+		ok, err := s.scripted(w, envNS, op)
+		if err != nil {
+			s.fault(w, envNS, 0, soap.NewFault("soap:Server", err.Error()))
+			return
+		}
+		if ok {
+			return
+		}
+	}
+	var b bytes.Buffer
+	if err := s.sample(&b, op); err != nil {
+		s.fault(w, envNS, 0, soap.NewFault("soap:Server", err.Error()))
+		return
+	}
+	s.write(w, envNS, http.StatusOK, b.Bytes())
+}
+
+// readBody returns the first element of the SOAP body of the request.
+func readBody(r *http.Request) (*node, error) {
+	d := xml.NewDecoder(r.Body)
+	d.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse request: %v", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "Envelope" || start.Name.Local == "Header" {
+			if start.Name.Local == "Header" {
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if start.Name.Local != "Body" {
+			return nil, fmt.Errorf("request is not a SOAP envelope")
+		}
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse request: %v", err)
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				return parseNode(d, t)
+			case xml.EndElement:
+				return nil, nil
+			}
+		}
+	}
+}
+
+// requestAction returns the SOAP action of a SOAP 1.1 or 1.2 request.
+func requestAction(r *http.Request) string {
+	if v := r.Header.Get("SOAPAction"); v != "" {
+		return strings.Trim(v, `"`)
+	}
+	for _, p := range strings.Split(r.Header.Get("Content-Type"), ";") {
+		p = strings.TrimSpace(p)
+		if strings.HasPrefix(p, "action=") {
+			return strings.Trim(p[len("action="):], `"`)
+		}
+	}
+	return ""
+}
+
+// operation returns the operation for the request with the given SOAP
+// action and body, or nil.
+func (s *Server) operation(action string, body *node) *operation {
+	if action != "" {
+		for _, op := range s.ops {
+			if op.action == action {
+				return op
+			}
+		}
+		// Client.RoundTripWithAction sends namespace/action.
+		for _, op := range s.ops {
+			if strings.HasSuffix(action, "/"+op.name) || action == op.name ||
+				(op.action != "" && strings.HasSuffix(action, "/"+op.action)) {
+				return op
+			}
+		}
+	}
+	if body == nil {
+		return nil
+	}
+	for _, op := range s.ops {
+		if op.rpc {
+			if body.name.Local == op.name {
+				return op
+			}
+			continue
+		}
+		if op.input == nil {
+			continue
+		}
+		for _, part := range op.input.Parts {
+			if part.Element != "" && trimns(part.Element) == body.name.Local {
+				return op
+			}
+		}
+	}
+	return nil
+}
+
+// validate validates the request body against the input message of op.
+func (s *Server) validate(op *operation, body *node) []string {
+	if op.input == nil || len(op.input.Parts) == 0 {
+		return nil
+	}
+	if body == nil {
+		return []string{"empty request body"}
+	}
+	v := &validator{s: s.schema}
+	if op.rpc {
+		parts := make(map[string]*node)
+		for _, c := range body.children {
+			parts[c.name.Local] = c
+		}
+		for _, part := range op.input.Parts {
+			n, ok := parts[part.Name]
+			if !ok {
+				v.errorf(op.name, "missing part %q", part.Name)
+				continue
+			}
+			v.part(n, part, op.name+"/"+part.Name)
+		}
+		return v.errs
+	}
+	for _, part := range op.input.Parts {
+		if part.Element == "" || trimns(part.Element) == body.name.Local {
+			v.part(body, part, body.name.Local)
+			break
+		}
+	}
+	return v.errs
+}
+
+func (v *validator) part(n *node, part *wsdl.Part, path string) {
+	if part.Element != "" {
+		el, ok := v.s.elements[trimns(part.Element)]
+		if !ok {
+			v.errorf(path, "element %q is not defined", part.Element)
+			return
+		}
+		v.element(n, el, path)
+		return
+	}
+	v.typed(n, part.Type, path)
+}
+
+// sample writes a sample response for op, derived from the schema.
+func (s *Server) sample(w *bytes.Buffer, op *operation) error {
+	if op.output == nil {
+		return nil
+	}
+	enc := xml.NewEncoder(w)
+	sp := &sampler{s: s.schema, enc: enc}
+	tns := xml.Attr{Name: xml.Name{Local: "xmlns:tns"}, Value: s.schema.ns}
+	if op.rpc {
+		start := xml.StartElement{
+			Name: xml.Name{Local: "tns:" + op.name + "Response"},
+			Attr: []xml.Attr{tns},
+		}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		for _, part := range op.output.Parts {
+			el := &wsdl.Element{Name: part.Name, Type: part.Type}
+			if part.Element != "" {
+				el = &wsdl.Element{Ref: part.Element}
+			}
+			if err := sp.element(el, false, 0); err != nil {
+				return err
+			}
+		}
+		if err := enc.EncodeToken(start.End()); err != nil {
+			return err
+		}
+		return enc.Flush()
+	}
+	for _, part := range op.output.Parts {
+		if part.Element == "" {
+			continue
+		}
+		el, ok := s.schema.elements[trimns(part.Element)]
+		if !ok {
+			return fmt.Errorf("element %q is not defined", part.Element)
+		}
+		if err := sp.element(el, true, 0, tns); err != nil {
+			return err
+		}
+	}
+	return enc.Flush()
+}
+
+// scripted writes the scripted response of op, if any.
+func (s *Server) scripted(w http.ResponseWriter, envNS string, op *operation) (bool, error) {
+	name := filepath.Join(s.ResponseDir, op.name)
+	data, err := ioutil.ReadFile(name + ".xml")
+	if err == nil {
+		if isEnvelope(data) {
+			w.Header().Set("Content-Type", contentType(envNS))
+			w.Write(data)
+		} else {
+			s.write(w, envNS, http.StatusOK, data)
+		}
+		return true, nil
+	}
+	if !os.IsNotExist(err) {
+		return false, err
+	}
+	data, err = ioutil.ReadFile(name + ".json")
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var sc Script
+	if err = json.Unmarshal(data, &sc); err != nil {
+		return false, fmt.Errorf("%s.json: %v", name, err)
+	}
+	for k, v := range sc.Headers {
+		w.Header().Set(k, v)
+	}
+	if sc.Fault != nil {
+		f := &soap.Fault{
+			Code:   sc.Fault.Code,
+			String: sc.Fault.String,
+			Actor:  sc.Fault.Actor,
+		}
+		if sc.Fault.Detail != "" {
+			f.Detail = &soap.FaultDetail{Content: sc.Fault.Detail}
+		}
+		s.fault(w, envNS, sc.Status, f)
+		return true, nil
+	}
+	status := sc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	s.write(w, envNS, status, []byte(sc.Body))
+	return true, nil
+}
+
+func isEnvelope(data []byte) bool {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local == "Envelope"
+		}
+	}
+}
+
+func contentType(envNS string) string {
+	if envNS == SOAP12Namespace {
+		return "application/soap+xml; charset=utf-8"
+	}
+	return "text/xml; charset=utf-8"
+}
+
+// write writes a SOAP envelope with the given body content.
+func (s *Server) write(w http.ResponseWriter, envNS string, status int, content []byte) {
+	w.Header().Set("Content-Type", contentType(envNS))
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>`+
+		`<soap:Envelope xmlns:soap=%q><soap:Body>%s</soap:Body></soap:Envelope>`,
+		envNS, content)
+}
+
+// fault12 is a SOAP 1.2 fault.
+type fault12 struct {
+	XMLName xml.Name `xml:"soap:Fault"`
+	Code    string   `xml:"soap:Code>soap:Value"`
+	Reason  struct {
+		Lang string `xml:"xml:lang,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"soap:Reason>soap:Text"`
+	Role   string            `xml:"soap:Role,omitempty"`
+	Detail *soap.FaultDetail `xml:"soap:Detail,omitempty"`
+}
+
+// faultCodes12 are the SOAP 1.2 fault codes of SOAP 1.1 fault codes.
+var faultCodes12 = map[string]string{
+	"Client":          "Sender",
+	"Server":          "Receiver",
+	"VersionMismatch": "VersionMismatch",
+	"MustUnderstand":  "MustUnderstand",
+}
+
+// fault writes a SOAP fault in the SOAP version of envNS. The SOAP 1.1
+// fault codes of f, such as soap:Client, are mapped to those of SOAP
+// 1.2, such as soap:Sender. A zero status is that of the SOAP HTTP
+// binding: 500 for SOAP 1.1 faults, and 400 for SOAP 1.2 sender faults
+// or else 500.
+func (s *Server) fault(w http.ResponseWriter, envNS string, status int, f *soap.Fault) {
+	s.logf("mockserver: %v", f)
+	var v interface{} = struct {
+		XMLName xml.Name `xml:"soap:Fault"`
+		*soap.Fault
+	}{Fault: f}
+	if envNS == SOAP12Namespace {
+		code := f.Code
+		if c, ok := faultCodes12[trimns(code)]; ok {
+			code = "soap:" + c
+		}
+		f12 := &fault12{Code: code, Role: f.Actor, Detail: f.Detail}
+		f12.Reason.Lang, f12.Reason.Text = "en", f.String
+		if status == 0 && code == "soap:Sender" {
+			status = http.StatusBadRequest
+		}
+		v = f12
+	}
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	b, err := xml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.write(w, envNS, status, b)
+}
@@ -0,0 +1,264 @@
+package mockserver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fiorix/wsdl2go/soap"
+	"github.com/fiorix/wsdl2go/wsdl"
+	"github.com/fiorix/wsdl2go/wsdlgo"
+)
+
+func newTestServer(t *testing.T, dir string) *httptest.Server {
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", "echo.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := wsdl.Unmarshal(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(d, raw)
+	s.ResponseDir = dir
+	return httptest.NewServer(s)
+}
+
+func post(t *testing.T, url, action, body string) (int, string) {
+	env := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">` +
+		`<soap:Body>` + body + `</soap:Body></soap:Envelope>`
+	req, err := http.NewRequest("POST", url, strings.NewReader(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "text/xml")
+	if action != "" {
+		req.Header.Set("SOAPAction", action)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(b)
+}
+
+func TestServeWSDL(t *testing.T) {
+	s := newTestServer(t, "")
+	defer s.Close()
+	resp, err := http.Get(s.URL + "/?wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || !bytes.Contains(b, []byte(`name="Echo"`)) {
+		t.Fatalf("unexpected response %d: %s", resp.StatusCode, b)
+	}
+}
+
+func TestServeSOAP(t *testing.T) {
+	s := newTestServer(t, filepath.Join("testdata", "responses"))
+	defer s.Close()
+	cases := []struct {
+		Action string
+		Body   string
+		Status int
+		Want   string
+	}{
+		{
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" id="1"><Text>hi</Text><Times>2</Times><Mode>loud</Mode></Echo>`,
+			Status: http.StatusOK,
+			Want:   `<tns:EchoResponse xmlns:tns="urn:echo"><tns:Text>string</tns:Text><tns:Count>5</tns:Count></tns:EchoResponse>`,
+		},
+		{
+			// elements and attributes of groups
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" traceId="7"><Text>hi</Text><Lang>en</Lang></Echo>`,
+			Status: http.StatusOK,
+			Want:   `<tns:EchoResponse`,
+		},
+		{
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" version="2.0"><Text>hi</Text></Echo>`,
+			Status: http.StatusInternalServerError,
+			Want:   `Echo/@version: value &#34;2.0&#34; differs from the fixed value &#34;1.0&#34;`,
+		},
+		{
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><Text>hi</Text><Times xsi:nil="true"/></Echo>`,
+			Status: http.StatusOK,
+			Want:   `<tns:EchoResponse`,
+		},
+		{
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><Text>hi</Text><Times xsi:nil="true">2</Times></Echo>`,
+			Status: http.StatusInternalServerError,
+			Want:   `Echo/Times: element with xsi:nil must be empty`,
+		},
+		{
+			// items of lists
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" modes="loud quiet"><Text>hi</Text></Echo>`,
+			Status: http.StatusOK,
+			Want:   `<tns:EchoResponse`,
+		},
+		{
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" modes="loud shout"><Text>hi</Text></Echo>`,
+			Status: http.StatusInternalServerError,
+			Want:   `Echo/@modes: value &#34;shout&#34; is not one of the Mode enumerations`,
+		},
+		{
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" traceId="x"><Text>hi</Text></Echo>`,
+			Status: http.StatusInternalServerError,
+			Want:   `Echo/@traceId: value &#34;x&#34; is not a valid int`,
+		},
+		{
+			// matched by the body element
+			Body:   `<Echo xmlns="urn:echo"><Text>hi</Text></Echo>`,
+			Status: http.StatusOK,
+			Want:   `<tns:EchoResponse`,
+		},
+		{
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo" id="x"><Times>two</Times><Mode>shout</Mode><Other/></Echo>`,
+			Status: http.StatusInternalServerError,
+			Want:   `Echo/@id: value &#34;x&#34; is not a valid int; Echo/Times: value &#34;two&#34; is not a valid int; Echo/Mode: value &#34;shout&#34; is not one of the Mode enumerations; Echo: unexpected element &#34;Other&#34;; Echo: missing element &#34;Text&#34;`,
+		},
+		{
+			Action: "urn:echo:Ping",
+			Body:   `<Ping xmlns="urn:echo"/>`,
+			Status: http.StatusInternalServerError,
+			Want:   `<detail><retryAfter>60</retryAfter></detail>`,
+		},
+		{
+			Action: "urn:echo:Echo",
+			Body:   `<Echo xmlns="urn:echo">`,
+			Status: http.StatusInternalServerError,
+			Want:   `<faultcode>soap:Client</faultcode><faultstring>XML syntax error`,
+		},
+		{
+			Action: "urn:echo:Unknown",
+			Body:   `<Unknown/>`,
+			Status: http.StatusInternalServerError,
+			Want:   `no operation matches the request`,
+		},
+	}
+	for i, tc := range cases {
+		status, body := post(t, s.URL, tc.Action, tc.Body)
+		if status != tc.Status {
+			t.Errorf("test %d: want status %d, have %d: %s", i, tc.Status, status, body)
+			continue
+		}
+		if !strings.Contains(body, tc.Want) {
+			t.Errorf("test %d: want %s\nhave %s", i, tc.Want, body)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	s := newTestServer(t, filepath.Join("testdata", "responses"))
+	defer s.Close()
+	type echo struct {
+		M struct {
+			Text string `xml:"Text"`
+		} `xml:"urn:echo Echo"`
+	}
+	var out struct {
+		Resp struct {
+			Text  []string `xml:"Text"`
+			Count int      `xml:"Count"`
+		} `xml:"EchoResponse"`
+	}
+	cli := &soap.Client{URL: s.URL, Namespace: "urn:echo"}
+	in := &echo{}
+	in.M.Text = "hi"
+	if err := cli.RoundTripWithAction("Echo", in, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Resp.Count != 5 || len(out.Resp.Text) != 1 {
+		t.Fatalf("unexpected response: %#v", out)
+	}
+}
+
+func TestServeSOAP12Fault(t *testing.T) {
+	s := newTestServer(t, "")
+	defer s.Close()
+	env := `<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">` +
+		`<soap:Body><Echo xmlns="urn:echo"/></soap:Body></soap:Envelope>`
+	resp, err := http.Post(s.URL, `application/soap+xml; action="urn:echo:Echo"`, strings.NewReader(env))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("want status %d, have %d: %s", http.StatusBadRequest, resp.StatusCode, b)
+	}
+	want := `<soap:Fault><soap:Code><soap:Value>soap:Sender</soap:Value></soap:Code>` +
+		`<soap:Reason><soap:Text xml:lang="en">invalid request: Echo: missing element &#34;Text&#34;</soap:Text></soap:Reason></soap:Fault>`
+	if !strings.Contains(string(b), want) {
+		t.Fatalf("want %s\nhave %s", want, b)
+	}
+}
+
+func TestRecursiveExtension(t *testing.T) {
+	d, err := wsdl.Unmarshal(strings.NewReader(`<definitions xmlns="http://schemas.xmlsoap.org/wsdl/"
+		xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:tns="urn:loop" targetNamespace="urn:loop">
+		<types><xs:schema targetNamespace="urn:loop">
+			<xs:complexType name="A"><xs:complexContent><xs:extension base="tns:B">
+				<xs:sequence><xs:element name="X" type="xs:string"/></xs:sequence>
+			</xs:extension></xs:complexContent></xs:complexType>
+			<xs:complexType name="B"><xs:complexContent><xs:extension base="tns:A">
+				<xs:sequence><xs:element name="Y" type="xs:string" minOccurs="0"/></xs:sequence>
+			</xs:extension></xs:complexContent></xs:complexType>
+		</xs:schema></types></definitions>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSchema(d)
+	fields, _, _ := s.fields(s.ctypes["A"])
+	if len(fields) != 2 || fields[0].el.Name != "Y" || fields[1].el.Name != "X" {
+		t.Fatalf("unexpected fields: %#v", fields)
+	}
+	if fields[0].min() != 0 || fields[1].min() != 1 {
+		t.Fatalf("unexpected occurrences: %d, %d", fields[0].min(), fields[1].min())
+	}
+}
+
+func TestImportedSchema(t *testing.T) {
+	name := filepath.Join("testdata", "imported.wsdl")
+	raw, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := wsdl.Unmarshal(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = wsdlgo.Import(d, name, nil); err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(New(d, raw))
+	defer s.Close()
+	status, body := post(t, s.URL, "urn:imported:Lookup", `<Lookup xmlns="urn:imported"><Key>k</Key></Lookup>`)
+	want := `<tns:LookupResponse xmlns:tns="urn:imported"><tns:Value>1</tns:Value></tns:LookupResponse>`
+	if status != http.StatusOK || !strings.Contains(body, want) {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+	status, body = post(t, s.URL, "urn:imported:Lookup", `<Lookup xmlns="urn:imported"/>`)
+	if status != http.StatusInternalServerError || !strings.Contains(body, `missing element &#34;Key&#34;`) {
+		t.Fatalf("unexpected response %d: %s", status, body)
+	}
+}
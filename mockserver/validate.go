@@ -0,0 +1,254 @@
+package mockserver
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/soap"
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// node is a parsed XML element.
+type node struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	children []*node
+	text     string
+}
+
+// parseNode parses the element that starts with start.
+func parseNode(d *xml.Decoder, start xml.StartElement) (*node, error) {
+	n := &node{name: start.Name, attrs: start.Attr}
+	var text bytes.Buffer
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			c, err := parseNode(d, t)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, c)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			n.text = strings.TrimSpace(text.String())
+			return n, nil
+		}
+	}
+}
+
+func (n *node) attr(local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Local == local && a.Name.Space != "xmlns" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// validator validates XML elements against the schema. Elements are
+// matched by local name.
+type validator struct {
+	s    *schema
+	errs []string
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, path+": "+fmt.Sprintf(format, args...))
+}
+
+// element validates n against the element declaration el.
+func (v *validator) element(n *node, el *wsdl.Element, path string) {
+	el = v.s.element(el)
+	if el.Nillable && soap.IsNil(xml.StartElement{Attr: n.attrs}) {
+		if len(n.children) > 0 || strings.TrimSpace(n.text) != "" {
+			v.errorf(path, "element with xsi:nil must be empty")
+		}
+		return
+	}
+	if ct := v.s.complexType(el); ct != nil {
+		v.complexType(n, ct, path)
+		return
+	}
+	typ := el.Type
+	if typ == "" {
+		typ = "string"
+	}
+	v.typed(n, typ, path)
+	if text := strings.TrimSpace(n.text); el.Fixed != "" && text != "" && text != el.Fixed {
+		v.errorf(path, "value %q differs from the fixed value %q", text, el.Fixed)
+	}
+}
+
+// typed validates n against the named type typ.
+func (v *validator) typed(n *node, typ, path string) {
+	if ct, ok := v.s.ctypes[trimns(typ)]; ok {
+		v.complexType(n, ct, path)
+		return
+	}
+	if len(n.children) > 0 {
+		v.errorf(path, "unexpected child element %q in simple type %s", n.children[0].name.Local, typ)
+		return
+	}
+	if err := v.value(n.text, typ); err != nil {
+		v.errorf(path, "%v", err)
+	}
+}
+
+func (v *validator) complexType(n *node, ct *wsdl.ComplexType, path string) {
+	fields, attrs, anyElement := v.s.fields(ct)
+	for _, attr := range attrs {
+		name := attr.Name
+		if name == "" {
+			name = trimns(attr.Ref)
+		}
+		val, ok := n.attr(name)
+		if !ok {
+			if attr.Use == "required" {
+				v.errorf(path, "missing attribute %q", name)
+			}
+			continue
+		}
+		if attr.Type != "" {
+			if err := v.value(val, attr.Type); err != nil {
+				v.errorf(path+"/@"+name, "%v", err)
+			}
+		}
+		if attr.Fixed != "" && val != attr.Fixed {
+			v.errorf(path+"/@"+name, "value %q differs from the fixed value %q", val, attr.Fixed)
+		}
+	}
+	if base := v.s.simpleContent(ct); base != "" {
+		if err := v.value(n.text, base); err != nil {
+			v.errorf(path, "%v", err)
+		}
+	}
+	known := make(map[string]*field, len(fields))
+	count := make(map[string]int, len(fields))
+	for _, f := range fields {
+		known[f.el.Name] = f
+	}
+	for _, c := range n.children {
+		name := c.name.Local
+		f, ok := known[name]
+		if !ok {
+			if !anyElement {
+				v.errorf(path, "unexpected element %q", name)
+			}
+			continue
+		}
+		count[name]++
+		v.element(c, f.el, path+"/"+name)
+	}
+	for _, f := range fields {
+		n := count[f.el.Name]
+		if n < f.min() {
+			v.errorf(path, "missing element %q", f.el.Name)
+		}
+		if max := f.max(); max >= 0 && n > max {
+			v.errorf(path, "element %q occurs %d times, at most %d allowed", f.el.Name, n, max)
+		}
+	}
+}
+
+var (
+	dateRE     = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}(Z|[+-]\d{2}:\d{2})?$`)
+	timeRE     = regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+	dateTimeRE = regexp.MustCompile(`^-?\d{4,}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+)
+
+// value validates the text s against the simple type typ.
+func (v *validator) value(s, typ string) error {
+	name := trimns(typ)
+	if st, ok := v.s.stypes[name]; ok {
+		switch {
+		case st.List != nil && st.List.ItemType != "":
+			for _, item := range strings.Fields(s) {
+				if err := v.value(item, st.List.ItemType); err != nil {
+					return err
+				}
+			}
+			return nil
+		case st.Union != nil:
+			members := strings.Fields(st.Union.MemberTypes)
+			for _, m := range members {
+				if v.value(s, m) == nil {
+					return nil
+				}
+			}
+			if len(members) == 0 || len(st.Union.SimpleTypes) > 0 {
+				return nil
+			}
+			return fmt.Errorf("value %q is not a valid %s", s, name)
+		case st.Restriction == nil:
+			return nil
+		}
+		if err := v.value(s, st.Restriction.Base); err != nil {
+			return err
+		}
+		if len(st.Restriction.Enum) == 0 {
+			return nil
+		}
+		for _, e := range st.Restriction.Enum {
+			if e.Value == s {
+				return nil
+			}
+		}
+		return fmt.Errorf("value %q is not one of the %s enumerations", s, name)
+	}
+	var err error
+	switch strings.ToLower(name) {
+	case "boolean":
+		switch s {
+		case "true", "false", "1", "0":
+		default:
+			err = fmt.Errorf("invalid boolean")
+		}
+	case "byte":
+		_, err = strconv.ParseInt(s, 10, 8)
+	case "short":
+		_, err = strconv.ParseInt(s, 10, 16)
+	case "int":
+		_, err = strconv.ParseInt(s, 10, 32)
+	case "long", "integer", "negativeinteger", "nonpositiveinteger":
+		_, err = strconv.ParseInt(s, 10, 64)
+	case "unsignedbyte":
+		_, err = strconv.ParseUint(s, 10, 8)
+	case "unsignedshort":
+		_, err = strconv.ParseUint(s, 10, 16)
+	case "unsignedint":
+		_, err = strconv.ParseUint(s, 10, 32)
+	case "unsignedlong", "nonnegativeinteger", "positiveinteger":
+		_, err = strconv.ParseUint(s, 10, 64)
+	case "float", "double", "decimal":
+		_, err = strconv.ParseFloat(s, 64)
+	case "date":
+		if !dateRE.MatchString(s) {
+			err = fmt.Errorf("invalid date")
+		}
+	case "time":
+		if !timeRE.MatchString(s) {
+			err = fmt.Errorf("invalid time")
+		}
+	case "datetime":
+		if !dateTimeRE.MatchString(s) {
+			err = fmt.Errorf("invalid dateTime")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("value %q is not a valid %s", s, name)
+	}
+	return nil
+}
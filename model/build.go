@@ -0,0 +1,482 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// Build builds the model of the WSDL document d, whose schema includes
+// the schemas it imports. Names are resolved in the Scope of the
+// definition they are in. References to names that are not defined
+// resolve to the definition of the same kind and local name if there is
+// only one, as many documents use prefixes loosely; other references
+// are left nil, and listed in Unresolved.
+func Build(d *wsdl.Definitions) *Definitions {
+	b := &builder{
+		d: d,
+		m: &Definitions{
+			TargetNamespace: d.TargetNamespace,
+			Messages:        make(map[Name]*Message),
+			Elements:        make(map[Name]*Element),
+			Types:           make(map[Name]*Type),
+			Groups:          make(map[Name]*wsdl.Group),
+			AttributeGroups: make(map[Name]*wsdl.AttributeGroup),
+			names:           make(map[Kind]map[string][]Name),
+		},
+		builtins:   make(map[string]*Type),
+		unresolved: make(map[Name]bool),
+	}
+	b.scope = Scope{d.Namespaces, d.Namespaces, d.TargetNamespace}
+	b.qualified = d.Schema.ElementFormDefault == "qualified"
+	b.buildSchema()
+	b.buildMessages()
+	b.buildServices()
+	return b.m
+}
+
+type builder struct {
+	d         *wsdl.Definitions
+	m         *Definitions
+	scope     Scope
+	qualified bool // whether local elements are qualified
+
+	builtins   map[string]*Type
+	unresolved map[Name]bool
+}
+
+// enter makes the namespace declarations and target namespace of a
+// global definition the scope of the names that are resolved, and
+// returns a function that restores the previous scope. Definitions
+// without them inherit those of the document.
+func (b *builder) enter(namespaces map[string]string, target string) func() {
+	prev := b.scope
+	if namespaces != nil {
+		b.scope.Namespaces = namespaces
+	}
+	if target != "" {
+		b.scope.Target = target
+	}
+	return func() { b.scope = prev }
+}
+
+// index adds name, a definition of kind k, to the definitions by local
+// name.
+func (b *builder) index(k Kind, name Name) {
+	byLocal := b.m.names[k]
+	if byLocal == nil {
+		byLocal = make(map[string][]Name)
+		b.m.names[k] = byLocal
+	}
+	for _, n := range byLocal[name.Local] {
+		if n == name {
+			return
+		}
+	}
+	byLocal[name.Local] = append(byLocal[name.Local], name)
+}
+
+// lookup returns the name that a reference to n, a definition of kind
+// k, resolves to: n if defined, or else the only definition of the same
+// local name.
+func (b *builder) lookup(k Kind, n Name, defined func(Name) bool) (Name, bool) {
+	if defined(n) {
+		return n, true
+	}
+	if names := b.m.Named(k, n.Local); len(names) == 1 {
+		return names[0], true
+	}
+	return n, false
+}
+
+func (b *builder) unresolvedName(n Name) {
+	if !b.unresolved[n] {
+		b.unresolved[n] = true
+		b.m.Unresolved = append(b.m.Unresolved, n)
+	}
+}
+
+// elementName returns the name of the global element el. Elements of
+// schemas without target namespace are in that of the document.
+func (b *builder) elementName(el *wsdl.Element) Name {
+	n := Name{Space: el.TargetNamespace, Local: el.Name}
+	if n.Space == "" {
+		n.Space = b.d.TargetNamespace
+	}
+	return n
+}
+
+func (b *builder) buildSchema() {
+	s := &b.d.Schema
+	for _, st := range s.SimpleTypes {
+		n := Name{Space: st.TargetNamespace, Local: st.Name}
+		b.m.Types[n] = &Type{Name: n, Simple: st}
+		b.index(SimpleTypeKind, n)
+	}
+	var elements []*Element
+	for _, el := range s.Elements {
+		n := b.elementName(el)
+		if _, exists := b.m.Elements[n]; exists {
+			continue
+		}
+		e := &Element{Name: n, WSDL: el}
+		if e.Anonymous() {
+			ct := *el.ComplexType
+			ct.Name, ct.Namespaces = el.Name, el.Namespaces
+			e.Type = &Type{Name: n, Complex: &ct}
+			b.index(ComplexTypeKind, n)
+		}
+		b.m.Elements[n] = e
+		b.index(ElementKind, n)
+		elements = append(elements, e)
+	}
+	for _, ct := range s.ComplexTypes {
+		n := Name{Space: ct.TargetNamespace, Local: ct.Name}
+		if t, ok := b.m.Types[n]; ok {
+			t.Complex = ct
+		} else {
+			b.m.Types[n] = &Type{Name: n, Complex: ct}
+		}
+		b.index(ComplexTypeKind, n)
+	}
+	for _, g := range s.Groups {
+		n := Name{Space: g.TargetNamespace, Local: g.Name}
+		b.m.Groups[n] = g
+		b.index(GroupKind, n)
+	}
+	for _, ag := range s.AttributeGroups {
+		n := Name{Space: ag.TargetNamespace, Local: ag.Name}
+		b.m.AttributeGroups[n] = ag
+		b.index(AttributeGroupKind, n)
+	}
+	for _, st := range s.SimpleTypes {
+		t := b.m.Types[Name{Space: st.TargetNamespace, Local: st.Name}]
+		if st.Restriction != nil && st.Restriction.Base != "" && t.Simple == st {
+			leave := b.enter(st.Namespaces, st.TargetNamespace)
+			t.Base = b.typeRef(st.Restriction.Base)
+			leave()
+		}
+	}
+	built := make(map[*wsdl.ComplexType]bool)
+	for _, ct := range s.ComplexTypes {
+		t := b.m.Types[Name{Space: ct.TargetNamespace, Local: ct.Name}]
+		if t.Complex != ct || built[ct] {
+			continue
+		}
+		built[ct] = true
+		leave := b.enter(ct.Namespaces, ct.TargetNamespace)
+		b.buildComplexType(t, ct)
+		leave()
+	}
+	for _, e := range elements {
+		leave := b.enter(e.WSDL.Namespaces, e.WSDL.TargetNamespace)
+		if e.Anonymous() {
+			b.buildComplexType(e.Type, e.Type.Complex)
+		} else {
+			b.buildElement(e)
+		}
+		leave()
+	}
+}
+
+// typeRef returns the type named s, a built-in type of XML Schema if s
+// is in its namespace, or is not defined by the schema and has no
+// prefix.
+func (b *builder) typeRef(s string) *Type {
+	n := b.scope.Name(s)
+	if n.Space == XSDNamespace {
+		return b.builtin(n.Local)
+	}
+	if t := b.lookupType(n); t != nil {
+		return t
+	}
+	if !strings.Contains(s, ":") {
+		return b.builtin(n.Local)
+	}
+	b.unresolvedName(n)
+	return nil
+}
+
+// lookupType returns the simple or complex type named n, or the
+// anonymous type of the global element named n, or else the only one of
+// them with the same local name.
+func (b *builder) lookupType(n Name) *Type {
+	if t, ok := b.m.Types[n]; ok {
+		return t
+	}
+	if el, ok := b.m.Elements[n]; ok && el.Anonymous() {
+		return el.Type
+	}
+	names := b.m.Named(SimpleTypeKind, n.Local)
+	for _, c := range b.m.Named(ComplexTypeKind, n.Local) {
+		if _, ok := b.m.Types[c]; !ok || b.m.Types[c].Simple == nil {
+			names = append(names, c)
+		}
+	}
+	if len(names) != 1 {
+		return nil
+	}
+	if t, ok := b.m.Types[names[0]]; ok {
+		return t
+	}
+	return b.m.Elements[names[0]].Type
+}
+
+func (b *builder) elementRef(s string) *Element {
+	n, ok := b.lookup(ElementKind, b.scope.Name(s), func(n Name) bool {
+		_, ok := b.m.Elements[n]
+		return ok
+	})
+	if !ok {
+		b.unresolvedName(n)
+		return nil
+	}
+	return b.m.Elements[n]
+}
+
+func (b *builder) groupRef(s string) *wsdl.Group {
+	n, ok := b.lookup(GroupKind, b.scope.Name(s), func(n Name) bool {
+		_, ok := b.m.Groups[n]
+		return ok
+	})
+	if !ok {
+		b.unresolvedName(n)
+		return nil
+	}
+	return b.m.Groups[n]
+}
+
+func (b *builder) builtin(local string) *Type {
+	t, ok := b.builtins[local]
+	if !ok {
+		t = &Type{Name: Name{Space: XSDNamespace, Local: local}}
+		b.builtins[local] = t
+	}
+	return t
+}
+
+// buildElement resolves the type of the element el, or builds its
+// anonymous type.
+func (b *builder) buildElement(el *Element) {
+	switch {
+	case el.WSDL.ComplexType != nil:
+		el.Type = &Type{Complex: el.WSDL.ComplexType}
+		b.buildComplexType(el.Type, el.WSDL.ComplexType)
+	case el.WSDL.Type != "":
+		el.Type = b.typeRef(el.WSDL.Type)
+	default:
+		el.Type = b.builtin("anyType")
+	}
+}
+
+// buildComplexType resolves the base type of the complex type ct of t,
+// and its child elements.
+func (b *builder) buildComplexType(t *Type, ct *wsdl.ComplexType) {
+	var ext *wsdl.Extension
+	if cc := ct.ComplexContent; cc != nil {
+		if cc.Extension != nil {
+			ext = cc.Extension
+		} else if cc.Restriction != nil && cc.Restriction.Base != "" {
+			t.Base = b.typeRef(cc.Restriction.Base)
+		}
+	}
+	if sc := ct.SimpleContent; sc != nil {
+		if sc.Extension != nil {
+			ext = sc.Extension
+		} else if sc.Restriction != nil && sc.Restriction.Base != "" {
+			t.Base = b.typeRef(sc.Restriction.Base)
+		}
+	}
+	seen := make(map[*wsdl.Group]bool)
+	if ext != nil {
+		if ext.Base != "" {
+			t.Base = b.typeRef(ext.Base)
+		}
+		b.sequence(t, ext.Sequence, seen)
+		b.choice(t, ext.Choice, seen)
+		b.group(t, ext.Group, seen)
+	}
+	b.elements(t, ct.AllElements)
+	b.sequence(t, ct.Sequence, seen)
+	b.choice(t, ct.Choice, seen)
+	b.group(t, ct.Group, seen)
+}
+
+func (b *builder) sequence(t *Type, seq *wsdl.Sequence, seen map[*wsdl.Group]bool) {
+	if seq == nil {
+		return
+	}
+	b.elements(t, seq.Elements)
+	for _, c := range seq.Choices {
+		b.choice(t, c, seen)
+	}
+	for _, g := range seq.Groups {
+		b.group(t, g, seen)
+	}
+}
+
+func (b *builder) choice(t *Type, c *wsdl.Choice, seen map[*wsdl.Group]bool) {
+	if c == nil {
+		return
+	}
+	b.elements(t, c.Elements)
+	for _, g := range c.Groups {
+		b.group(t, g, seen)
+	}
+}
+
+// group adds the elements of the model group g, or the group it
+// references, to t.
+func (b *builder) group(t *Type, g *wsdl.Group, seen map[*wsdl.Group]bool) {
+	if g == nil {
+		return
+	}
+	if g.Ref != "" {
+		if g = b.groupRef(g.Ref); g == nil {
+			return
+		}
+	}
+	if seen[g] {
+		return
+	}
+	seen[g] = true
+	leave := b.enter(g.Namespaces, g.TargetNamespace)
+	defer leave()
+	b.elements(t, g.AllElements)
+	b.sequence(t, g.Sequence, seen)
+	b.choice(t, g.Choice, seen)
+}
+
+// elements adds the elements l to t, resolving references to global
+// elements.
+func (b *builder) elements(t *Type, l []*wsdl.Element) {
+	for _, el := range l {
+		if el.Ref != "" {
+			if ref := b.elementRef(el.Ref); ref != nil {
+				t.Elements = append(t.Elements, ref)
+			}
+			continue
+		}
+		n := Name{Local: el.Name}
+		if b.qualified {
+			n.Space = b.scope.Target
+		}
+		e := &Element{Name: n, WSDL: el}
+		b.buildElement(e)
+		t.Elements = append(t.Elements, e)
+	}
+}
+
+// buildMessages builds the messages, whose parts are resolved in the
+// scope of the document.
+func (b *builder) buildMessages() {
+	for _, msg := range b.d.Messages {
+		n := Name{Space: b.d.TargetNamespace, Local: msg.Name}
+		m := &Message{Name: n, WSDL: msg}
+		for _, part := range msg.Parts {
+			p := &Part{Name: part.Name, WSDL: part}
+			switch {
+			case part.Element != "":
+				p.Element = b.elementRef(part.Element)
+			case part.Type != "":
+				p.Type = b.typeRef(part.Type)
+			}
+			m.Parts = append(m.Parts, p)
+		}
+		b.m.Messages[n] = m
+		b.index(MessageKind, n)
+	}
+}
+
+// message returns the message named s. The messages of imported
+// documents are merged into the document, losing their namespace, so
+// they are found by local name.
+func (b *builder) message(s string) *Message {
+	n, ok := b.lookup(MessageKind, b.scope.Name(s), func(n Name) bool {
+		_, ok := b.m.Messages[n]
+		return ok
+	})
+	if !ok {
+		b.unresolvedName(n)
+		return nil
+	}
+	return b.m.Messages[n]
+}
+
+func (b *builder) buildServices() {
+	d := b.d
+	pt := &PortType{
+		Name: Name{Space: d.TargetNamespace, Local: d.PortType.Name},
+		WSDL: &d.PortType,
+	}
+	for _, op := range d.PortType.Operations {
+		o := &Operation{Name: op.Name, Doc: op.Doc, WSDL: op}
+		if op.Input != nil {
+			o.Input = b.message(op.Input.Message)
+		}
+		if op.Output != nil {
+			o.Output = b.message(op.Output.Message)
+		}
+		pt.Operations = append(pt.Operations, o)
+	}
+	b.m.PortTypes = append(b.m.PortTypes, pt)
+
+	if d.Binding.Name == "" && len(d.Binding.Operations) == 0 {
+		return
+	}
+	bd := &Binding{
+		Name: Name{Space: d.TargetNamespace, Local: d.Binding.Name},
+		WSDL: &d.Binding,
+	}
+	if d.Binding.BindingType != nil {
+		bd.Style = d.Binding.BindingType.Style
+		bd.Transport = d.Binding.BindingType.Transport
+	}
+	if bd.Style == "" {
+		bd.Style = "document"
+	}
+	if d.Binding.Type == "" {
+		bd.PortType = pt
+	} else if n := b.scope.Name(d.Binding.Type); n == pt.Name {
+		bd.PortType = pt
+	} else {
+		b.unresolvedName(n)
+	}
+	for _, op := range d.Binding.Operations {
+		bo := &BindingOperation{Action: op.Operation.Action, SOAP12: true, WSDL: op}
+		if bo.Action == "" {
+			bo.Action, bo.SOAP12 = op.Operation11.Action, false
+		}
+		if op.Input != nil {
+			bo.InputUse = op.Input.Use
+		}
+		if op.Output != nil {
+			bo.OutputUse = op.Output.Use
+		}
+		for _, o := range pt.Operations {
+			if o.Name == op.Name {
+				bo.Operation = o
+			}
+		}
+		if bo.Operation == nil {
+			continue
+		}
+		bd.Operations = append(bd.Operations, bo)
+	}
+	b.m.Bindings = append(b.m.Bindings, bd)
+
+	svc := &Service{
+		Name: Name{Space: d.TargetNamespace, Local: d.Service.Name},
+		Doc:  d.Service.Doc,
+	}
+	for _, p := range d.Service.Ports {
+		port := &Port{Name: p.Name, Address: p.Address.Location}
+		if p.Binding != "" {
+			port.Binding = b.m.Binding(b.scope.Name(p.Binding))
+		}
+		svc.Ports = append(svc.Ports, port)
+	}
+	if len(svc.Ports) > 0 {
+		b.m.Services = append(b.m.Services, svc)
+	}
+}
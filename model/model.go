@@ -0,0 +1,261 @@
+// Package model provides a resolved model of WSDL documents, for code
+// generators.
+//
+// The wsdl package decodes documents as they are written: references
+// between definitions are prefixed names, to be resolved by the reader.
+// Build resolves them once, by namespace, into a graph of services,
+// ports, bindings, operations, messages, elements and types, so that
+// definitions with the same local name in different namespaces are
+// distinct. Generators look definitions up in the model by qualified
+// name, resolved with a Scope.
+package model
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// XSDNamespace is the namespace of the built-in types of XML Schema.
+const XSDNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// Name is a namespace qualified name.
+type Name struct {
+	Space, Local string
+}
+
+// String returns the name in the {namespace}local notation.
+func (n Name) String() string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return "{" + n.Space + "}" + n.Local
+}
+
+// Scope is the namespace context of the names in a definition: the
+// namespace declarations in scope of the definition, those of the
+// document, and the target namespace.
+type Scope struct {
+	Namespaces map[string]string
+	Document   map[string]string
+	Target     string
+}
+
+// Name resolves the prefix of the QName s. Names without prefix are in
+// the default namespace, if declared, or else in the target namespace.
+// Undeclared prefixes resolve to no namespace.
+func (sc Scope) Name(s string) Name {
+	prefix, local := "", s
+	if n := strings.Index(s, ":"); n >= 0 {
+		prefix, local = s[:n], s[n+1:]
+	}
+	if ns, ok := sc.Namespaces[prefix]; ok {
+		return Name{Space: ns, Local: local}
+	}
+	if ns, ok := sc.Document[prefix]; ok {
+		return Name{Space: ns, Local: local}
+	}
+	if prefix == "" {
+		return Name{Space: sc.Target, Local: local}
+	}
+	return Name{Local: local}
+}
+
+// Kind is the symbol space of a definition.
+type Kind string
+
+// Kinds of definitions. Simple and complex types are distinct kinds, as
+// generators map them to different Go types.
+const (
+	SimpleTypeKind     Kind = "simpleType"
+	ComplexTypeKind    Kind = "complexType"
+	ElementKind        Kind = "element"
+	GroupKind          Kind = "group"
+	AttributeGroupKind Kind = "attributeGroup"
+	MessageKind        Kind = "message"
+)
+
+// Definitions is the resolved model of a WSDL document and the schemas
+// it imports.
+type Definitions struct {
+	TargetNamespace string
+	Services        []*Service
+	Bindings        []*Binding
+	PortTypes       []*PortType
+
+	Messages        map[Name]*Message
+	Elements        map[Name]*Element // global elements
+	Types           map[Name]*Type    // global simple and complex types
+	Groups          map[Name]*wsdl.Group
+	AttributeGroups map[Name]*wsdl.AttributeGroup
+
+	// Unresolved lists the names of referenced definitions that are
+	// not defined, in the order they are found.
+	Unresolved []Name
+
+	// names of the definitions by kind and local name
+	names map[Kind]map[string][]Name
+}
+
+// Service is a set of ports.
+type Service struct {
+	Name  Name
+	Doc   string
+	Ports []*Port
+}
+
+// Port is the address of a binding.
+type Port struct {
+	Name    string
+	Address string
+	Binding *Binding
+}
+
+// Binding is the SOAP binding of the operations of a port type.
+type Binding struct {
+	Name       Name
+	PortType   *PortType
+	Style      string // document or rpc
+	Transport  string
+	Operations []*BindingOperation
+	WSDL       *wsdl.Binding
+}
+
+// BindingOperation is the SOAP binding of an operation.
+type BindingOperation struct {
+	Operation *Operation
+	Action    string
+	SOAP12    bool   // whether the action is a SOAP 1.2 action
+	InputUse  string // literal or encoded
+	OutputUse string
+	WSDL      *wsdl.BindingOperation
+}
+
+// Encoded reports whether the input or output of the operation use SOAP
+// encoding.
+func (bo *BindingOperation) Encoded() bool {
+	return bo.InputUse == "encoded" || bo.OutputUse == "encoded"
+}
+
+// PortType is a set of operations.
+type PortType struct {
+	Name       Name
+	Operations []*Operation
+	WSDL       *wsdl.PortType
+}
+
+// Operation is an abstract operation of a port type. One-way operations
+// have no output.
+type Operation struct {
+	Name   string
+	Doc    string
+	Input  *Message
+	Output *Message
+	WSDL   *wsdl.Operation
+}
+
+// Message is the input or output of operations.
+type Message struct {
+	Name  Name
+	Parts []*Part
+	WSDL  *wsdl.Message
+}
+
+// Part is a part of a message, either an element or a value of a type.
+type Part struct {
+	Name    string
+	Element *Element
+	Type    *Type
+	WSDL    *wsdl.Part
+}
+
+// Element is a schema element. The anonymous complex types of global
+// elements are named after the element, as generators declare them,
+// and those of local elements have no name.
+type Element struct {
+	Name Name
+	Type *Type
+	WSDL *wsdl.Element
+}
+
+// Type is a schema type. Built-in types of XML Schema only have a name.
+type Type struct {
+	Name    Name
+	Simple  *wsdl.SimpleType
+	Complex *wsdl.ComplexType
+
+	// Base is the type extended or restricted by the type.
+	Base *Type
+
+	// Elements are the child elements of complex types, in the order
+	// of the schema, with references resolved to global elements.
+	Elements []*Element
+}
+
+// Builtin reports whether t is a built-in type of XML Schema.
+func (t *Type) Builtin() bool {
+	return t.Name.Space == XSDNamespace
+}
+
+// Anonymous reports whether el has an anonymous complex type.
+func (el *Element) Anonymous() bool {
+	return el.WSDL.Type == "" && el.WSDL.ComplexType != nil
+}
+
+// SimpleType returns the simple type named n, or nil.
+func (d *Definitions) SimpleType(n Name) *wsdl.SimpleType {
+	if t, ok := d.Types[n]; ok {
+		return t.Simple
+	}
+	return nil
+}
+
+// ComplexType returns the complex type named n, or the anonymous type
+// of the global element named n, or nil.
+func (d *Definitions) ComplexType(n Name) *wsdl.ComplexType {
+	if t, ok := d.Types[n]; ok && t.Complex != nil {
+		return t.Complex
+	}
+	if el, ok := d.Elements[n]; ok && el.Anonymous() {
+		return el.Type.Complex
+	}
+	return nil
+}
+
+// Named returns the names of the definitions of kind k with the given
+// local name, in the order they are defined. Complex types include the
+// anonymous types of global elements.
+func (d *Definitions) Named(k Kind, local string) []Name {
+	return d.names[k][local]
+}
+
+// Binding returns the binding named name, or nil.
+func (d *Definitions) Binding(name Name) *Binding {
+	for _, b := range d.Bindings {
+		if b.Name == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// Operation returns the binding of the operation named name, or nil.
+// Operations bound more than once get the last binding.
+func (b *Binding) Operation(name string) *BindingOperation {
+	var op *BindingOperation
+	for _, bo := range b.Operations {
+		if bo.Operation.Name == name {
+			op = bo
+		}
+	}
+	return op
+}
+
+// SortedOperations returns the operations of pt sorted by name.
+func (pt *PortType) SortedOperations() []*Operation {
+	ops := make([]*Operation, len(pt.Operations))
+	copy(ops, pt.Operations)
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].Name < ops[j].Name })
+	return ops
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+func loadShop(t *testing.T) *wsdl.Definitions {
+	f, err := os.Open(filepath.Join("testdata", "shop.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := wsdl.Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a type of an imported schema, as merged by the code generator
+	d.Schema.ComplexTypes = append(d.Schema.ComplexTypes, &wsdl.ComplexType{
+		Name:            "Address",
+		TargetNamespace: "urn:crm",
+	})
+	return d
+}
+
+func TestBuildServices(t *testing.T) {
+	m := Build(loadShop(t))
+	if len(m.Services) != 1 || len(m.Services[0].Ports) != 1 {
+		t.Fatalf("unexpected services: %#v", m.Services)
+	}
+	svc := m.Services[0]
+	if want := (Name{"urn:shop", "ShopService"}); svc.Name != want {
+		t.Errorf("want service %v, have %v", want, svc.Name)
+	}
+	port := svc.Ports[0]
+	if port.Address != "http://example.com/shop" || port.Binding == nil {
+		t.Fatalf("unexpected port: %#v", port)
+	}
+	b := port.Binding
+	if b.Style != "document" || b.PortType == nil || b.PortType.Name.Local != "ShopPortType" {
+		t.Errorf("unexpected binding: %#v", b)
+	}
+	bo := b.Operation("PlaceOrder")
+	if bo == nil || bo.Action != "urn:shop:PlaceOrder" || bo.SOAP12 || bo.Encoded() {
+		t.Fatalf("unexpected binding operation: %#v", bo)
+	}
+	op := bo.Operation
+	if op.Input == nil || op.Output == nil {
+		t.Fatalf("unexpected operation: %#v", op)
+	}
+	el := op.Input.Parts[0].Element
+	if el == nil || el.Name != (Name{"urn:shop", "PlaceOrder"}) || el.Type.Name != el.Name {
+		t.Fatalf("unexpected input element: %#v", el)
+	}
+	out := op.Output.Parts[0].Element
+	if out == nil || !out.Type.Builtin() || out.Type.Name.Local != "int" {
+		t.Errorf("unexpected output element: %#v", out)
+	}
+	names := []string{}
+	for _, op := range b.PortType.SortedOperations() {
+		names = append(names, op.Name)
+	}
+	if want := []string{"Cancel", "PlaceOrder"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("want operations %v, have %v", want, names)
+	}
+}
+
+func TestBuildTypes(t *testing.T) {
+	m := Build(loadShop(t))
+	shop := m.Types[Name{"urn:shop", "Address"}]
+	crm := m.Types[Name{"urn:crm", "Address"}]
+	if shop == nil || crm == nil || shop == crm {
+		t.Fatalf("want distinct Address types, have %#v and %#v", shop, crm)
+	}
+	order := m.Types[Name{"urn:shop", "Order"}]
+	if order == nil || len(order.Elements) != 3 {
+		t.Fatalf("unexpected order type: %#v", order)
+	}
+	if order.Elements[0].Type != shop || order.Elements[1].Type != crm {
+		t.Errorf("order addresses resolved to %v and %v", order.Elements[0].Type.Name, order.Elements[1].Type.Name)
+	}
+	if order.Elements[0].Name != (Name{"urn:shop", "ShipTo"}) {
+		t.Errorf("unexpected local element name: %v", order.Elements[0].Name)
+	}
+	if note := order.Elements[2]; note != m.Elements[Name{"urn:shop", "Note"}] {
+		t.Errorf("reference resolved to %#v", note)
+	}
+	want := []Name{{"urn:shop", "Cancel"}}
+	if !reflect.DeepEqual(m.Unresolved, want) {
+		t.Errorf("want unresolved %v, have %v", want, m.Unresolved)
+	}
+}
+
+func TestBuildSchemaNamespaces(t *testing.T) {
+	doc := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:shop">
+  <types>
+    <xsd:schema targetNamespace="urn:crm" xmlns:c="urn:crm">
+      <xsd:complexType name="Address"/>
+      <xsd:element name="Contact" type="c:Address"/>
+    </xsd:schema>
+    <xsd:schema targetNamespace="urn:shop" xmlns:c="urn:shop">
+      <xsd:complexType name="Address"/>
+      <xsd:element name="ShipTo" type="c:Address"/>
+    </xsd:schema>
+  </types>
+</definitions>`
+	d, err := wsdl.Unmarshal(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Build(d)
+	for _, n := range []Name{{"urn:crm", "Contact"}, {"urn:shop", "ShipTo"}} {
+		el := m.Elements[n]
+		if el == nil || el.Type == nil {
+			t.Fatalf("element %v is not resolved", n)
+		}
+		if want := (Name{n.Space, "Address"}); el.Type.Name != want {
+			t.Errorf("element %v: want type %v, have %v", n, want, el.Type.Name)
+		}
+	}
+}
+
+func TestBuildUnresolved(t *testing.T) {
+	d := loadShop(t)
+	d.PortType.Operations[1].Input.Message = "tns:Missing"
+	d.Binding.Operations = append(d.Binding.Operations, &wsdl.BindingOperation{Name: "Missing"})
+	m := Build(d)
+	if op := m.PortTypes[0].Operations[1]; op.Input != nil {
+		t.Errorf("want unresolved input message, have %#v", op.Input)
+	}
+	if bo := m.Bindings[0].Operation("Missing"); bo != nil {
+		t.Errorf("want unbound operation, have %#v", bo)
+	}
+}
+
+func TestScopeName(t *testing.T) {
+	sc := Scope{
+		Namespaces: map[string]string{"c": "urn:crm"},
+		Document:   map[string]string{"tns": "urn:shop", "c": "urn:other"},
+		Target:     "urn:target",
+	}
+	for s, want := range map[string]Name{
+		"c:Address":    {"urn:crm", "Address"},
+		"tns:Order":    {"urn:shop", "Order"},
+		"Order":        {"urn:target", "Order"},
+		"undeclared:X": {Local: "X"},
+	} {
+		if n := sc.Name(s); n != want {
+			t.Errorf("%s: want %v, have %v", s, want, n)
+		}
+	}
+}
+
+func TestNameString(t *testing.T) {
+	if s := (Name{"urn:shop", "Order"}).String(); s != "{urn:shop}Order" {
+		t.Errorf("unexpected name: %s", s)
+	}
+	if s := (Name{Local: "Order"}).String(); s != "Order" {
+		t.Errorf("unexpected name: %s", s)
+	}
+}
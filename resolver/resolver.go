@@ -0,0 +1,136 @@
+// Package resolver locates and reads WSDL documents and schemas: files,
+// URLs, documents in zip and tar archives, and the local copies that an
+// XML catalog maps their locations to.
+//
+// The code generator, the linter and the commands of wsdl2go load the
+// documents and their imports with a Resolver, so that a location means
+// the same document to all of them.
+package resolver
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/archive"
+	"github.com/fiorix/wsdl2go/catalog"
+)
+
+// Resolver reads documents by location.
+type Resolver struct {
+	// Client fetches remote documents. Defaults to http.DefaultClient.
+	// Credentials, headers and time limits of the requests are set
+	// up in the client.
+	Client *http.Client
+
+	// Catalog, if not nil, maps the locations of documents to the
+	// locations they are read from.
+	Catalog *catalog.Catalog
+
+	// content of the archives that documents are read from
+	archives map[string][]byte
+}
+
+// Read returns the content of the document at loc, a file path, URL or
+// location in an archive, or at the location the catalog maps loc to.
+// Archives are read once.
+func (r *Resolver) Read(loc string) ([]byte, error) {
+	src, _ := r.Catalog.Resolve(loc)
+	a, name, ok := archive.Split(src)
+	if !ok {
+		return r.read(src)
+	}
+	data, cached := r.archives[a]
+	if !cached {
+		var err error
+		if data, err = r.read(a); err != nil {
+			return nil, err
+		}
+		if r.archives == nil {
+			r.archives = make(map[string][]byte)
+		}
+		r.archives[a] = data
+	}
+	return archive.Read(a, data, name)
+}
+
+// read returns the content of the file or URL src.
+func (r *Resolver) read(src string) ([]byte, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		// file paths are opened as is, with the query of ?xsd=N
+		// style locations in the file name
+		name := src
+		if u.Scheme == "file" {
+			name = u.Path
+		}
+		return ioutil.ReadFile(name)
+	}
+	cli := r.Client
+	if cli == nil {
+		cli = http.DefaultClient
+	}
+	resp, err := cli.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", src, resp.Status)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", src, err)
+	}
+	return b, nil
+}
+
+// Resolve returns the location of loc, a file path or URL, relative to
+// base, the location of the document that refers to it. Relative file
+// paths that do not exist relative to base are relative to the working
+// directory, as documents written for earlier versions of wsdl2go
+// expect. Locations relative to documents in archives are in the same
+// archive. The queries of ?wsdl and ?xsd=N style endpoint locations are
+// kept, and are part of the names of local copies.
+func Resolve(base, loc string) string {
+	if v, ok := archive.Join(base, loc); ok {
+		return v
+	}
+	u, err := url.Parse(loc)
+	if err != nil || u.IsAbs() || base == "" {
+		return loc
+	}
+	b, err := url.Parse(base)
+	if err == nil && b.IsAbs() && b.Scheme != "file" {
+		return b.ResolveReference(u).String()
+	}
+	if err == nil && b.Scheme == "file" {
+		b.Path = path.Join(path.Dir(b.Path), u.Path)
+		return b.String()
+	}
+	if filepath.IsAbs(loc) {
+		return loc
+	}
+	name := filepath.Join(filepath.Dir(base), filepath.FromSlash(u.Path))
+	if u.Path == "" {
+		// a query alone, such as ?xsd=1, refers to the same endpoint
+		name = strings.SplitN(base, "?", 2)[0]
+	}
+	if u.RawQuery != "" {
+		name += "?" + u.RawQuery
+	}
+	if _, err := os.Stat(name); err != nil {
+		if _, err := os.Stat(loc); err == nil {
+			return loc
+		}
+	}
+	return name
+}
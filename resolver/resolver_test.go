@@ -0,0 +1,87 @@
+package resolver
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fiorix/wsdl2go/catalog"
+)
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		Base, Loc, Want string
+	}{
+		{"", "a.xsd", "a.xsd"},
+		{"testdata/parts/orders.wsdl", "../types.xsd", "testdata/types.xsd"},
+		{"testdata/parts/orders.wsdl", "testdata/types.xsd", "testdata/types.xsd"},
+		{"http://example.com/a/b.wsdl", "c/d.xsd", "http://example.com/a/c/d.xsd"},
+		{"http://example.com/a/b.wsdl", "../d.xsd?xsd=1", "http://example.com/d.xsd?xsd=1"},
+		{"file:///srv/a/b.wsdl", "../c.xsd", "file:///srv/c.xsd"},
+		{"testdata/b.wsdl", "http://example.com/c.xsd", "http://example.com/c.xsd"},
+		{"http://example.com/ctx/Service?wsdl", "Service?xsd=1", "http://example.com/ctx/Service?xsd=1"},
+		{"http://example.com/ctx/Service?wsdl", "?xsd=2", "http://example.com/ctx/Service?xsd=2"},
+		{"testdata/ctx/Service?wsdl", "Service?xsd=1", "testdata/ctx/Service?xsd=1"},
+		{"testdata/ctx/Service?wsdl", "?xsd=2", "testdata/ctx/Service?xsd=2"},
+		{"contract.zip#wsdl/a.wsdl", "../xsd/b.xsd", "contract.zip#xsd/b.xsd"},
+	}
+	for _, c := range cases {
+		if have := Resolve(c.Base, c.Loc); have != c.Want {
+			t.Errorf("resolve %q against %q: want %q, have %q", c.Loc, c.Base, c.Want, have)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	f, err := zw.Create("wsdl/a.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("<definitions/>"))
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/contract.zip" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(zipped.Bytes())
+	}))
+	defer srv.Close()
+	c, err := catalog.Load("testdata/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Resolver{Catalog: c}
+	cases := []struct {
+		Loc, Want string
+	}{
+		{"testdata/parts/orders.wsdl", "<definitions"},
+		{"http://example.com/types.xsd", "<schema"},
+		{srv.URL + "/contract.zip#wsdl/a.wsdl", "<definitions/>"},
+		{srv.URL + "/contract.zip#wsdl/a.wsdl", "<definitions/>"},
+	}
+	for _, c := range cases {
+		b, err := r.Read(c.Loc)
+		if err != nil {
+			t.Fatalf("%s: %v", c.Loc, err)
+		}
+		if !strings.HasPrefix(string(b), c.Want) {
+			t.Errorf("%s: unexpected content %q", c.Loc, b)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("want the archive read once, have %d requests", requests)
+	}
+	if _, err := r.Read(srv.URL + "/missing.wsdl"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("want status error, have %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package soap
+
+import (
+	"encoding/xml"
+)
+
+// AnyElement is an XML element of any name, with its attributes and
+// content preserved. Generated code uses it for the elements matched by
+// xs:any wildcards and for types with mixed content.
+//
+// When decoded, Content is the raw inner XML of the element, and
+// Children and Text are its child elements and character data. When
+// encoded, Content is written as is if set, otherwise Text and
+// Children are.
+type AnyElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr    `xml:",any,attr"`
+	Children []*AnyElement `xml:",any"`
+	Text     string        `xml:",chardata"`
+	Content  []byte        `xml:",innerxml"`
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (a AnyElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if a.XMLName.Local != "" {
+		start.Name = a.XMLName
+	}
+	for _, attr := range a.Attrs {
+		switch {
+		case attr.Name.Space == "xmlns":
+			// namespace declarations, used by the raw content
+			attr.Name = xml.Name{Local: "xmlns:" + attr.Name.Local}
+		case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			if start.Name.Space != "" {
+				continue
+			}
+		}
+		start.Attr = append(start.Attr, attr)
+	}
+	if a.Content != nil {
+		return e.EncodeElement(struct {
+			Content []byte `xml:",innerxml"`
+		}{a.Content}, start)
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if a.Text != "" {
+		if err := e.EncodeToken(xml.CharData(a.Text)); err != nil {
+			return err
+		}
+	}
+	for _, c := range a.Children {
+		if err := e.Encode(c); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Attr returns the value of the attribute local in namespace space,
+// and whether it is set. An empty space matches any namespace.
+func (a *AnyElement) Attr(space, local string) (string, bool) {
+	for _, attr := range a.Attrs {
+		if attr.Name.Local == local && (space == "" || attr.Name.Space == space) {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// Decode decodes the element into v, usually a pointer to a generated
+// type.
+func (a *AnyElement) Decode(v interface{}) error {
+	b, err := xml.Marshal(a)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(b, v)
+}
+
+// FindElement returns the first element of l named local in namespace
+// space, or nil. An empty space matches any namespace.
+func FindElement(l []*AnyElement, space, local string) *AnyElement {
+	for _, a := range l {
+		if a.XMLName.Local == local && (space == "" || a.XMLName.Space == space) {
+			return a
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package soap
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+type testExtension struct {
+	XMLName xml.Name `xml:"urn:vendor Extension"`
+	Code    int      `xml:"Code"`
+	Note    string   `xml:"note,attr"`
+}
+
+type testBag struct {
+	XMLName xml.Name      `xml:"Bag"`
+	Name    string        `xml:"Name"`
+	Any     []*AnyElement `xml:",any"`
+}
+
+func TestAnyElement(t *testing.T) {
+	doc := `<Bag><Name>b</Name>` +
+		`<Extension xmlns="urn:vendor" xmlns:v="urn:vendor" note="n" v:id="1"><Code>42</Code><v:Tag>x</v:Tag></Extension>` +
+		`<Other>text <b>bold</b></Other></Bag>`
+	var bag testBag
+	if err := xml.Unmarshal([]byte(doc), &bag); err != nil {
+		t.Fatal(err)
+	}
+	if len(bag.Any) != 2 {
+		t.Fatalf("want 2 elements, have %d", len(bag.Any))
+	}
+	ext := FindElement(bag.Any, "urn:vendor", "Extension")
+	if ext == nil {
+		t.Fatal("extension not found")
+	}
+	if v, ok := ext.Attr("urn:vendor", "id"); !ok || v != "1" {
+		t.Fatalf("unexpected id attribute: %q", v)
+	}
+	if len(ext.Children) != 2 || ext.Children[1].XMLName.Local != "Tag" || ext.Children[1].Text != "x" {
+		t.Fatalf("unexpected children: %#v", ext.Children)
+	}
+	var e testExtension
+	if err := ext.Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Code != 42 || e.Note != "n" {
+		t.Fatalf("unexpected extension: %#v", e)
+	}
+	other := FindElement(bag.Any, "", "Other")
+	if other == nil || string(other.Content) != "text <b>bold</b>" {
+		t.Fatalf("unexpected content: %#v", other)
+	}
+
+	b, err := xml.Marshal(&bag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<Code>42</Code><v:Tag>x</v:Tag></Extension>`, `<Other>text <b>bold</b></Other>`} {
+		if !strings.Contains(string(b), want) {
+			t.Fatalf("want %s in %s", want, b)
+		}
+	}
+	var again testBag
+	if err := xml.Unmarshal(b, &again); err != nil {
+		t.Fatal(err)
+	}
+	if err := FindElement(again.Any, "urn:vendor", "Extension").Decode(&e); err != nil || e.Code != 42 {
+		t.Fatalf("unexpected extension after round trip: %#v, %v", e, err)
+	}
+}
+
+func TestAnyElementBuild(t *testing.T) {
+	a := &AnyElement{
+		XMLName: xml.Name{Local: "Note"},
+		Attrs:   []xml.Attr{{Name: xml.Name{Local: "lang"}, Value: "en"}},
+		Children: []*AnyElement{
+			{XMLName: xml.Name{Local: "Line"}, Text: "hi"},
+		},
+	}
+	b, err := xml.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<Note lang="en"><Line>hi</Line></Note>`
+	if string(b) != want {
+		t.Fatalf("want %s, have %s", want, b)
+	}
+}
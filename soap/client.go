@@ -4,24 +4,31 @@ package soap
 import (
 	"bytes"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"reflect"
-
-	"golang.org/x/net/html/charset"
 )
 
 // XSINamespace is a link to the XML Schema instance namespace.
 const XSINamespace = "http://www.w3.org/2001/XMLSchema-instance"
 
+// ErrEmptyResponse is returned when the server sends no response to an
+// operation that has output, e.g. with status 202 Accepted.
+var ErrEmptyResponse = errors.New("soap: empty response")
+
 var xmlTyperType reflect.Type = reflect.TypeOf((*XMLTyper)(nil)).Elem()
 
 // A RoundTripper executes a request passing the given req as the SOAP
 // envelope body. The HTTP response is then de-serialized onto the resp
 // object. Returns error in case an error occurs serializing req, making
-// the HTTP request, or de-serializing the response.
+// the HTTP request, or de-serializing the response. SOAP faults are
+// returned as *Fault. A nil resp is for one-way operations, whose
+// response body is ignored. For a non-nil resp, responses with an empty
+// body, such as those with status 202 Accepted or 204 No Content,
+// return ErrEmptyResponse.
 type RoundTripper interface {
 	RoundTrip(req, resp Message) error
 	RoundTripSoap12(action string, req, resp Message) error
@@ -94,7 +101,7 @@ func setXMLType(v reflect.Value) {
 	}
 }
 
-func doRoundTrip(c *Client, setHeaders func(*http.Request), in, out Message) error {
+func doRoundTrip(c *Client, setHeaders func(*http.Request), encoded bool, in, out Message) error {
 	setXMLType(reflect.ValueOf(in))
 	req := &Envelope{
 		EnvelopeAttr: c.Envelope,
@@ -105,6 +112,11 @@ func doRoundTrip(c *Client, setHeaders func(*http.Request), in, out Message) err
 		Header:       c.Header,
 		Body:         in,
 	}
+	if encoded {
+		req.EncAttr = EncodingNamespace
+		req.XSDAttr = XSDNamespace
+		req.EncodingStyle = EncodingNamespace
+	}
 
 	if req.EnvelopeAttr == "" {
 		req.EnvelopeAttr = "http://schemas.xmlsoap.org/soap/envelope/"
@@ -140,25 +152,44 @@ func doRoundTrip(c *Client, setHeaders func(*http.Request), in, out Message) err
 	if c.Post != nil {
 		c.Post(resp)
 	}
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+	default:
 		// read only the first MiB of the body in error case
 		limReader := io.LimitReader(resp.Body, 1024*1024)
 		body, _ := ioutil.ReadAll(limReader)
+		if f := decodeFault(body); f != nil {
+			return f
+		}
 		return &HTTPError{
 			StatusCode: resp.StatusCode,
 			Status:     resp.Status,
 			Msg:        string(body),
 		}
 	}
+	if out == nil {
+		// one-way operations may be acknowledged without a response
+		return nil
+	}
 
 	marshalStructure := struct {
 		XMLName xml.Name `xml:"Envelope"`
 		Body    Message
 	}{Body: out}
 
-	decoder := xml.NewDecoder(resp.Body)
-	decoder.CharsetReader = charset.NewReaderLabel
-	return decoder.Decode(&marshalStructure)
+	var body io.Reader = resp.Body
+	if encoded {
+		b, err := ResolveRefs(resp.Body)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	err = NewDecoder(body).Decode(&marshalStructure)
+	if err == io.EOF {
+		return ErrEmptyResponse
+	}
+	return err
 }
 
 // RoundTrip implements the RoundTripper interface.
@@ -185,13 +216,26 @@ func (c *Client) RoundTrip(in, out Message) error {
 			r.Header.Add("SOAPAction", actionName)
 		}
 	}
-	return doRoundTrip(c, headerFunc, in, out)
+	return doRoundTrip(c, headerFunc, false, in, out)
 }
 
 // RoundTripWithAction implements the RoundTripper interface for SOAP clients
 // that need to set the SOAPAction header.
 func (c *Client) RoundTripWithAction(soapAction string, in, out Message) error {
-	headerFunc := func(r *http.Request) {
+	return doRoundTrip(c, c.actionHeaders(soapAction, in), false, in, out)
+}
+
+// RoundTripEncoded is like RoundTripWithAction for rpc/encoded operations.
+// The envelope declares SOAP encoding, and the multi-reference values of
+// the response are resolved before it is decoded.
+func (c *Client) RoundTripEncoded(soapAction string, in, out Message) error {
+	return doRoundTrip(c, c.actionHeaders(soapAction, in), true, in, out)
+}
+
+// actionHeaders returns a function that sets the headers of requests
+// with the SOAPAction header.
+func (c *Client) actionHeaders(soapAction string, in Message) func(*http.Request) {
+	return func(r *http.Request) {
 		if c.UserAgent != "" {
 			r.Header.Add("User-Agent", c.UserAgent)
 		}
@@ -210,7 +254,6 @@ func (c *Client) RoundTripWithAction(soapAction string, in, out Message) error {
 			r.Header.Add("SOAPAction", actionName)
 		}
 	}
-	return doRoundTrip(c, headerFunc, in, out)
 }
 
 // RoundTripSoap12 implements the RoundTripper interface for SOAP 1.2.
@@ -218,7 +261,7 @@ func (c *Client) RoundTripSoap12(action string, in, out Message) error {
 	headerFunc := func(r *http.Request) {
 		r.Header.Add("Content-Type", fmt.Sprintf("application/soap+xml; charset=utf-8; action=\"%s\"", action))
 	}
-	return doRoundTrip(c, headerFunc, in, out)
+	return doRoundTrip(c, headerFunc, false, in, out)
 }
 
 // HTTPError is detailed soap http error
@@ -234,12 +277,15 @@ func (e *HTTPError) Error() string {
 
 // Envelope is a SOAP envelope.
 type Envelope struct {
-	XMLName      xml.Name `xml:"SOAP-ENV:Envelope"`
-	EnvelopeAttr string   `xml:"xmlns:SOAP-ENV,attr"`
-	NSAttr       string   `xml:"xmlns:ns,attr"`
-	TNSAttr      string   `xml:"xmlns:tns,attr,omitempty"`
-	URNAttr      string   `xml:"xmlns:urn,attr,omitempty"`
-	XSIAttr      string   `xml:"xmlns:xsi,attr,omitempty"`
-	Header       Message  `xml:"SOAP-ENV:Header"`
-	Body         Message  `xml:"SOAP-ENV:Body"`
+	XMLName       xml.Name `xml:"SOAP-ENV:Envelope"`
+	EnvelopeAttr  string   `xml:"xmlns:SOAP-ENV,attr"`
+	NSAttr        string   `xml:"xmlns:ns,attr"`
+	TNSAttr       string   `xml:"xmlns:tns,attr,omitempty"`
+	URNAttr       string   `xml:"xmlns:urn,attr,omitempty"`
+	XSIAttr       string   `xml:"xmlns:xsi,attr,omitempty"`
+	XSDAttr       string   `xml:"xmlns:xsd,attr,omitempty"`
+	EncAttr       string   `xml:"xmlns:SOAP-ENC,attr,omitempty"`
+	EncodingStyle string   `xml:"SOAP-ENV:encodingStyle,attr,omitempty"`
+	Header        Message  `xml:"SOAP-ENV:Header"`
+	Body          Message  `xml:"SOAP-ENV:Body"`
 }
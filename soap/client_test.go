@@ -1,8 +1,10 @@
 package soap
 
 import (
+	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -261,3 +263,106 @@ func TestRoundTripSoap12(t *testing.T) {
 		}
 	}
 }
+
+func TestRoundTripOneWay(t *testing.T) {
+	type msgT struct{ A, B string }
+	type envT struct{ msgT }
+	var status int
+	var body string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	})
+	s := httptest.NewServer(h)
+	defer s.Close()
+	cases := []struct {
+		Status int
+		Body   string
+		Out    Message
+		Fail   bool
+		Err    error
+	}{
+		{Status: http.StatusAccepted},
+		{Status: http.StatusNoContent},
+		{Status: http.StatusOK},
+		{Status: http.StatusOK, Body: "<Envelope><Body></Body></Envelope>"},
+		{Status: http.StatusOK, Body: "<Envelope><Body></Body></Envelope>", Out: &envT{}},
+		{Status: http.StatusAccepted, Out: &envT{}, Fail: true, Err: ErrEmptyResponse},
+		{Status: http.StatusNoContent, Out: &envT{}, Fail: true, Err: ErrEmptyResponse},
+		{Status: http.StatusOK, Out: &envT{}, Fail: true, Err: ErrEmptyResponse},
+		{Status: http.StatusOK, Body: "<Envelope", Out: &envT{}, Fail: true},
+		{Status: http.StatusInternalServerError, Fail: true},
+	}
+	for i, tc := range cases {
+		status, body = tc.Status, tc.Body
+		c := &Client{URL: s.URL}
+		err := c.RoundTripWithAction("notify", &msgT{A: "hello"}, tc.Out)
+		if tc.Fail {
+			if err == nil {
+				t.Errorf("test %d: unexpected success", i)
+			} else if tc.Err != nil && err != tc.Err {
+				t.Errorf("test %d: want %v, have %v", i, tc.Err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if tc.Out != nil && !reflect.DeepEqual(tc.Out, &envT{}) {
+			t.Errorf("test %d: response was decoded: %#v", i, tc.Out)
+		}
+	}
+}
+
+func TestRoundTripFault(t *testing.T) {
+	type msgT struct{ A, B string }
+	var body string
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, body)
+	})
+	s := httptest.NewServer(h)
+	defer s.Close()
+	cases := []struct {
+		Body  string
+		Fault *Fault
+	}{
+		{
+			Body: `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+				`<soap:Fault><faultcode>soap:Client</faultcode><faultstring>bad key</faultstring>` +
+				`<detail><reason>missing</reason></detail></soap:Fault></soap:Body></soap:Envelope>`,
+			Fault: &Fault{Code: "soap:Client", String: "bad key", Detail: &FaultDetail{Content: "<reason>missing</reason>"}},
+		},
+		{
+			Body: `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope"><env:Body>` +
+				`<env:Fault><env:Code><env:Value>env:Sender</env:Value></env:Code>` +
+				`<env:Reason><env:Text xml:lang="en">bad key</env:Text></env:Reason></env:Fault>` +
+				`</env:Body></env:Envelope>`,
+			Fault: &Fault{Code: "env:Sender", String: "bad key"},
+		},
+		{Body: "internal error"},
+	}
+	for i, tc := range cases {
+		body = tc.Body
+		c := &Client{URL: s.URL}
+		err := c.RoundTripWithAction("echo", &msgT{A: "hello"}, &msgT{})
+		if tc.Fault == nil {
+			if _, ok := err.(*HTTPError); !ok {
+				t.Errorf("test %d: want *HTTPError, have %#v", i, err)
+			}
+			continue
+		}
+		f, ok := err.(*Fault)
+		if !ok {
+			t.Errorf("test %d: want *Fault, have %#v", i, err)
+			continue
+		}
+		f.XMLName = xml.Name{}
+		if !reflect.DeepEqual(f, tc.Fault) {
+			t.Errorf("test %d: want %#v, have %#v", i, tc.Fault, f)
+		}
+	}
+}
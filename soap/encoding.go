@@ -0,0 +1,297 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// EncodingNamespace is the namespace of SOAP encoding, section 5 of the
+// SOAP 1.1 specification.
+const EncodingNamespace = "http://schemas.xmlsoap.org/soap/encoding/"
+
+// XSDNamespace is the XML Schema namespace, of the built-in types.
+const XSDNamespace = "http://www.w3.org/2001/XMLSchema"
+
+// EncodeTyped encodes v as the element start with an xsi:type attribute
+// of typ, as SOAP encoding requires for the parts of rpc/encoded
+// operations. Nil values are encoded with xsi:nil set to true. Values
+// that set their own xsi:type, generated from extension types, keep it.
+func EncodeTyped(e *xml.Encoder, start xml.StartElement, typ xml.Name, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if v == nil || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return EncodeNil(e, start)
+	}
+	if t, ok := v.(XMLTyper); ok {
+		t.SetXMLType()
+	} else {
+		start.Attr = append(start.Attr, typeAttrs("xsi:type", "tp", typ, "")...)
+	}
+	return e.EncodeElement(v, start)
+}
+
+// typeAttrs returns the attribute name set to the type typ with the
+// given suffix, and the declaration of prefix for its namespace.
+func typeAttrs(name, prefix string, typ xml.Name, suffix string) []xml.Attr {
+	if typ.Space == "" {
+		return []xml.Attr{{Name: xml.Name{Local: name}, Value: typ.Local + suffix}}
+	}
+	return []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:" + prefix}, Value: typ.Space},
+		{Name: xml.Name{Local: name}, Value: prefix + ":" + typ.Local + suffix},
+	}
+}
+
+// EncodeArray encodes items, a slice, as the SOAP-ENC:Array element
+// start with an arrayType of itemType. Each item is encoded as an item
+// element.
+func EncodeArray(e *xml.Encoder, start xml.StartElement, itemType xml.Name, items interface{}) error {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("soap: cannot encode %T as an array", items)
+	}
+	attrs := start.Attr[:0:0]
+	for _, attr := range start.Attr {
+		// the array type replaces the xsi:type of the part
+		if attr.Name.Local != "xsi:type" {
+			attrs = append(attrs, attr)
+		}
+	}
+	start.Attr = append(attrs,
+		xml.Attr{Name: xml.Name{Local: "xmlns:SOAP-ENC"}, Value: EncodingNamespace},
+		xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: "SOAP-ENC:Array"},
+	)
+	start.Attr = append(start.Attr, typeAttrs("SOAP-ENC:arrayType", "it", itemType, fmt.Sprintf("[%d]", v.Len()))...)
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	item := xml.StartElement{Name: xml.Name{Local: "item"}}
+	for i := 0; i < v.Len(); i++ {
+		if err := e.EncodeElement(v.Index(i).Interface(), item); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// DecodeArray decodes the child elements of the SOAP-ENC:Array element
+// start into items, a pointer to a slice. Items are decoded regardless
+// of their element name.
+func DecodeArray(d *xml.Decoder, start xml.StartElement, items interface{}) error {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("soap: cannot decode an array into %T", items)
+	}
+	s := v.Elem()
+	s.Set(reflect.MakeSlice(s.Type(), 0, 0))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			item := reflect.New(s.Type().Elem())
+			if err := d.DecodeElement(item.Interface(), &t); err != nil {
+				return err
+			}
+			s.Set(reflect.Append(s, item.Elem()))
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// refNode is an element of a document whose multi-reference values are
+// being resolved. Its children are *refNode or other XML tokens.
+type refNode struct {
+	start    xml.StartElement
+	children []interface{}
+}
+
+// attr returns the value of the unqualified attribute local of n.
+func (n *refNode) attr(local string) (string, bool) {
+	for _, attr := range n.start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// ResolveRefs resolves the multi-reference values of a SOAP encoded
+// document, such as the multiRef elements of Apache Axis services:
+// elements with an href attribute of the form "#id" get the attributes
+// and content of the element with that id, and referenced elements are
+// removed from the SOAP body. The document is returned as UTF-8, without
+// XML declaration.
+func ResolveRefs(r io.Reader) ([]byte, error) {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = charset.NewReaderLabel
+	root, err := parseRefs(d)
+	if err != nil {
+		return nil, err
+	}
+	rw := &refWriter{
+		ids:     make(map[string]*refNode),
+		refs:    make(map[string]bool),
+		pending: make(map[*refNode]bool),
+	}
+	rw.index(root)
+	for _, c := range root.children {
+		if err := rw.write(c, false); err != nil {
+			return nil, err
+		}
+	}
+	return rw.b.Bytes(), nil
+}
+
+// parseRefs parses the tokens of d into a tree, keeping the namespace
+// prefixes as they are.
+func parseRefs(d *xml.Decoder) (*refNode, error) {
+	root := &refNode{}
+	stack := []*refNode{root}
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &refNode{start: t.Copy()}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("soap: unexpected end element </%s>", t.Name.Local)
+			}
+			stack = stack[:len(stack)-1]
+		case xml.ProcInst:
+			// the document is written as UTF-8
+			if t.Target != "xml" {
+				top.children = append(top.children, t.Copy())
+			}
+		default:
+			top.children = append(top.children, xml.CopyToken(t))
+		}
+	}
+	if len(stack) != 1 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return root, nil
+}
+
+// refWriter writes a document tree with its references resolved.
+type refWriter struct {
+	b       bytes.Buffer
+	ids     map[string]*refNode
+	refs    map[string]bool   // referenced ids
+	pending map[*refNode]bool // elements being resolved, for cycles
+}
+
+func (rw *refWriter) index(n *refNode) {
+	if id, ok := n.attr("id"); ok {
+		rw.ids[id] = n
+	}
+	if href, ok := n.attr("href"); ok && strings.HasPrefix(href, "#") {
+		rw.refs[href[1:]] = true
+	}
+	for _, c := range n.children {
+		if c, ok := c.(*refNode); ok {
+			rw.index(c)
+		}
+	}
+}
+
+var errCyclicRef = errors.New("soap: cyclic multi-reference value")
+
+// write writes the token v. Children of the SOAP body that are
+// referenced are skipped.
+func (rw *refWriter) write(v interface{}, body bool) error {
+	switch t := v.(type) {
+	case *refNode:
+		return rw.element(t, body)
+	case xml.CharData:
+		return xml.EscapeText(&rw.b, t)
+	case xml.Comment:
+		fmt.Fprintf(&rw.b, "<!--%s-->", t)
+	case xml.ProcInst:
+		fmt.Fprintf(&rw.b, "<?%s %s?>", t.Target, t.Inst)
+	case xml.Directive:
+		fmt.Fprintf(&rw.b, "<!%s>", t)
+	}
+	return nil
+}
+
+func (rw *refWriter) element(n *refNode, body bool) error {
+	if id, ok := n.attr("id"); ok && body && rw.refs[id] {
+		return nil
+	}
+	attrs, children := n.start.Attr, n.children
+	href, _ := n.attr("href")
+	target := rw.ids[strings.TrimPrefix(href, "#")]
+	if strings.HasPrefix(href, "#") && target != nil {
+		if rw.pending[target] {
+			return errCyclicRef
+		}
+		rw.pending[target] = true
+		defer delete(rw.pending, target)
+		attrs = nil
+		for _, attr := range n.start.Attr {
+			if attr.Name.Space != "" || attr.Name.Local != "href" {
+				attrs = append(attrs, attr)
+			}
+		}
+		for _, attr := range target.start.Attr {
+			if (attr.Name.Space != "" || attr.Name.Local != "id") && !hasAttr(attrs, attr.Name) {
+				attrs = append(attrs, attr)
+			}
+		}
+		children = target.children
+	}
+	name := rawName(n.start.Name)
+	rw.b.WriteString("<" + name)
+	for _, attr := range attrs {
+		rw.b.WriteString(" " + rawName(attr.Name) + `="`)
+		if err := xml.EscapeText(&rw.b, []byte(attr.Value)); err != nil {
+			return err
+		}
+		rw.b.WriteString(`"`)
+	}
+	rw.b.WriteString(">")
+	isBody := n.start.Name.Local == "Body"
+	for _, c := range children {
+		if err := rw.write(c, isBody); err != nil {
+			return err
+		}
+	}
+	rw.b.WriteString("</" + name + ">")
+	return nil
+}
+
+func hasAttr(l []xml.Attr, name xml.Name) bool {
+	for _, attr := range l {
+		if attr.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rawName returns the name as written in the document.
+func rawName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
@@ -0,0 +1,163 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testPrices struct {
+	Items []float64 `xml:"item,omitempty"`
+}
+
+func (t testPrices) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return EncodeArray(e, start, xml.Name{Space: XSDNamespace, Local: "float"}, t.Items)
+}
+
+func (t *testPrices) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return DecodeArray(d, start, &t.Items)
+}
+
+type testQuote struct {
+	Symbol *string
+	Prices *testPrices
+}
+
+func (t testQuote) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := EncodeTyped(e, xml.StartElement{Name: xml.Name{Local: "symbol"}}, xml.Name{Space: XSDNamespace, Local: "string"}, t.Symbol); err != nil {
+		return err
+	}
+	if err := EncodeTyped(e, xml.StartElement{Name: xml.Name{Local: "prices"}}, xml.Name{Space: "urn:q", Local: "ArrayOfFloat"}, t.Prices); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+func TestEncodeTyped(t *testing.T) {
+	symbol := "ACME"
+	q := testQuote{Symbol: &symbol, Prices: &testPrices{Items: []float64{1.5, 2}}}
+	b, err := xml.Marshal(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<testQuote>` +
+		`<symbol xmlns:tp="http://www.w3.org/2001/XMLSchema" xsi:type="tp:string">ACME</symbol>` +
+		`<prices xmlns:tp="urn:q" xmlns:SOAP-ENC="http://schemas.xmlsoap.org/soap/encoding/" xsi:type="SOAP-ENC:Array" ` +
+		`xmlns:it="http://www.w3.org/2001/XMLSchema" SOAP-ENC:arrayType="it:float[2]"><item>1.5</item><item>2</item></prices>` +
+		`</testQuote>`
+	if string(b) != want {
+		t.Fatalf("want %s\nhave %s", want, b)
+	}
+	b, err = xml.Marshal(testQuote{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `<testQuote><symbol xsi:nil="true"></symbol><prices xsi:nil="true"></prices></testQuote>`
+	if string(b) != want {
+		t.Fatalf("want %s\nhave %s", want, b)
+	}
+}
+
+func TestDecodeArray(t *testing.T) {
+	var v struct {
+		Prices testPrices `xml:"prices"`
+	}
+	doc := `<r><prices SOAP-ENC:arrayType="xsd:float[2]"><float>1.5</float><item>2</item></prices></r>`
+	if err := xml.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Prices.Items) != 2 || v.Prices.Items[0] != 1.5 || v.Prices.Items[1] != 2 {
+		t.Fatalf("unexpected items: %v", v.Prices.Items)
+	}
+}
+
+const multiRefResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+ <soapenv:Body>
+  <ns1:GetQuoteResponse xmlns:ns1="urn:q">
+   <quote href="#id0"/>
+  </ns1:GetQuoteResponse>
+  <multiRef id="id0" soapenc:root="0" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/">
+   <symbol xsi:type="xsd:string">AC&amp;ME</symbol>
+   <prices href="#id1"/>
+  </multiRef>
+  <multiRef id="id1" soapenc:arrayType="xsd:float[2]" xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/">
+   <item>1.5</item>
+   <item>2</item>
+  </multiRef>
+ </soapenv:Body>
+</soapenv:Envelope>`
+
+type testQuoteResponse struct {
+	Quote struct {
+		Symbol string     `xml:"symbol"`
+		Prices testPrices `xml:"prices"`
+	} `xml:"quote"`
+}
+
+func TestResolveRefs(t *testing.T) {
+	b, err := ResolveRefs(strings.NewReader(multiRefResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("multiRef")) || bytes.Contains(b, []byte("href")) {
+		t.Fatalf("references not resolved: %s", b)
+	}
+	var env struct {
+		Body struct {
+			Response testQuoteResponse `xml:"GetQuoteResponse"`
+		}
+	}
+	if err := xml.Unmarshal(b, &env); err != nil {
+		t.Fatal(err)
+	}
+	q := env.Body.Response.Quote
+	if q.Symbol != "AC&ME" || len(q.Prices.Items) != 2 || q.Prices.Items[1] != 2 {
+		t.Fatalf("unexpected quote: %+v", q)
+	}
+
+	cyclic := `<Envelope><Body><r><a href="#a1"/></r><m id="a1"><b href="#a1"/></m></Body></Envelope>`
+	if _, err := ResolveRefs(strings.NewReader(cyclic)); err != errCyclicRef {
+		t.Fatalf("want cyclic reference error, have %v", err)
+	}
+}
+
+func TestRoundTripEncoded(t *testing.T) {
+	var req []byte
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var b bytes.Buffer
+		io.Copy(&b, r.Body)
+		req = b.Bytes()
+		io.WriteString(w, multiRefResponse)
+	}))
+	defer s.Close()
+	symbol := "ACME"
+	in := struct {
+		M testQuote `xml:"tns:GetQuote"`
+	}{testQuote{Symbol: &symbol}}
+	var out struct {
+		M testQuoteResponse `xml:"GetQuoteResponse"`
+	}
+	c := &Client{URL: s.URL, Namespace: "urn:q"}
+	if err := c.RoundTripEncoded("GetQuote", in, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`SOAP-ENV:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"`,
+		`xsi:type="tp:string">ACME</symbol>`,
+	} {
+		if !bytes.Contains(req, []byte(want)) {
+			t.Fatalf("want %s in request %s", want, req)
+		}
+	}
+	if out.M.Quote.Symbol != "AC&ME" || len(out.M.Quote.Prices.Items) != 2 {
+		t.Fatalf("unexpected response: %+v", out.M)
+	}
+}
@@ -0,0 +1,77 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Fault is a SOAP 1.1 fault. It implements the error interface so that
+// it can be returned by service methods, and by mocks to simulate the
+// faults of a remote service. The Client returns the faults of the
+// server as *Fault, with SOAP 1.2 faults mapped to the SOAP 1.1 fields.
+type Fault struct {
+	XMLName xml.Name     `xml:"Fault"`
+	Code    string       `xml:"faultcode"`
+	String  string       `xml:"faultstring"`
+	Actor   string       `xml:"faultactor,omitempty"`
+	Detail  *FaultDetail `xml:"detail,omitempty"`
+}
+
+// FaultDetail carries the application specific detail of a Fault as
+// raw XML.
+type FaultDetail struct {
+	Content string `xml:",innerxml"`
+}
+
+// NewFault creates and initializes a Fault with the given code and
+// message, e.g. NewFault("soap:Server", "internal error").
+func NewFault(code, msg string) *Fault {
+	return &Fault{Code: code, String: msg}
+}
+
+func (f *Fault) Error() string {
+	if f.Actor != "" {
+		return fmt.Sprintf("soap fault %s: %s (actor %s)", f.Code, f.String, f.Actor)
+	}
+	return fmt.Sprintf("soap fault %s: %s", f.Code, f.String)
+}
+
+// faultEnvelope is a SOAP envelope whose body is a SOAP 1.1 or 1.2
+// fault.
+type faultEnvelope struct {
+	XMLName xml.Name `xml:"Envelope"`
+	Body    struct {
+		Fault *struct {
+			Code   string       `xml:"faultcode"`
+			String string       `xml:"faultstring"`
+			Actor  string       `xml:"faultactor"`
+			Detail *FaultDetail `xml:"detail"`
+
+			// SOAP 1.2
+			Code12   string       `xml:"Code>Value"`
+			Reason12 string       `xml:"Reason>Text"`
+			Role12   string       `xml:"Role"`
+			Detail12 *FaultDetail `xml:"Detail"`
+		} `xml:"Fault"`
+	} `xml:"Body"`
+}
+
+// decodeFault returns the fault of the SOAP envelope in data, or nil if
+// data is not a SOAP fault.
+func decodeFault(data []byte) *Fault {
+	var env faultEnvelope
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = charset.NewReaderLabel
+	if err := d.Decode(&env); err != nil || env.Body.Fault == nil {
+		return nil
+	}
+	v := env.Body.Fault
+	f := &Fault{Code: v.Code, String: v.String, Actor: v.Actor, Detail: v.Detail}
+	if f.Code == "" && f.String == "" {
+		f.Code, f.String, f.Actor, f.Detail = v.Code12, v.Reason12, v.Role12, v.Detail12
+	}
+	return f
+}
@@ -0,0 +1,36 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestFault(t *testing.T) {
+	data := `<Fault>
+		<faultcode>soap:Client</faultcode>
+		<faultstring>bad request</faultstring>
+		<detail><reason>missing key</reason></detail>
+	</Fault>`
+	var f Fault
+	if err := xml.Unmarshal([]byte(data), &f); err != nil {
+		t.Fatal(err)
+	}
+	if f.Code != "soap:Client" || f.String != "bad request" {
+		t.Fatalf("unexpected fault: %#v", f)
+	}
+	if f.Detail == nil || f.Detail.Content != "<reason>missing key</reason>" {
+		t.Fatalf("unexpected fault detail: %#v", f.Detail)
+	}
+	b, err := xml.Marshal(NewFault("soap:Server", "boom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "<Fault><faultcode>soap:Server</faultcode><faultstring>boom</faultstring></Fault>"
+	if string(b) != want {
+		t.Fatalf("want %s, have %s", want, b)
+	}
+	err = NewFault("soap:Server", "boom")
+	if want, have := "soap fault soap:Server: boom", err.Error(); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+}
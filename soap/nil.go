@@ -0,0 +1,25 @@
+package soap
+
+import "encoding/xml"
+
+// IsNil reports whether the element start has the xsi:nil attribute set
+// to true.
+func IsNil(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if (attr.Name.Space == XSINamespace || attr.Name.Space == "xsi") && attr.Name.Local == "nil" {
+			return attr.Value == "true" || attr.Value == "1"
+		}
+	}
+	return false
+}
+
+// EncodeNil encodes the element start as an empty element with the
+// xsi:nil attribute set to true. The xsi prefix is declared by the SOAP
+// envelope.
+func EncodeNil(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"})
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,45 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestEncodeNil(t *testing.T) {
+	var b bytes.Buffer
+	e := xml.NewEncoder(&b)
+	if err := EncodeNil(e, xml.StartElement{Name: xml.Name{Local: "Name"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := `<Name xsi:nil="true"></Name>`
+	if b.String() != want {
+		t.Fatalf("want %s, have %s", want, b.String())
+	}
+}
+
+func TestIsNil(t *testing.T) {
+	cases := []struct {
+		Doc  string
+		Want bool
+	}{
+		{`<Name xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"/>`, true},
+		{`<Name xmlns:i="http://www.w3.org/2001/XMLSchema-instance" i:nil="1"/>`, true},
+		{`<Name xsi:nil="true"/>`, true},
+		{`<Name xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="false"/>`, false},
+		{`<Name nil="true"/>`, false},
+	}
+	for i, tc := range cases {
+		d := xml.NewDecoder(bytes.NewBufferString(tc.Doc))
+		tok, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := IsNil(tok.(xml.StartElement)); have != tc.Want {
+			t.Errorf("test %d: want %v, have %v", i, tc.Want, have)
+		}
+	}
+}
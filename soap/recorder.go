@@ -0,0 +1,327 @@
+package soap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// RecorderMode tells the Recorder whether to record or replay.
+type RecorderMode int
+
+// Recorder modes.
+const (
+	// Replay serves responses from the cassette directory and fails
+	// requests that were not recorded.
+	Replay RecorderMode = iota
+
+	// Record forwards requests to the server and saves every
+	// interaction to the cassette directory.
+	Record
+)
+
+// DefaultIgnorePaths are the paths of elements ignored when matching
+// request bodies: WS-Security nonces and timestamps and WS-Addressing
+// message ids, which change on every request.
+var DefaultIgnorePaths = []string{
+	"//Nonce",
+	"//Created",
+	"//Expires",
+	"//Timestamp",
+	"//MessageID",
+}
+
+// Recorder is an http.RoundTripper that records SOAP interactions in a
+// cassette directory and replays them, so integration tests can run
+// offline against captured traffic. It can be used as the Transport
+// of Client.Config.
+//
+// Requests are matched by SOAPAction plus a normalised body, where
+// whitespace, comments, namespace prefixes and the order of attributes
+// are ignored, as well as the elements and attributes at DefaultIgnorePaths
+// and IgnorePaths.
+type Recorder struct {
+	Dir       string            // Cassette directory
+	Mode      RecorderMode      // Record or Replay (default)
+	Transport http.RoundTripper // Optional transport used in Record mode
+
+	// IgnorePaths are additional paths ignored when matching request
+	// bodies. Paths are a subset of XPath that match local names:
+	// "/Envelope/Header/Token" is absolute, "//Token" matches
+	// anywhere, "*" matches any element and a final "@name" step
+	// matches an attribute, e.g. "//Request/@timestamp".
+	IgnorePaths []string
+}
+
+// Interaction is a recorded request and response, as stored in the
+// cassette directory.
+type Interaction struct {
+	Action   string      `json:"action"`
+	Request  string      `json:"request"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header,omitempty"`
+	Response string      `json:"response"`
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	action := requestAction(req)
+	key, err := r.key(action, body)
+	if err != nil {
+		return nil, err
+	}
+	name := filepath.Join(r.Dir, cassetteName(action, key))
+	if r.Mode == Record {
+		return r.record(req, name, action, body)
+	}
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("soap recorder: no recorded interaction for SOAPAction %q (%s), request:\n%s",
+			action, name, body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var it Interaction
+	if err = json.Unmarshal(data, &it); err != nil {
+		return nil, fmt.Errorf("soap recorder: %s: %v", name, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", it.Status, http.StatusText(it.Status)),
+		StatusCode:    it.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        it.Header,
+		Body:          ioutil.NopCloser(strings.NewReader(it.Response)),
+		ContentLength: int64(len(it.Response)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) record(req *http.Request, name, action string, body []byte) (*http.Response, error) {
+	tr := r.Transport
+	if tr == nil {
+		tr = http.DefaultTransport
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+	it := &Interaction{
+		Action:   action,
+		Request:  string(body),
+		Status:   resp.StatusCode,
+		Header:   resp.Header,
+		Response: string(data),
+	}
+	b, err := json.MarshalIndent(it, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(r.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(name, b, 0644); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// key returns the matching key of a request.
+func (r *Recorder) key(action string, body []byte) (string, error) {
+	var list []string
+	list = append(list, DefaultIgnorePaths...)
+	list = append(list, r.IgnorePaths...)
+	paths := make([]*ignorePath, len(list))
+	for i, p := range list {
+		ip, err := parseIgnorePath(p)
+		if err != nil {
+			return "", err
+		}
+		paths[i] = ip
+	}
+	norm, err := normalizeXML(body, paths)
+	if err != nil {
+		return "", fmt.Errorf("soap recorder: cannot normalise request body: %v", err)
+	}
+	h := sha256.New()
+	io.WriteString(h, action)
+	io.WriteString(h, "\n")
+	h.Write(norm)
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// requestAction returns the SOAP action of a SOAP 1.1 or 1.2 request.
+func requestAction(req *http.Request) string {
+	if v := req.Header.Get("SOAPAction"); v != "" {
+		return strings.Trim(v, `"`)
+	}
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return params["action"]
+}
+
+var invalidFileChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func cassetteName(action, key string) string {
+	name := action
+	if i := strings.LastIndexAny(name, "/:#"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = invalidFileChars.ReplaceAllString(name, "_")
+	if name == "" {
+		return key + ".json"
+	}
+	return name + "-" + key + ".json"
+}
+
+// ignorePath is a parsed IgnorePaths entry.
+type ignorePath struct {
+	anywhere bool
+	steps    []string
+	attr     string
+}
+
+func parseIgnorePath(path string) (*ignorePath, error) {
+	ip := &ignorePath{}
+	p := path
+	switch {
+	case strings.HasPrefix(p, "//"):
+		ip.anywhere = true
+		p = p[2:]
+	case strings.HasPrefix(p, "/"):
+		p = p[1:]
+	default:
+		ip.anywhere = true
+	}
+	steps := strings.Split(p, "/")
+	if last := steps[len(steps)-1]; strings.HasPrefix(last, "@") {
+		ip.attr = last[1:]
+		steps = steps[:len(steps)-1]
+	}
+	for _, s := range steps {
+		if s == "" {
+			return nil, fmt.Errorf("soap recorder: invalid ignore path %q", path)
+		}
+	}
+	ip.steps = steps
+	return ip, nil
+}
+
+// match reports whether the element stack matches the path steps.
+func (ip *ignorePath) match(stack []string) bool {
+	if len(stack) < len(ip.steps) || (!ip.anywhere && len(stack) != len(ip.steps)) {
+		return false
+	}
+	stack = stack[len(stack)-len(ip.steps):]
+	for i, s := range ip.steps {
+		if s != "*" && s != stack[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeXML returns a canonical form of the XML document in b,
+// without the content of ignored paths.
+func normalizeXML(b []byte, paths []*ignorePath) ([]byte, error) {
+	var out bytes.Buffer
+	var stack []string
+	skip := 0
+	d := xml.NewDecoder(bytes.NewReader(b))
+	d.CharsetReader = charset.NewReaderLabel
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			if skip > 0 {
+				skip++
+				continue
+			}
+			ignored := false
+			for _, p := range paths {
+				if p.attr == "" && p.match(stack) {
+					ignored = true
+					break
+				}
+			}
+			if ignored {
+				skip = 1
+				fmt.Fprintf(&out, "<{%s}%s ignored>", t.Name.Space, t.Name.Local)
+				continue
+			}
+			attrs := make([]string, 0, len(t.Attr))
+			for _, a := range t.Attr {
+				if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" || ignoredAttr(paths, stack, a.Name.Local) {
+					continue
+				}
+				attrs = append(attrs, fmt.Sprintf("{%s}%s=%q", a.Name.Space, a.Name.Local, a.Value))
+			}
+			sort.Strings(attrs)
+			fmt.Fprintf(&out, "<{%s}%s %s>", t.Name.Space, t.Name.Local, strings.Join(attrs, " "))
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			if skip > 0 {
+				skip--
+				if skip > 0 {
+					continue
+				}
+			}
+			fmt.Fprintf(&out, "</{%s}%s>", t.Name.Space, t.Name.Local)
+		case xml.CharData:
+			if skip > 0 {
+				continue
+			}
+			out.Write(bytes.TrimSpace(t))
+		}
+	}
+}
+
+func ignoredAttr(paths []*ignorePath, stack []string, name string) bool {
+	for _, p := range paths {
+		if p.attr == name && p.match(stack) {
+			return true
+		}
+	}
+	return false
+}
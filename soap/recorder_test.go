@@ -0,0 +1,126 @@
+package soap
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	type msgT struct {
+		Nonce string
+		Key   string
+		At    string `xml:"at,attr"`
+	}
+	type envT struct{ msgT }
+	dir, err := ioutil.TempDir("", "cassettes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	n := 0
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Write(b)
+	})
+	s := httptest.NewServer(echo)
+	ignore := []string{"/Envelope/Body/@at"}
+	record := &Client{
+		URL: s.URL,
+		Config: &http.Client{Transport: &Recorder{
+			Dir:         dir,
+			Mode:        Record,
+			IgnorePaths: ignore,
+		}},
+	}
+	var out envT
+	err = record.RoundTripWithAction("Get", &msgT{Nonce: "1", Key: "a", At: "10:00"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if n != 1 {
+		t.Fatalf("want 1 request to the server, have %d", n)
+	}
+	replay := &Client{
+		URL: s.URL,
+		Config: &http.Client{Transport: &Recorder{
+			Dir:         dir,
+			IgnorePaths: ignore,
+		}},
+	}
+	cases := []struct {
+		In   *msgT
+		Fail bool
+	}{
+		{In: &msgT{Nonce: "1", Key: "a", At: "10:00"}},
+		{In: &msgT{Nonce: "2", Key: "a", At: "11:00"}},
+		{In: &msgT{Nonce: "1", Key: "b"}, Fail: true},
+	}
+	for i, tc := range cases {
+		out = envT{}
+		err = replay.RoundTripWithAction("Get", tc.In, &out)
+		if tc.Fail {
+			if err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+				t.Errorf("test %d: want unmatched request error, have %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if out.Key != "a" || out.Nonce != "1" {
+			t.Errorf("test %d: unexpected response %#v", i, out.msgT)
+		}
+	}
+	// a different action is never matched
+	err = replay.RoundTripWithAction("Set", &msgT{Nonce: "1", Key: "a"}, &out)
+	if err == nil {
+		t.Errorf("want unmatched request error for a different action")
+	}
+}
+
+func TestNormalizeXML(t *testing.T) {
+	paths := []*ignorePath{}
+	for _, p := range []string{"//Nonce", "/Envelope/Body/Req/@id"} {
+		ip, err := parseIgnorePath(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, ip)
+	}
+	docs := []string{
+		`<s:Envelope xmlns:s="urn:s"><s:Body><Req id="1" b="2" a="1"><Nonce>x</Nonce></Req></s:Body></s:Envelope>`,
+		`<Envelope xmlns="urn:s">
+			<!-- comment -->
+			<Body>
+				<Req xmlns="" a="1" b="2" id="2"><Nonce><v>y</v></Nonce></Req>
+			</Body>
+		</Envelope>`,
+	}
+	var want string
+	for i, doc := range docs {
+		have, err := normalizeXML([]byte(doc), paths)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			want = string(have)
+			continue
+		}
+		if string(have) != want {
+			t.Errorf("documents differ:\nwant %s\nhave %s", want, have)
+		}
+	}
+	if _, err := parseIgnorePath("/a//b"); err == nil {
+		t.Errorf("want error for invalid path")
+	}
+	if name := cassetteName("http://example.com/Get", "abc"); name != "Get-abc.json" {
+		t.Errorf("unexpected cassette name %q", name)
+	}
+}
@@ -0,0 +1,268 @@
+package soap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// TypeRegistry maps XML type and element names to Go types. Generated
+// code uses it to decode abstract types into the concrete type named by
+// the xsi:type attribute, or by the element name of a member of a
+// substitution group.
+type TypeRegistry struct {
+	types    map[xml.Name]func() interface{}
+	elements map[xml.Name]func() interface{}
+	heads    map[xml.Name]xml.Name
+}
+
+// NewTypeRegistry creates and initializes an empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		types:    make(map[xml.Name]func() interface{}),
+		elements: make(map[xml.Name]func() interface{}),
+		heads:    make(map[xml.Name]xml.Name),
+	}
+}
+
+// RegisterType registers the constructor of the Go type for the XML
+// type name.
+func (r *TypeRegistry) RegisterType(name xml.Name, f func() interface{}) {
+	r.types[name] = f
+}
+
+// RegisterElement registers the constructor of the Go type for the
+// element name, a member of a substitution group.
+func (r *TypeRegistry) RegisterElement(name xml.Name, f func() interface{}) {
+	r.elements[name] = f
+}
+
+// RegisterSubstitute registers the constructor of the Go type for the
+// element name, a member of the substitution group of head.
+func (r *TypeRegistry) RegisterSubstitute(head, name xml.Name, f func() interface{}) {
+	r.elements[name] = f
+	r.heads[name] = head
+}
+
+// Substitute is an element of a substitution group: the name of the
+// member element, and its concrete value.
+type Substitute struct {
+	Name  xml.Name
+	Value interface{}
+}
+
+// MarshalXML implements the xml.Marshaler interface. The value is
+// encoded as the member element.
+func (s Substitute) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if s.Value == nil {
+		return nil
+	}
+	return e.EncodeElement(s.Value, xml.StartElement{Name: s.Name})
+}
+
+// DecodeSubstitute decodes the element start if it is a member of the
+// substitution group of head, at any depth. Other elements are skipped,
+// and nil is returned.
+func (r *TypeRegistry) DecodeSubstitute(d *xml.Decoder, start xml.StartElement, head xml.Name) (*Substitute, error) {
+	if !r.member(start.Name, head) {
+		return nil, d.Skip()
+	}
+	v, err := r.Decode(d, start)
+	if err != nil {
+		return nil, err
+	}
+	return &Substitute{Name: start.Name, Value: v}, nil
+}
+
+// member reports whether the element name is a member of the
+// substitution group of head. Names without namespace, of unqualified
+// elements, match by local name.
+func (r *TypeRegistry) member(name, head xml.Name) bool {
+	h, ok := r.heads[name]
+	if !ok && name.Space == "" {
+		for k, v := range r.heads {
+			if k.Local == name.Local {
+				h, ok = v, true
+				break
+			}
+		}
+	}
+	for i := 0; ok && i < len(r.heads); i++ {
+		if h == head || (name.Space == "" && h.Local == head.Local) {
+			return true
+		}
+		h, ok = r.heads[h]
+	}
+	return false
+}
+
+// Decode decodes the element start into a new value of the type named
+// by its xsi:type attribute or, without one, registered for the element
+// name.
+func (r *TypeRegistry) Decode(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	var f func() interface{}
+	if name, ok := XSIType(start); ok {
+		if f = lookup(r.types, name); f == nil {
+			return nil, fmt.Errorf("soap: element %q has unknown xsi:type %q", start.Name.Local, name.Local)
+		}
+	} else if f = lookup(r.elements, start.Name); f == nil {
+		return nil, fmt.Errorf("soap: cannot determine the type of element %q without xsi:type", start.Name.Local)
+	}
+	v := f()
+	if name, ok := expectedName(v); ok {
+		// the concrete type may expect its own element name
+		start.Name = name
+	}
+	if err := d.DecodeElement(v, &start); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// lookup returns the constructor for name, or for its local name if
+// that is unambiguous, as prefixes of xsi:type values declared on
+// ancestor elements cannot be resolved.
+func lookup(m map[xml.Name]func() interface{}, name xml.Name) func() interface{} {
+	if f, ok := m[name]; ok {
+		return f
+	}
+	var found func() interface{}
+	for k, f := range m {
+		if k.Local != name.Local {
+			continue
+		}
+		if found != nil {
+			return nil
+		}
+		found = f
+	}
+	return found
+}
+
+// XSIType returns the type name of the xsi:type attribute of start. The
+// namespace of the name is resolved for prefixes declared on start,
+// which decoders of NewDecoder declare for the prefixes in scope.
+func XSIType(start xml.StartElement) (xml.Name, bool) {
+	for _, attr := range start.Attr {
+		if (attr.Name.Space != XSINamespace && attr.Name.Space != "xsi") || attr.Name.Local != "type" {
+			continue
+		}
+		v := strings.TrimSpace(attr.Value)
+		i := strings.Index(v, ":")
+		if i < 0 {
+			return xml.Name{Local: v}, true
+		}
+		name := xml.Name{Local: v[i+1:]}
+		for _, a := range start.Attr {
+			if a.Name.Space == "xmlns" && a.Name.Local == v[:i] {
+				name.Space = a.Value
+			}
+		}
+		return name, true
+	}
+	return xml.Name{}, false
+}
+
+// expectedName returns the element name in the XMLName field tag of the
+// struct pointed to by v, if any.
+func expectedName(v interface{}) (xml.Name, bool) {
+	t := reflect.TypeOf(v)
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return xml.Name{}, false
+	}
+	f, ok := t.Elem().FieldByName("XMLName")
+	if !ok {
+		return xml.Name{}, false
+	}
+	tag := strings.Split(f.Tag.Get("xml"), ",")[0]
+	if tag == "" {
+		return xml.Name{}, false
+	}
+	if i := strings.LastIndex(tag, " "); i >= 0 {
+		return xml.Name{Space: tag[:i], Local: tag[i+1:]}, true
+	}
+	return xml.Name{Local: tag}, true
+}
+
+// EncodeElement encodes the concrete value v of an abstract type as the
+// element start. Values generated from extension types get their
+// xsi:type set.
+func EncodeElement(e *xml.Encoder, start xml.StartElement, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	if t, ok := v.(XMLTyper); ok {
+		t.SetXMLType()
+	}
+	return e.EncodeElement(v, start)
+}
+
+// NewDecoder creates an xml.Decoder of r that declares, on elements with
+// an xsi:type attribute, the namespace prefix of its value if it is
+// declared on an ancestor element, for XSIType.
+func NewDecoder(r io.Reader) *xml.Decoder {
+	raw := xml.NewDecoder(r)
+	raw.CharsetReader = charset.NewReaderLabel
+	return xml.NewTokenDecoder(&scopeReader{d: raw})
+}
+
+// scopeReader is an xml.TokenReader of raw tokens that keeps track of
+// the namespace declarations in scope.
+type scopeReader struct {
+	d      *xml.Decoder
+	scopes []map[string]string
+}
+
+func (r *scopeReader) Token() (xml.Token, error) {
+	tok, err := r.d.RawToken()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case xml.StartElement:
+		t = t.Copy()
+		declared := make(map[string]string)
+		for _, attr := range t.Attr {
+			if attr.Name.Space == "xmlns" {
+				declared[attr.Name.Local] = attr.Value
+			}
+		}
+		r.scopes = append(r.scopes, declared)
+		for _, attr := range t.Attr {
+			if attr.Name.Local != "type" || r.lookup(attr.Name.Space) != XSINamespace {
+				continue
+			}
+			i := strings.Index(attr.Value, ":")
+			if i < 0 {
+				break
+			}
+			prefix := strings.TrimSpace(attr.Value[:i])
+			if _, ok := declared[prefix]; ok {
+				break
+			}
+			if ns := r.lookup(prefix); ns != "" {
+				t.Attr = append(t.Attr, xml.Attr{Name: xml.Name{Space: "xmlns", Local: prefix}, Value: ns})
+			}
+		}
+		return t, nil
+	case xml.EndElement:
+		if n := len(r.scopes); n > 0 {
+			r.scopes = r.scopes[:n-1]
+		}
+	}
+	return xml.CopyToken(tok), nil
+}
+
+// lookup returns the namespace of prefix in scope.
+func (r *scopeReader) lookup(prefix string) string {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if ns, ok := r.scopes[i][prefix]; ok {
+			return ns
+		}
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+type testAnimal struct {
+	Value interface{}
+}
+
+var testRegistry = NewTypeRegistry()
+
+func init() {
+	testRegistry.RegisterType(xml.Name{Space: "urn:zoo", Local: "Dog"}, func() interface{} { return &testDog{} })
+	testRegistry.RegisterType(xml.Name{Space: "urn:zoo", Local: "Cat"}, func() interface{} { return &testCat{} })
+	testRegistry.RegisterElement(xml.Name{Space: "urn:zoo", Local: "Puppy"}, func() interface{} { return &testDog{} })
+	testRegistry.RegisterSubstitute(xml.Name{Space: "urn:zoo", Local: "AnyAnimal"},
+		xml.Name{Space: "urn:zoo", Local: "Kitten"}, func() interface{} { return &testCat{} })
+	testRegistry.RegisterSubstitute(xml.Name{Space: "urn:zoo", Local: "Kitten"},
+		xml.Name{Space: "urn:zoo", Local: "Lion"}, func() interface{} { return &testCat{} })
+}
+
+func (t *testAnimal) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v, err := testRegistry.Decode(d, start)
+	if err != nil {
+		return err
+	}
+	t.Value = v
+	return nil
+}
+
+func (t testAnimal) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return EncodeElement(e, start, t.Value)
+}
+
+type testDog struct {
+	XMLName     xml.Name `xml:"urn:zoo Dog"`
+	Name        string   `xml:"Name"`
+	TypeAttrXSI string   `xml:"xsi:type,attr,omitempty"`
+}
+
+func (t *testDog) SetXMLType() { t.TypeAttrXSI = "Dog" }
+
+type testCat struct {
+	Lives int `xml:"Lives"`
+}
+
+type testZoo struct {
+	XMLName xml.Name      `xml:"urn:zoo Zoo"`
+	Animals []*testAnimal `xml:",any"`
+}
+
+func TestTypeRegistry(t *testing.T) {
+	data := `<Zoo xmlns="urn:zoo" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+		<Animal xmlns:z="urn:zoo" xsi:type="z:Dog"><Name>Rex</Name></Animal>
+		<Animal xsi:type="z:Cat"><Lives>9</Lives></Animal>
+		<Puppy><Name>Bit</Name></Puppy>
+	</Zoo>`
+	var zoo testZoo
+	if err := xml.Unmarshal([]byte(data), &zoo); err != nil {
+		t.Fatal(err)
+	}
+	if len(zoo.Animals) != 3 {
+		t.Fatalf("unexpected animals: %#v", zoo.Animals)
+	}
+	if dog, ok := zoo.Animals[0].Value.(*testDog); !ok || dog.Name != "Rex" {
+		t.Fatalf("unexpected dog: %#v", zoo.Animals[0].Value)
+	}
+	if cat, ok := zoo.Animals[1].Value.(*testCat); !ok || cat.Lives != 9 {
+		t.Fatalf("unexpected cat: %#v", zoo.Animals[1].Value)
+	}
+	if dog, ok := zoo.Animals[2].Value.(*testDog); !ok || dog.Name != "Bit" {
+		t.Fatalf("unexpected puppy: %#v", zoo.Animals[2].Value)
+	}
+
+	err := xml.Unmarshal([]byte(`<Zoo xmlns="urn:zoo"><Fish/></Zoo>`), &zoo)
+	if err == nil || !strings.Contains(err.Error(), `"Fish"`) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := xml.NewEncoder(&b).Encode(&testAnimal{Value: &testDog{Name: "Rex"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<testAnimal xsi:type="Dog"><Name>Rex</Name></testAnimal>`; b.String() != want {
+		t.Fatalf("want %s, have %s", want, b.String())
+	}
+}
+
+type testAnimals []*Substitute
+
+func (g *testAnimals) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v, err := testRegistry.DecodeSubstitute(d, start, xml.Name{Space: "urn:zoo", Local: "AnyAnimal"})
+	if v != nil {
+		*g = append(*g, v)
+	}
+	return err
+}
+
+type testPark struct {
+	XMLName xml.Name    `xml:"urn:zoo Park"`
+	Animals testAnimals `xml:",any"`
+}
+
+func TestSubstitutionGroup(t *testing.T) {
+	data := `<Park xmlns="urn:zoo"><Kitten><Lives>9</Lives></Kitten><Fish/><Lion><Lives>1</Lives></Lion></Park>`
+	var park testPark
+	if err := xml.Unmarshal([]byte(data), &park); err != nil {
+		t.Fatal(err)
+	}
+	if len(park.Animals) != 2 || park.Animals[0].Name.Local != "Kitten" || park.Animals[1].Name.Local != "Lion" {
+		t.Fatalf("unexpected animals: %#v", park.Animals)
+	}
+	if cat, ok := park.Animals[1].Value.(*testCat); !ok || cat.Lives != 1 {
+		t.Fatalf("unexpected lion: %#v", park.Animals[1].Value)
+	}
+	b, err := xml.Marshal(&park)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<Park xmlns="urn:zoo"><Kitten xmlns="urn:zoo"><Lives>9</Lives></Kitten><Lion xmlns="urn:zoo"><Lives>1</Lives></Lion></Park>`
+	if string(b) != want {
+		t.Fatalf("want %s, have %s", want, b)
+	}
+}
+
+func TestDecoderScope(t *testing.T) {
+	data := `<Zoo xmlns="urn:other" xmlns:z="urn:zoo" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+		<Animal xsi:type="z:Dog"><Name>Rex</Name></Animal>
+	</Zoo>`
+	var zoo struct {
+		Animals []*testAnimal `xml:",any"`
+	}
+	if err := NewDecoder(strings.NewReader(data)).Decode(&zoo); err != nil {
+		t.Fatal(err)
+	}
+	if len(zoo.Animals) != 1 {
+		t.Fatalf("unexpected animals: %#v", zoo.Animals)
+	}
+	if dog, ok := zoo.Animals[0].Value.(*testDog); !ok || dog.Name != "Rex" {
+		t.Fatalf("unexpected dog: %#v", zoo.Animals[0].Value)
+	}
+	var start xml.StartElement
+	d := NewDecoder(strings.NewReader(data))
+	for start.Name.Local != "Animal" {
+		tok, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		start, _ = tok.(xml.StartElement)
+	}
+	if name, ok := XSIType(start); !ok || name != (xml.Name{Space: "urn:zoo", Local: "Dog"}) {
+		t.Fatalf("unexpected xsi:type %v", name)
+	}
+}
@@ -0,0 +1,205 @@
+package soap
+
+import (
+	"bytes"
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MarshalList returns the text of the list v, a slice, with its items
+// separated by a space. Generated code uses it to implement the
+// encoding.TextMarshaler interface of xs:list types.
+func MarshalList(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("soap: cannot marshal %T as a list", v)
+	}
+	var b bytes.Buffer
+	for i := 0; i < rv.Len(); i++ {
+		s, err := marshalText(rv.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s)
+	}
+	return b.Bytes(), nil
+}
+
+// UnmarshalList decodes the whitespace separated items of text into the
+// slice pointed to by v. Generated code uses it to implement the
+// encoding.TextUnmarshaler interface of xs:list types.
+func UnmarshalList(text []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("soap: cannot unmarshal a list into %T", v)
+	}
+	items := strings.Fields(string(text))
+	list := reflect.MakeSlice(rv.Elem().Type(), len(items), len(items))
+	for i, item := range items {
+		if err := unmarshalText(item, list.Index(i)); err != nil {
+			return fmt.Errorf("soap: list item %d: %v", i, err)
+		}
+	}
+	rv.Elem().Set(list)
+	return nil
+}
+
+// MarshalUnion returns the text of the first member of a union that is
+// set. Members are pointers to the values of the member types, nil if
+// not set. Generated code uses it to implement the
+// encoding.TextMarshaler interface of xs:union types.
+func MarshalUnion(members ...interface{}) ([]byte, error) {
+	for _, m := range members {
+		rv := reflect.ValueOf(m)
+		if rv.Kind() != reflect.Ptr || rv.IsNil() {
+			continue
+		}
+		s, err := marshalText(rv.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	}
+	return nil, nil
+}
+
+// UnmarshalUnion decodes text as the first member type of a union that
+// accepts it, and sets that member. Members are pointers to the pointer
+// fields of the member types. Generated code uses it to implement the
+// encoding.TextUnmarshaler interface of xs:union types.
+//
+// The lexical spaces of string-backed member types without enumerations,
+// such as xs:date, are not checked, so these types accept any text. They
+// are tried after the other member types, which approximates the member
+// order of the schema.
+func UnmarshalUnion(text []byte, members ...interface{}) error {
+	var loose []reflect.Value
+	for _, m := range members {
+		rv := reflect.ValueOf(m)
+		if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Ptr {
+			return fmt.Errorf("soap: cannot unmarshal a union member into %T", m)
+		}
+		if acceptsAnyText(rv.Elem().Type().Elem()) {
+			loose = append(loose, rv)
+			continue
+		}
+		if unmarshalMember(text, rv) {
+			return nil
+		}
+	}
+	for _, rv := range loose {
+		if unmarshalMember(text, rv) {
+			return nil
+		}
+	}
+	return fmt.Errorf("soap: %q is not a value of any member type of the union", text)
+}
+
+// unmarshalMember decodes text into a new value of the union member rv,
+// and reports whether it succeeded.
+func unmarshalMember(text []byte, rv reflect.Value) bool {
+	v := reflect.New(rv.Elem().Type().Elem())
+	if err := unmarshalText(string(text), v.Elem()); err != nil {
+		return false
+	}
+	rv.Elem().Set(v)
+	return true
+}
+
+// acceptsAnyText reports whether unmarshalText accepts any text for
+// values of type t.
+func acceptsAnyText(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) || t.Implements(validatorType) {
+		return false
+	}
+	return t.Kind() == reflect.String
+}
+
+// validator is implemented by generated simple types with enumerations.
+type validator interface {
+	Validate() bool
+}
+
+var (
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	validatorType       = reflect.TypeOf((*validator)(nil)).Elem()
+)
+
+// marshalText returns the text of the simple value v.
+func marshalText(v reflect.Value) (string, error) {
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), nil
+		}
+	}
+	return "", fmt.Errorf("soap: cannot marshal %s as text", v.Type())
+}
+
+// unmarshalText decodes s into the addressable simple value v. Values
+// of types with enumerations must be valid.
+func unmarshalText(s string, v reflect.Value) error {
+	if pv := v.Addr(); pv.Type().Implements(textUnmarshalerType) {
+		return pv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("soap: cannot unmarshal text into %s", v.Type())
+		}
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("soap: cannot unmarshal text into %s", v.Type())
+	}
+	if val, ok := v.Interface().(validator); ok && !val.Validate() {
+		return fmt.Errorf("soap: %q is not a valid %s", s, v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package soap
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+type testColor string
+
+func (v testColor) Validate() bool {
+	return v == "red" || v == "green"
+}
+
+type testSizes []int
+
+func (v testSizes) MarshalText() ([]byte, error) {
+	return MarshalList(v)
+}
+
+func (v *testSizes) UnmarshalText(text []byte) error {
+	return UnmarshalList(text, v)
+}
+
+type testSize struct {
+	Int       *int
+	TestColor *testColor
+	String    *string
+}
+
+func (v testSize) MarshalText() ([]byte, error) {
+	return MarshalUnion(v.Int, v.TestColor, v.String)
+}
+
+func (v *testSize) UnmarshalText(text []byte) error {
+	*v = testSize{}
+	return UnmarshalUnion(text, &v.Int, &v.TestColor, &v.String)
+}
+
+type testShirt struct {
+	XMLName xml.Name  `xml:"Shirt"`
+	Sizes   testSizes `xml:"Sizes,omitempty"`
+	Size    testSize  `xml:"size,attr"`
+}
+
+func TestList(t *testing.T) {
+	var s testShirt
+	err := xml.Unmarshal([]byte(`<Shirt size="1"><Sizes> 1 2
+		3 </Sizes></Shirt>`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Sizes) != 3 || s.Sizes[2] != 3 {
+		t.Fatalf("unexpected sizes: %v", s.Sizes)
+	}
+	b, err := xml.Marshal(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `<Shirt size="1"><Sizes>1 2 3</Sizes></Shirt>`
+	if string(b) != want {
+		t.Fatalf("want %s, have %s", want, b)
+	}
+	if err := xml.Unmarshal([]byte(`<Shirt><Sizes>1 x</Sizes></Shirt>`), &s); err == nil {
+		t.Fatal("want error for invalid item")
+	}
+}
+
+type testDate string
+
+type testLimit struct {
+	Float64  *float64
+	TestDate *testDate
+	Bool     *bool
+}
+
+func (v *testLimit) UnmarshalText(text []byte) error {
+	*v = testLimit{}
+	return UnmarshalUnion(text, &v.Float64, &v.TestDate, &v.Bool)
+}
+
+func TestUnionStringMemberLast(t *testing.T) {
+	var v testLimit
+	if err := v.UnmarshalText([]byte("true")); err != nil {
+		t.Fatal(err)
+	}
+	if v.Bool == nil || !*v.Bool || v.TestDate != nil {
+		t.Fatalf("want Bool set, have %+v", v)
+	}
+	if err := v.UnmarshalText([]byte("2016-01-02")); err != nil {
+		t.Fatal(err)
+	}
+	if v.TestDate == nil || *v.TestDate != "2016-01-02" || v.Bool != nil {
+		t.Fatalf("want TestDate set, have %+v", v)
+	}
+}
+
+func TestUnion(t *testing.T) {
+	cases := []struct {
+		text  string
+		check func(testSize) bool
+	}{
+		{"42", func(v testSize) bool { return v.Int != nil && *v.Int == 42 && v.TestColor == nil }},
+		{"red", func(v testSize) bool { return v.TestColor != nil && *v.TestColor == "red" && v.String == nil }},
+		{"blue", func(v testSize) bool { return v.String != nil && *v.String == "blue" && v.TestColor == nil }},
+	}
+	for i, c := range cases {
+		var v testSize
+		if err := v.UnmarshalText([]byte(c.text)); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if !c.check(v) {
+			t.Fatalf("case %d: unexpected value %+v", i, v)
+		}
+		b, err := v.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != c.text {
+			t.Fatalf("case %d: want %q, have %q", i, c.text, b)
+		}
+	}
+	var v struct {
+		Int   *int
+		Color *testColor
+	}
+	if err := UnmarshalUnion([]byte("blue"), &v.Int, &v.Color); err == nil {
+		t.Fatal("want error for a value of no member type")
+	}
+}
@@ -1,4 +1,5 @@
-// Package wsdl provides Web Services Description Language (WSDL) decoder.
+// Package wsdl provides Web Services Description Language (WSDL) decoder
+// and encoder.
 //
 // http://www.w3schools.com/xml/xml_wsdl.asp
 package wsdl
@@ -6,21 +7,168 @@ package wsdl
 import (
 	"encoding/xml"
 	"io"
+	"reflect"
 
 	"golang.org/x/net/html/charset"
 )
 
-// Unmarshal unmarshals WSDL documents starting from the <definitions> tag.
+// Unmarshal unmarshals WSDL documents starting from the <definitions> tag,
+// or the <description> tag of WSDL 2.0 documents.
 //
 // The Definitions object it returns is an unmarshalled version of the
 // WSDL XML that can be introspected to generate the Web Services API.
+// WSDL 2.0 documents are converted to it, see Description.Definitions.
+//
+// Malformed documents return a *ParseError.
 func Unmarshal(r io.Reader) (*Definitions, error) {
-	var d Definitions
-	decoder := xml.NewDecoder(r)
-	decoder.CharsetReader = charset.NewReaderLabel
-	err := decoder.Decode(&d)
+	return NewDecoder(r).Decode()
+}
+
+// A Decoder decodes a WSDL document or schema from an input stream,
+// and records the positions of its elements, complex types, messages
+// and operations in the document.
+type Decoder struct {
+	// Name is the file path or URL of the document, recorded in
+	// positions and parse errors.
+	Name string
+
+	r *positionReader
+}
+
+// NewDecoder returns a decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: &positionReader{r: r}}
+}
+
+// Decode decodes a WSDL document, like Unmarshal.
+func (dec *Decoder) Decode() (*Definitions, error) {
+	d, _, err := dec.decode(false)
+	return d, err
+}
+
+// DecodeDocument decodes either a WSDL document or, if the root element
+// is <schema>, a schema, such as the documents of schema imports.
+func (dec *Decoder) DecodeDocument() (*Definitions, *Schema, error) {
+	return dec.decode(true)
+}
+
+// Node is an element of a document as written, with its attributes,
+// children and position, for tools such as linters that check documents
+// before they are decoded into Definitions.
+type Node struct {
+	Name     xml.Name
+	Attrs    []xml.Attr // including namespace declarations
+	Children []*Node
+	Pos      Position
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (n *Node) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	n.Pos = offset(d)
+	n.Name, n.Attrs = start.Name, start.Attr
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			c := &Node{}
+			if err = c.UnmarshalXML(d, t); err != nil {
+				return err
+			}
+			n.Children = append(n.Children, c)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// DecodeNode decodes the document, whatever its root element, into a
+// tree of nodes.
+func (dec *Decoder) DecodeNode() (*Node, error) {
+	decoder, start, err := dec.root()
 	if err != nil {
 		return nil, err
 	}
-	return &d, nil
+	var n Node
+	if err := dec.decodeElement(decoder, &n, start); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// root returns the XML decoder of the document, positioned after the
+// start tag of the root element, and the start tag.
+func (dec *Decoder) root() (*xml.Decoder, *xml.StartElement, error) {
+	r, converted := utf8Reader(dec.r.r)
+	dec.r.r = r
+	decoder := xml.NewDecoder(dec.r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	if converted {
+		decoder.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) {
+			return r, nil
+		}
+	}
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, nil, dec.parseError(decoder, err)
+		}
+		if t, ok := tok.(xml.StartElement); ok {
+			return decoder, &t, nil
+		}
+	}
+}
+
+func (dec *Decoder) decode(schemas bool) (*Definitions, *Schema, error) {
+	decoder, start, err := dec.root()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case start.Name.Space == WSDL20Namespace && start.Name.Local == "description":
+		var desc Description
+		if err := dec.decodeElement(decoder, &desc, start); err != nil {
+			return nil, nil, err
+		}
+		d, err := desc.Definitions()
+		return d, nil, err
+	case schemas && start.Name.Local == "schema":
+		var s Schema
+		if err := dec.decodeElement(decoder, &s, start); err != nil {
+			return nil, nil, err
+		}
+		return nil, &s, nil
+	}
+	var d Definitions
+	if err := dec.decodeElement(decoder, &d, start); err != nil {
+		return nil, nil, err
+	}
+	return &d, nil, nil
+}
+
+// decodeElement decodes the root element start into v, and sets the
+// positions of its nodes.
+func (dec *Decoder) decodeElement(decoder *xml.Decoder, v interface{}, start *xml.StartElement) error {
+	if err := decoder.DecodeElement(v, start); err != nil {
+		return dec.parseError(decoder, err)
+	}
+	dec.r.setPositions(dec.Name, reflect.ValueOf(v))
+	return nil
+}
+
+func (dec *Decoder) parseError(decoder *xml.Decoder, err error) error {
+	return &ParseError{
+		Pos: dec.r.position(dec.Name, decoder.InputOffset()),
+		Err: err,
+	}
+}
+
+// offset returns the offset of the decoder, after the start tag of the
+// element being decoded by an UnmarshalXML method. It is recorded in
+// the Pos field of the element, and replaced by the position of the
+// start tag once the document is decoded.
+func offset(d *xml.Decoder) Position {
+	return Position{Offset: d.InputOffset()}
 }
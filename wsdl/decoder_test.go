@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -34,10 +35,253 @@ func TestUnmarshal(t *testing.T) {
 			}
 			continue
 		}
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("test %d (%q) failed: want ParseError, have %#v", i, tc.F, err)
+			continue
+		}
 		want := reflect.ValueOf(tc.E).Type().Name()
-		have := reflect.ValueOf(err).Type().Name()
+		have := reflect.ValueOf(pe.Err).Type().Name()
 		if want != have {
 			t.Errorf("test %d (%q) failed: want %q, have %q", i, tc.F, want, have)
 		}
 	}
 }
+
+func TestUnmarshalWSDL20(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "wsdl20.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.TargetNamespace != "urn:weather" || d.Namespaces["tns"] != "urn:weather" {
+		t.Errorf("unexpected namespaces: %q %v", d.TargetNamespace, d.Namespaces)
+	}
+	if len(d.Schema.Elements) != 3 {
+		t.Errorf("want 3 schema elements, have %d", len(d.Schema.Elements))
+	}
+	if d.PortType.Name != "WeatherInterface" || d.Binding.Name != "WeatherSOAPBinding" {
+		t.Errorf("unexpected port type %q or binding %q", d.PortType.Name, d.Binding.Name)
+	}
+	if len(d.Service.Ports) != 1 || d.Service.Ports[0].Address.Location != "http://example.com/weather" {
+		t.Errorf("unexpected ports: %#v", d.Service.Ports)
+	}
+	ops := d.PortType.Operations
+	if len(ops) != 2 || ops[0].Name != "Report" || ops[1].Name != "GetForecast" {
+		t.Fatalf("unexpected operations: %#v", ops)
+	}
+	if ops[0].Output != nil {
+		t.Errorf("in-only operation has an output: %#v", ops[0].Output)
+	}
+	if ops[1].Input.Message != "tns:GetForecastRequest" || ops[1].Output.Message != "tns:GetForecastResponse" {
+		t.Errorf("unexpected messages: %#v %#v", ops[1].Input, ops[1].Output)
+	}
+	want := []*Message{
+		{Name: "ReportRequest", Parts: []*Part{{Name: "parameters", Element: "tns:Report"}}},
+		{Name: "GetForecastRequest", Parts: []*Part{{Name: "parameters", Element: "tns:GetForecast"}}},
+		{Name: "GetForecastResponse", Parts: []*Part{{Name: "parameters", Element: "tns:GetForecastResponse"}}},
+	}
+	if !reflect.DeepEqual(d.Messages, want) {
+		t.Errorf("unexpected messages: %#v", d.Messages)
+	}
+	bo := d.Binding.Operations[1]
+	if bo.Operation11.Action != "urn:weather:GetForecast" || bo.Operation.Action != "" {
+		t.Errorf("unexpected SOAP 1.1 action: %#v", bo)
+	}
+}
+
+func TestUnmarshalWSDL20Pattern(t *testing.T) {
+	doc := `<description xmlns="http://www.w3.org/ns/wsdl" xmlns:tns="urn:x" targetNamespace="urn:x">
+  <interface name="I">
+    <operation name="Poll" pattern="http://www.w3.org/ns/wsdl/out-in"/>
+  </interface>
+  <binding name="B" interface="tns:I" type="http://www.w3.org/ns/wsdl/soap"/>
+</description>`
+	_, err := Unmarshal(strings.NewReader(doc))
+	if err == nil || !strings.Contains(err.Error(), "unsupported message exchange pattern") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestUnmarshalSchemaNamespaces(t *testing.T) {
+	doc := `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:shop">
+  <types>
+    <xsd:schema targetNamespace="urn:crm" xmlns:crm="urn:crm">
+      <xsd:complexType name="Address"/>
+      <xsd:element name="Customer" type="crm:Address"/>
+    </xsd:schema>
+    <xsd:schema targetNamespace="urn:shop" xmlns:s="urn:shop">
+      <xsd:complexType name="Address"/>
+      <xsd:simpleType name="Code"/>
+    </xsd:schema>
+  </types>
+</definitions>`
+	d, err := Unmarshal(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cts := d.Schema.ComplexTypes
+	if len(cts) != 2 || cts[0].TargetNamespace != "urn:crm" || cts[1].TargetNamespace != "urn:shop" {
+		t.Fatalf("unexpected complex types: %#v", cts)
+	}
+	if cts[0].Namespaces["crm"] != "urn:crm" || cts[1].Namespaces["s"] != "urn:shop" || cts[1].Namespaces["crm"] != "" {
+		t.Errorf("unexpected namespace declarations: %v %v", cts[0].Namespaces, cts[1].Namespaces)
+	}
+	el := d.Schema.Elements[0]
+	if el.TargetNamespace != "urn:crm" || el.Namespaces["crm"] != "urn:crm" {
+		t.Errorf("unexpected element namespaces: %q %v", el.TargetNamespace, el.Namespaces)
+	}
+	if st := d.Schema.SimpleTypes[0]; st.TargetNamespace != "urn:shop" {
+		t.Errorf("unexpected simple type namespace: %q", st.TargetNamespace)
+	}
+}
+
+func TestDecoderPositions(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <types>
+    <xsd:schema targetNamespace="urn:x">
+      <xsd:complexType name="Item">
+        <xsd:sequence>
+          <xsd:element name="Name" type="xsd:string"/>
+        </xsd:sequence>
+      </xsd:complexType>
+      <xsd:element name="Get" type="xsd:string"/>
+    </xsd:schema>
+  </types>
+  <message name="GetRequest"><part name="p" element="Get"/></message>
+  <portType name="P">
+    <operation name="Get">
+      <input message="GetRequest"/>
+    </operation>
+  </portType>
+</definitions>`
+	dec := NewDecoder(strings.NewReader(doc))
+	dec.Name = "service.wsdl"
+	d, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		Node string
+		Pos  Position
+		Want string
+	}{
+		{"complexType", d.Schema.ComplexTypes[0].Pos, "service.wsdl:5:7"},
+		{"local element", d.Schema.ComplexTypes[0].Sequence.Elements[0].Pos, "service.wsdl:7:11"},
+		{"element", d.Schema.Elements[0].Pos, "service.wsdl:10:7"},
+		{"message", d.Messages[0].Pos, "service.wsdl:13:3"},
+		{"operation", d.PortType.Operations[0].Pos, "service.wsdl:15:5"},
+	}
+	for _, c := range cases {
+		if c.Pos.String() != c.Want {
+			t.Errorf("%s: want %s, have %s", c.Node, c.Want, c.Pos)
+		}
+	}
+	if off := d.Messages[0].Pos.Offset; !strings.HasPrefix(doc[off:], "<message") {
+		t.Errorf("message offset %d is not at its start tag", off)
+	}
+}
+
+func TestDecoderParseError(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("<definitions>\n  <types>\n</definitions>"))
+	dec.Name = "broken.wsdl"
+	_, err := dec.Decode()
+	pe, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("want ParseError, have %#v", err)
+	}
+	if pe.Pos.Line != 3 || pe.Pos.Document != "broken.wsdl" {
+		t.Errorf("unexpected position: %s", pe.Pos)
+	}
+	if _, ok := pe.Err.(*xml.SyntaxError); !ok {
+		t.Errorf("want xml.SyntaxError, have %#v", pe.Err)
+	}
+	want := "broken.wsdl:3:15: element <types> closed by </definitions>"
+	if err.Error() != want {
+		t.Errorf("want %q, have %q", want, err)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	f, err := os.Open(filepath.Join("..", "wsdlgo", "testdata", "docs.wsdl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	d, err := Unmarshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Service.Doc != "Product catalog of the store." {
+		t.Errorf("unexpected service doc: %q", d.Service.Doc)
+	}
+	docs := d.Schema.SimpleTypes[0].Docs
+	cases := []struct {
+		Lang, Want string
+	}{
+		{"", "Color of a product."},
+		{"en-US", "Color of a product."},
+		{"pt-BR", "Cor de um produto."},
+		{"pt", "Cor de um produto."},
+		{"de", "Color of a product."},
+	}
+	for _, c := range cases {
+		if have := docs.Text(c.Lang); have != c.Want {
+			t.Errorf("lang %q: want %q, have %q", c.Lang, c.Want, have)
+		}
+	}
+	ct := d.Schema.ComplexTypes[0]
+	if !strings.HasPrefix(ct.Doc, "A product of the catalog, with its stock keeping unit") {
+		t.Errorf("unexpected complex type doc: %q", ct.Doc)
+	}
+	if ct.Attributes[0].Docs.Text("") != "Whether the product is no longer sold." {
+		t.Errorf("unexpected attribute docs: %#v", ct.Attributes[0].Docs)
+	}
+	multi := Docs{{Text: " one "}, {Lang: "en", Text: "en"}, {Text: "two"}}
+	if have := multi.Text("fr"); have != "one\n\ntwo" {
+		t.Errorf("unexpected docs without language: %q", have)
+	}
+}
+
+func TestDecoderPositionsCharset(t *testing.T) {
+	f, err := os.Open("testdata/latin1.wsdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dec := NewDecoder(f)
+	dec.Name = "latin1.wsdl"
+	d, err := dec.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Messages) != 2 || d.Messages[0].Name != "Reçu" {
+		t.Fatalf("unexpected messages: %#v", d.Messages)
+	}
+	// columns count the bytes of the document converted to UTF-8
+	if have, want := d.Messages[1].Pos.String(), "latin1.wsdl:3:67"; have != want {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
+
+func TestDecodeNode(t *testing.T) {
+	doc := "<?xml version=\"1.0\"?>\n<a xmlns=\"urn:a\" xmlns:b=\"urn:b\">\n  <b:c x=\"1\"/>\n</a>"
+	dec := NewDecoder(strings.NewReader(doc))
+	dec.Name = "a.xml"
+	n, err := dec.DecodeNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Name.Space != "urn:a" || n.Pos.String() != "a.xml:2:1" || len(n.Children) != 1 {
+		t.Fatalf("unexpected root: %#v", n)
+	}
+	c := n.Children[0]
+	if c.Name.Space != "urn:b" || c.Pos.String() != "a.xml:3:3" || len(c.Attrs) != 1 {
+		t.Fatalf("unexpected child: %#v", c)
+	}
+}
@@ -0,0 +1,88 @@
+package wsdl
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Documentation is the human readable documentation of a node, of an
+// xs:documentation element of a schema annotation, or of a
+// wsdl:documentation element.
+type Documentation struct {
+	// Lang is the xml:lang attribute, the language of the text.
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+
+	// Text is the text of the documentation, including the text of
+	// markup such as XHTML.
+	Text string `xml:",chardata"`
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. It collects
+// the text of the element and of its descendants.
+func (doc *Documentation) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "lang" && (attr.Name.Space == "xml" || attr.Name.Space == "http://www.w3.org/XML/1998/namespace") {
+			doc.Lang = attr.Value
+		}
+	}
+	var text []string
+	for depth := 1; depth > 0; {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			text = append(text, string(t))
+		}
+	}
+	doc.Text = strings.Join(text, "")
+	return nil
+}
+
+// Docs are the documentation of a node, in one or more languages.
+type Docs []*Documentation
+
+// Text returns the documentation in the language lang, such as "en" or
+// "pt-BR". Documentation in another variant of the language matches too.
+// Without documentation in that language, or if lang is empty, it
+// returns the documentation without language, or else in English, or
+// else in the first language. Several documentation elements in the
+// same language are separated by blank lines.
+func (docs Docs) Text(lang string) string {
+	if len(docs) == 0 {
+		return ""
+	}
+	match := func(f func(string) bool) string {
+		var text []string
+		for _, doc := range docs {
+			if f(doc.Lang) && strings.TrimSpace(doc.Text) != "" {
+				text = append(text, strings.TrimSpace(doc.Text))
+			}
+		}
+		return strings.Join(text, "\n\n")
+	}
+	primary := func(s string) string {
+		return strings.ToLower(strings.SplitN(s, "-", 2)[0])
+	}
+	if lang != "" {
+		if v := match(func(l string) bool { return strings.EqualFold(l, lang) }); v != "" {
+			return v
+		}
+		if v := match(func(l string) bool { return primary(l) == primary(lang) }); v != "" {
+			return v
+		}
+	}
+	if v := match(func(l string) bool { return l == "" }); v != "" {
+		return v
+	}
+	if v := match(func(l string) bool { return primary(l) == "en" }); v != "" {
+		return v
+	}
+	first := docs[0].Lang
+	return match(func(l string) bool { return l == first })
+}
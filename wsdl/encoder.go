@@ -0,0 +1,555 @@
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Namespaces of the elements of WSDL 1.1 documents.
+const (
+	WSDLNamespace   = "http://schemas.xmlsoap.org/wsdl/"
+	SOAPNamespace   = "http://schemas.xmlsoap.org/wsdl/soap/"
+	SOAP12Namespace = "http://schemas.xmlsoap.org/wsdl/soap12/"
+	XSDNamespace    = "http://www.w3.org/2001/XMLSchema"
+)
+
+// xmlNamespace is the namespace of the xml prefix, as in xml:lang.
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// Marshal writes d to w as an indented WSDL 1.1 document.
+//
+// Only what the types of this package describe is written: constructs
+// that are not decoded, such as facets other than enumerations, are
+// dropped.
+func Marshal(w io.Writer, d *Definitions) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(d); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// definitionsXML is the layout of a WSDL 1.1 document, in the order
+// of its schema.
+type definitionsXML struct {
+	XMLName         xml.Name
+	Name            string       `xml:"name,attr,omitempty"`
+	TargetNamespace string       `xml:"targetNamespace,attr,omitempty"`
+	SOAPEnv         string       `xml:"SOAP-ENV,attr,omitempty"`
+	SOAPEnc         string       `xml:"SOAP-ENC,attr,omitempty"`
+	Imports         []*Import    `xml:"import"`
+	Schemas         []*schemaXML `xml:"types>schema"`
+	Messages        []*Message   `xml:"message"`
+	PortType        *PortType    `xml:"portType"`
+	Binding         *Binding     `xml:"binding"`
+	Service         *serviceXML  `xml:"service"`
+}
+
+type schemaXML struct {
+	XMLName xml.Name
+	*schemaDup
+}
+
+type serviceXML struct {
+	Name  string     `xml:"name,attr"`
+	Docs  Docs       `xml:"documentation"`
+	Ports []*portXML `xml:"port"`
+}
+
+type portXML struct {
+	Name    string `xml:"name,attr"`
+	Binding string `xml:"binding,attr"`
+	Address *struct {
+		XMLName  xml.Name
+		Location string `xml:"location,attr"`
+	}
+}
+
+// MarshalXML implements the xml.Marshaler interface. It writes a WSDL
+// 1.1 document with the namespace declarations of d and of its schemas,
+// so that the prefixes of names in attributes, such as tns:Item, still
+// resolve. Elements are qualified by the prefixes declared for their
+// namespaces, or by new ones.
+//
+// The schema of d is written as the schemas it was decoded from, each
+// with its own target namespace and namespace declarations.
+func (def *Definitions) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := &definitionsXML{
+		XMLName:         xml.Name{Space: WSDLNamespace, Local: "definitions"},
+		Name:            def.Name,
+		TargetNamespace: def.TargetNamespace,
+		SOAPEnv:         def.SOAPEnv,
+		SOAPEnc:         def.SOAPEnc,
+		Imports:         def.Imports,
+		Messages:        def.Messages,
+	}
+	declarations := []map[string]string{def.Namespaces}
+	for _, s := range def.Schema.split() {
+		v.Schemas = append(v.Schemas, &schemaXML{
+			XMLName:   xml.Name{Space: XSDNamespace, Local: "schema"},
+			schemaDup: (*schemaDup)(s),
+		})
+		declarations = append(declarations, s.Namespaces)
+	}
+	if def.PortType.Name != "" || len(def.PortType.Operations) > 0 {
+		v.PortType = &def.PortType
+	}
+	if def.Binding.Name != "" {
+		v.Binding = &def.Binding
+	}
+	if def.Service.Name != "" || len(def.Service.Ports) > 0 {
+		v.Service = &serviceXML{Name: def.Service.Name, Docs: def.Service.Docs}
+		for _, port := range def.Service.Ports {
+			p := &portXML{Name: port.Name, Binding: port.Binding}
+			if port.Address.Location != "" {
+				p.Address = &struct {
+					XMLName  xml.Name
+					Location string `xml:"location,attr"`
+				}{port.Address.XMLName, port.Address.Location}
+				if p.Address.XMLName.Space == "" {
+					p.Address.XMLName = xml.Name{Space: def.Binding.soapNamespace(), Local: "address"}
+				}
+			}
+			v.Service.Ports = append(v.Service.Ports, p)
+		}
+	}
+	return qualify(e, v, declarations)
+}
+
+// MarshalXML implements the xml.Marshaler interface. It writes an XML
+// schema with the namespace declarations of schema, like
+// Definitions.MarshalXML. Schemas of WSDL documents, which are decoded
+// from all the schemas of the document, are written by their
+// Definitions.
+func (schema *Schema) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	v := &schemaXML{
+		XMLName:   xml.Name{Space: XSDNamespace, Local: "schema"},
+		schemaDup: (*schemaDup)(schema),
+	}
+	return qualify(e, v, []map[string]string{schema.Namespaces})
+}
+
+// split returns the schemas that the global definitions of schema were
+// decoded from: one schema per target namespace, namespace declarations
+// and form, which the definitions are stamped with. Definitions without
+// target namespace are in that of schema, and definitions that are not
+// stamped, such as those added after decoding, are in the first schema
+// of their target namespace. Imports are in all the schemas of the
+// importing target namespace, and includes in the first.
+func (schema *Schema) split() []*Schema {
+	type key struct {
+		tns        string
+		namespaces uintptr
+		form       *schemaForm
+	}
+	var schemas []*Schema
+	index := make(map[key]*Schema)
+	first := make(map[string]*Schema)
+	get := func(tns string, namespaces map[string]string, form *schemaForm) *Schema {
+		if tns == "" {
+			tns = schema.TargetNamespace
+		}
+		k := key{tns, reflect.ValueOf(namespaces).Pointer(), form}
+		if s, ok := index[k]; ok {
+			return s
+		}
+		if s, ok := first[tns]; ok && namespaces == nil && form == nil {
+			return s
+		}
+		s := &Schema{
+			TargetNamespace:      tns,
+			ElementFormDefault:   schema.ElementFormDefault,
+			AttributeFormDefault: schema.AttributeFormDefault,
+			Namespaces:           namespaces,
+		}
+		if form != nil {
+			s.ElementFormDefault, s.AttributeFormDefault = form.element, form.attribute
+		}
+		index[k] = s
+		if first[tns] == nil {
+			first[tns] = s
+		}
+		schemas = append(schemas, s)
+		return s
+	}
+	for _, v := range schema.SimpleTypes {
+		s := get(v.TargetNamespace, v.Namespaces, v.form)
+		s.SimpleTypes = append(s.SimpleTypes, v)
+	}
+	for _, v := range schema.ComplexTypes {
+		s := get(v.TargetNamespace, v.Namespaces, v.form)
+		s.ComplexTypes = append(s.ComplexTypes, v)
+	}
+	for _, v := range schema.Elements {
+		s := get(v.TargetNamespace, v.Namespaces, v.form)
+		s.Elements = append(s.Elements, v)
+	}
+	for _, v := range schema.Groups {
+		s := get(v.TargetNamespace, v.Namespaces, v.form)
+		s.Groups = append(s.Groups, v)
+	}
+	for _, v := range schema.AttributeGroups {
+		s := get(v.TargetNamespace, v.Namespaces, v.form)
+		s.AttributeGroups = append(s.AttributeGroups, v)
+	}
+	for _, v := range schema.Imports {
+		get(v.TargetNamespace, nil, nil)
+		tns := v.TargetNamespace
+		if tns == "" {
+			tns = schema.TargetNamespace
+		}
+		for _, s := range schemas {
+			if s.TargetNamespace == tns {
+				s.Imports = append(s.Imports, v)
+			}
+		}
+	}
+	for _, v := range schema.Includes {
+		s := get(v.TargetNamespace, nil, nil)
+		s.Includes = append(s.Includes, v)
+	}
+	if len(schemas) == 0 && (schema.TargetNamespace != "" || len(schema.Namespaces) > 0) {
+		get("", schema.Namespaces, nil)
+	}
+	return schemas
+}
+
+// MarshalXML implements the xml.Marshaler interface. It writes the
+// particles of the sequence in document order, see Group.Position.
+// Complex types directly in sequences are not valid schema, and are
+// not written.
+func (seq *Sequence) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	encode := func(v interface{}, local string) error {
+		return e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: local}})
+	}
+	n := len(seq.Elements)
+	at := func(pos, i int) bool {
+		return pos == i || (i == n && pos > n)
+	}
+	for i := 0; i <= n; i++ {
+		for _, g := range seq.Groups {
+			if at(g.Position, i) {
+				if err := encode(g, "group"); err != nil {
+					return err
+				}
+			}
+		}
+		for _, c := range seq.Choices {
+			if at(c.Position, i) {
+				if err := encode(c, "choice"); err != nil {
+					return err
+				}
+			}
+		}
+		for _, any := range seq.Any {
+			if at(any.Position, i) {
+				if err := encode(any, "any"); err != nil {
+					return err
+				}
+			}
+		}
+		if i < n {
+			if err := encode(seq.Elements[i], "element"); err != nil {
+				return err
+			}
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+type bindingXML struct {
+	Name        string                 `xml:"name,attr"`
+	Type        string                 `xml:"type,attr"`
+	BindingType *BindingType           `xml:"binding"`
+	Operations  []*bindingOperationXML `xml:"operation"`
+}
+
+type bindingOperationXML struct {
+	Name        string           `xml:"name,attr"`
+	Operation   *SOAP12Operation `xml:"http://schemas.xmlsoap.org/wsdl/soap12/ operation"`
+	Operation11 *SOAP11Operation `xml:"http://schemas.xmlsoap.org/wsdl/soap/ operation"`
+	Input       *bindingIOXML    `xml:"input>body"`
+	Output      *bindingIOXML    `xml:"output>body"`
+}
+
+type bindingIOXML struct {
+	XMLName xml.Name
+	BindingIO
+}
+
+// MarshalXML implements the xml.Marshaler interface. The SOAP elements
+// of the binding are written in its SOAP namespace, and the SOAP
+// operations without action are not written.
+func (b *Binding) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	ns := b.soapNamespace()
+	v := &bindingXML{Name: b.Name, Type: b.Type}
+	if b.BindingType != nil {
+		bt := *b.BindingType
+		if bt.XMLName.Space == "" {
+			bt.XMLName = xml.Name{Space: ns, Local: "binding"}
+		}
+		v.BindingType = &bt
+	}
+	body := func(bio *BindingIO) *bindingIOXML {
+		if bio == nil {
+			return nil
+		}
+		return &bindingIOXML{xml.Name{Space: ns, Local: "body"}, *bio}
+	}
+	for _, op := range b.Operations {
+		o := &bindingOperationXML{
+			Name:   op.Name,
+			Input:  body(op.Input),
+			Output: body(op.Output),
+		}
+		if op.Operation.Action != "" {
+			o.Operation = &op.Operation
+		}
+		if op.Operation11.Action != "" {
+			o.Operation11 = &op.Operation11
+		}
+		v.Operations = append(v.Operations, o)
+	}
+	return e.EncodeElement(v, start)
+}
+
+// soapNamespace returns the namespace of the SOAP extensions of the
+// binding: that of its soap:binding element, or else SOAP 1.2 if its
+// operations have SOAP 1.2 actions, or else SOAP 1.1.
+func (b *Binding) soapNamespace() string {
+	if b.BindingType != nil && b.BindingType.XMLName.Space != "" {
+		return b.BindingType.XMLName.Space
+	}
+	for _, op := range b.Operations {
+		if op.Operation.Action != "" {
+			return SOAP12Namespace
+		}
+	}
+	return SOAPNamespace
+}
+
+// prefixes are the conventional prefixes of the namespaces of WSDL
+// documents, declared where the document does not declare others.
+var prefixes = map[string]string{
+	WSDLNamespace:   "wsdl",
+	SOAPNamespace:   "soap",
+	SOAP12Namespace: "soap12",
+	XSDNamespace:    "xsd",
+}
+
+// wrappers are the elements that encoding/xml writes as the parents of
+// empty slices, as in annotation>documentation, and that are dropped if
+// empty.
+var wrappers = map[xml.Name]bool{
+	{Space: WSDLNamespace, Local: "types"}:     true,
+	{Space: XSDNamespace, Local: "annotation"}: true,
+	{Space: XSDNamespace, Local: "all"}:        true,
+}
+
+// qualify marshals v and writes its tokens to e, with the names of its
+// elements and attributes qualified by prefixes instead of the default
+// namespace declarations of encoding/xml. The root element declares the
+// namespaces of declarations[0], and the schema elements those of the
+// following declarations, in order. Namespaces without prefix are
+// declared at the root.
+func qualify(e *xml.Encoder, v interface{}, declarations []map[string]string) error {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var tokens []xml.Token
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if end, ok := tok.(xml.EndElement); ok && len(tokens) > 0 {
+			if start, ok := tokens[len(tokens)-1].(xml.StartElement); ok && wrappers[end.Name] && len(start.Attr) == 0 {
+				tokens = tokens[:len(tokens)-1]
+				continue
+			}
+		}
+		tokens = append(tokens, xml.CopyToken(tok))
+	}
+	q := &qualifier{taken: make(map[string]bool)}
+	for _, m := range declarations {
+		for prefix := range m {
+			q.taken[prefix] = true
+		}
+	}
+	// namespaces of the root element, and those used without prefix
+	root := make(map[string]string)
+	for prefix, ns := range declarations[0] {
+		root[prefix] = ns
+	}
+	for _, tok := range tokens {
+		t, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if t.Name.Space != "" && root[""] != t.Name.Space {
+			q.declare(root, t.Name.Space)
+		}
+		for _, attr := range t.Attr {
+			if attr.Name.Space != "" && attr.Name.Space != "xmlns" && attr.Name.Space != xmlNamespace {
+				q.declare(root, attr.Name.Space)
+			}
+		}
+	}
+	next := 1
+	for _, tok := range tokens {
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var m map[string]string
+			switch {
+			case len(q.names) == 0:
+				m = root
+			case t.Name.Space == XSDNamespace && t.Name.Local == "schema" && next < len(declarations):
+				m = declarations[next]
+				next++
+			}
+			tok = q.start(t, m)
+		case xml.EndElement:
+			tok = q.end()
+		}
+		if err := e.EncodeToken(tok); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// qualifier rewrites the names of elements and attributes with the
+// prefixes of their namespaces in scope.
+type qualifier struct {
+	scopes []map[string]string
+	names  []xml.Name // of the open elements
+	taken  map[string]bool
+}
+
+// declare adds a declaration of ns to m, unless m declares a prefix for
+// it, with a conventional or numbered prefix not taken in the document.
+func (q *qualifier) declare(m map[string]string, ns string) string {
+	if prefix := lookup(m, ns); prefix != "" {
+		return prefix
+	}
+	base, ok := prefixes[ns]
+	if !ok {
+		base = "ns"
+	}
+	prefix := base
+	for i := 1; q.taken[prefix]; i++ {
+		prefix = base + strconv.Itoa(i)
+	}
+	q.taken[prefix] = true
+	m[prefix] = ns
+	return prefix
+}
+
+// lookup returns the first of the prefixes declared for ns in m, or an
+// empty string.
+func lookup(m map[string]string, ns string) string {
+	var found []string
+	for prefix, v := range m {
+		if v == ns && prefix != "" {
+			found = append(found, prefix)
+		}
+	}
+	if len(found) == 0 {
+		return ""
+	}
+	sort.Strings(found)
+	return found[0]
+}
+
+// start returns the start element t with qualified names, and with the
+// declarations of m and of the namespaces not in scope.
+func (q *qualifier) start(t xml.StartElement, m map[string]string) xml.StartElement {
+	decl := make(map[string]string)
+	for prefix, ns := range m {
+		decl[prefix] = ns
+	}
+	scope := make(map[string]string)
+	if n := len(q.scopes); n > 0 {
+		for prefix, ns := range q.scopes[n-1] {
+			scope[prefix] = ns
+		}
+	}
+	for prefix, ns := range decl {
+		scope[prefix] = ns
+	}
+	qualified := func(name xml.Name, element bool) string {
+		if name.Space == "" {
+			return name.Local
+		}
+		if element && scope[""] == name.Space {
+			return name.Local
+		}
+		prefix := lookup(scope, name.Space)
+		if prefix == "" {
+			prefix = q.declare(decl, name.Space)
+			scope[prefix] = name.Space
+		}
+		return prefix + ":" + name.Local
+	}
+	if t.Name.Space == "" && scope[""] != "" {
+		// undeclare the default namespace for names without namespace
+		decl[""] = ""
+		scope[""] = ""
+	}
+	start := xml.StartElement{Name: xml.Name{Local: qualified(t.Name, true)}}
+	for _, attr := range t.Attr {
+		switch {
+		case attr.Name.Space == "xmlns", attr.Name.Space == "" && attr.Name.Local == "xmlns":
+			// declarations of encoding/xml
+			continue
+		case attr.Name.Space == xmlNamespace:
+			attr.Name.Local = "xml:" + attr.Name.Local
+		default:
+			attr.Name.Local = qualified(attr.Name, false)
+		}
+		attr.Name.Space = ""
+		start.Attr = append(start.Attr, attr)
+	}
+	var names []string
+	for prefix := range decl {
+		names = append(names, prefix)
+	}
+	sort.Strings(names)
+	var attrs []xml.Attr
+	for _, prefix := range names {
+		name := "xmlns"
+		if prefix != "" {
+			name += ":" + prefix
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: decl[prefix]})
+	}
+	start.Attr = append(attrs, start.Attr...)
+	q.scopes = append(q.scopes, scope)
+	q.names = append(q.names, start.Name)
+	return start
+}
+
+// end returns the end element of the innermost open element.
+func (q *qualifier) end() xml.EndElement {
+	n := len(q.names) - 1
+	name := q.names[n]
+	q.scopes, q.names = q.scopes[:n], q.names[:n]
+	return xml.EndElement{Name: name}
+}
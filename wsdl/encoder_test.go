@@ -0,0 +1,139 @@
+package wsdl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	for _, name := range []string{"golden1.wsdl", "wsdl20.wsdl"} {
+		f, err := os.Open(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := Unmarshal(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var first bytes.Buffer
+		if err = Marshal(&first, d); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		d, err = Unmarshal(bytes.NewReader(first.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: marshaled document does not decode: %v", name, err)
+		}
+		var second bytes.Buffer
+		if err = Marshal(&second, d); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if first.String() != second.String() {
+			t.Errorf("%s: marshaling is not stable:\n%s\n%s", name, first.String(), second.String())
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	data := `<wsdl:definitions name="Stock" targetNamespace="urn:stock"
+	    xmlns:tns="urn:stock" xmlns:c="urn:common"
+	    xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/"
+	    xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/">
+	  <wsdl:types>
+	    <schema xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:common">
+	      <simpleType name="Symbol"><restriction base="string"/></simpleType>
+	    </schema>
+	    <schema xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:stock" elementFormDefault="qualified">
+	      <import namespace="urn:common"/>
+	      <element name="GetQuote">
+	        <complexType>
+	          <sequence>
+	            <group ref="tns:Header"/>
+	            <element name="Symbol" type="c:Symbol"/>
+	            <element name="Currency" type="string" minOccurs="0"/>
+	            <any minOccurs="0" maxOccurs="unbounded"/>
+	          </sequence>
+	          <attribute name="id" type="string" use="required"/>
+	        </complexType>
+	      </element>
+	    </schema>
+	  </wsdl:types>
+	  <wsdl:binding name="StockBinding" type="tns:StockPortType">
+	    <soap12:binding transport="http://schemas.xmlsoap.org/soap/http"/>
+	    <wsdl:operation name="GetQuote">
+	      <wsdl:input><soap12:body use="literal"/></wsdl:input>
+	    </wsdl:operation>
+	  </wsdl:binding>
+	  <wsdl:service name="StockService">
+	    <wsdl:port name="StockPort" binding="tns:StockBinding">
+	      <soap12:address location="http://example.com/stock"/>
+	    </wsdl:port>
+	  </wsdl:service>
+	</wsdl:definitions>`
+	d, err := Unmarshal(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Service.Ports[0].Address.Location = "http://localhost:8080/stock"
+	var b bytes.Buffer
+	if err = Marshal(&b, d); err != nil {
+		t.Fatal(err)
+	}
+	have := b.String()
+	for _, want := range []string{
+		`<wsdl:definitions xmlns:c="urn:common" xmlns:soap12="http://schemas.xmlsoap.org/wsdl/soap12/" xmlns:tns="urn:stock" xmlns:wsdl="http://schemas.xmlsoap.org/wsdl/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" name="Stock" targetNamespace="urn:stock">`,
+		`<schema xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:common">`,
+		`<schema xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:stock" elementFormDefault="qualified">`,
+		`<restriction base="string">`,
+		`<import namespace="urn:common">`,
+		`<group ref="tns:Header">`,
+		`<element name="Symbol" type="c:Symbol">`,
+		`<element name="Currency" type="string" minOccurs="0">`,
+		`<any maxOccurs="unbounded" minOccurs="0">`,
+		`<attribute name="id" type="string" use="required">`,
+		`<soap12:binding transport="http://schemas.xmlsoap.org/soap/http">`,
+		`<soap12:body use="literal">`,
+		`<soap12:address location="http://localhost:8080/stock">`,
+	} {
+		if !strings.Contains(have, want) {
+			t.Errorf("missing %s in:\n%s", want, have)
+		}
+	}
+	if strings.Index(have, `ref="tns:Header"`) > strings.Index(have, `name="Symbol" type`) {
+		t.Errorf("sequence is out of order:\n%s", have)
+	}
+	if strings.Contains(have, "<wsdl:portType") || strings.Contains(have, "<wsdl:message") {
+		t.Errorf("empty definitions are written:\n%s", have)
+	}
+}
+
+func TestMarshalSchema(t *testing.T) {
+	data := `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:t="urn:t" targetNamespace="urn:t">
+	  <xs:complexType name="T">
+	    <xs:annotation><xs:documentation xml:lang="en">A type.</xs:documentation></xs:annotation>
+	    <xs:attribute name="a" type="xs:string"/>
+	  </xs:complexType>
+	</xs:schema>`
+	_, s, err := NewDecoder(strings.NewReader(data)).DecodeDocument()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := xml.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := string(b)
+	for _, want := range []string{
+		`<xs:schema xmlns:t="urn:t" xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:t">`,
+		`<xs:documentation xml:lang="en">A type.</xs:documentation>`,
+		`<xs:attribute name="a" type="xs:string">`,
+	} {
+		if !strings.Contains(have, want) {
+			t.Errorf("missing %s in:\n%s", want, have)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package wsdl
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Position is the location of a node in its source document. Columns
+// and offsets count the bytes of the document converted to UTF-8, which
+// differ from those of the file in other encodings.
+type Position struct {
+	Document string // file path or URL, if known
+	Line     int    // starting at 1
+	Column   int    // in bytes, starting at 1
+	Offset   int64  // in bytes, starting at 0
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position as document:line:column, without the
+// parts that are not known.
+func (p Position) String() string {
+	s := p.Document
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// ParseError is the error of decoding a malformed document, with the
+// position where decoding failed.
+type ParseError struct {
+	Pos Position
+	Err error // of encoding/xml, or io.EOF for documents without elements
+}
+
+func (e *ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Message()
+}
+
+// Message returns the error without the position.
+func (e *ParseError) Message() string {
+	if e.Err == io.EOF || e.Err == io.ErrUnexpectedEOF {
+		return "unexpected end of document"
+	}
+	if err, ok := e.Err.(*xml.SyntaxError); ok {
+		return err.Msg
+	}
+	return e.Err.Error()
+}
+
+// xmlEncoding matches the encoding of the XML declaration.
+var xmlEncoding = regexp.MustCompile(`^\x{feff}?\s*<\?xml[^>]*?\sencoding\s*=\s*["']([^"']+)["']`)
+
+// utf8Reader returns r converted to UTF-8 from the encoding of its XML
+// declaration, so that positions are counted in the text the XML
+// decoder reads, and whether it was converted. Documents in encodings
+// that are not known are returned as is, for the XML decoder to fail.
+func utf8Reader(r io.Reader) (io.Reader, bool) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(1024)
+	m := xmlEncoding.FindSubmatch(head)
+	if m == nil {
+		return br, false
+	}
+	cr, err := charset.NewReaderLabel(string(m[1]), br)
+	if err != nil {
+		return br, false
+	}
+	return cr, true
+}
+
+// positionReader records the offsets of the lines and tags of the data
+// read through it, to compute the positions of nodes while the data is
+// decoded.
+type positionReader struct {
+	r     io.Reader
+	n     int64
+	lines []int64 // offsets of the start of lines, after the first
+	tags  []int64 // offsets of '<'
+}
+
+func (pr *positionReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	for i, c := range b[:n] {
+		switch c {
+		case '\n':
+			pr.lines = append(pr.lines, pr.n+int64(i)+1)
+		case '<':
+			pr.tags = append(pr.tags, pr.n+int64(i))
+		}
+	}
+	pr.n += int64(n)
+	return n, err
+}
+
+// position returns the position of the byte at offset.
+func (pr *positionReader) position(name string, offset int64) Position {
+	line := sort.Search(len(pr.lines), func(i int) bool { return pr.lines[i] > offset })
+	start := int64(0)
+	if line > 0 {
+		start = pr.lines[line-1]
+	}
+	return Position{
+		Document: name,
+		Line:     line + 1,
+		Column:   int(offset-start) + 1,
+		Offset:   offset,
+	}
+}
+
+// tag returns the position of the start tag that ends before offset,
+// which is where the decoder is after reading the start tag.
+func (pr *positionReader) tag(name string, offset int64) Position {
+	i := sort.Search(len(pr.tags), func(i int) bool { return pr.tags[i] >= offset })
+	if i > 0 {
+		offset = pr.tags[i-1]
+	}
+	return pr.position(name, offset)
+}
+
+var positionType = reflect.TypeOf(Position{})
+
+// setPositions replaces the end offsets of the start tags, recorded in
+// the Pos fields of the nodes of v by their UnmarshalXML methods, with
+// the positions of the start tags.
+func (pr *positionReader) setPositions(name string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			pr.setPositions(name, v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			pr.setPositions(name, v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == positionType {
+			if p := v.Addr().Interface().(*Position); p.Line == 0 && p.Offset > 0 {
+				*p = pr.tag(name, p.Offset)
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			pr.setPositions(name, v.Field(i))
+		}
+	}
+}
@@ -2,7 +2,10 @@ package wsdl
 
 // TODO: Add all types from the spec.
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"strconv"
+)
 
 // Definitions is the root element of a WSDL document.
 type Definitions struct {
@@ -25,11 +28,11 @@ type definitionDup Definitions
 // UnmarshalXML implements the xml.Unmarshaler interface.
 func (def *Definitions) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	for _, attr := range start.Attr {
-		if attr.Name.Space == "xmlns" {
+		if prefix, ok := declaration(attr); ok {
 			if def.Namespaces == nil {
 				def.Namespaces = make(map[string]string)
 			}
-			def.Namespaces[attr.Name.Local] = attr.Value
+			def.Namespaces[prefix] = attr.Value
 		}
 	}
 	return d.DecodeElement((*definitionDup)(def), &start)
@@ -37,10 +40,21 @@ func (def *Definitions) UnmarshalXML(d *xml.Decoder, start xml.StartElement) err
 
 // Service defines a WSDL service and with a location, like an HTTP server.
 type Service struct {
-	Doc   string  `xml:"documentation"`
+	Name  string  `xml:"name,attr"`
+	Doc   string  `xml:"-"` // Docs in the default language
+	Docs  Docs    `xml:"documentation"`
 	Ports []*Port `xml:"port"`
 }
 
+type serviceDup Service
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (s *Service) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	err := d.DecodeElement((*serviceDup)(s), &start)
+	s.Doc = s.Docs.Text("")
+	return err
+}
+
 // Port for WSDL service.
 type Port struct {
 	XMLName xml.Name `xml:"port"`
@@ -57,14 +71,18 @@ type Address struct {
 
 // Schema of WSDL document.
 type Schema struct {
-	XMLName         xml.Name          `xml:"schema"`
-	TargetNamespace string            `xml:"targetNamespace,attr"`
-	Namespaces      map[string]string `xml:"-"`
-	Imports         []*ImportSchema   `xml:"import"`
-	Includes        []*IncludeSchema  `xml:"include"`
-	SimpleTypes     []*SimpleType     `xml:"simpleType"`
-	ComplexTypes    []*ComplexType    `xml:"complexType"`
-	Elements        []*Element        `xml:"element"`
+	XMLName              xml.Name          `xml:"schema"`
+	TargetNamespace      string            `xml:"targetNamespace,attr,omitempty"`
+	ElementFormDefault   string            `xml:"elementFormDefault,attr,omitempty"`
+	AttributeFormDefault string            `xml:"attributeFormDefault,attr,omitempty"`
+	Namespaces           map[string]string `xml:"-"`
+	Imports              []*ImportSchema   `xml:"import"`
+	Includes             []*IncludeSchema  `xml:"include"`
+	SimpleTypes          []*SimpleType     `xml:"simpleType"`
+	ComplexTypes         []*ComplexType    `xml:"complexType"`
+	Elements             []*Element        `xml:"element"`
+	Groups               []*Group          `xml:"group"`
+	AttributeGroups      []*AttributeGroup `xml:"attributeGroup"`
 }
 
 // Unmarshaling solution from Matt Harden (http://grokbase.com/t/gg/golang-nuts/14bk21xb7a/go-nuts-extending-encoding-xml-to-capture-unknown-attributes)
@@ -75,38 +93,152 @@ type Schema struct {
 type schemaDup Schema
 
 // UnmarshalXML implements the xml.Unmarshaler interface.
+//
+// Documents with more than one schema are decoded into the same Schema,
+// so the global definitions of each schema are stamped with its target
+// namespace and namespace declarations, for resolving names, and with
+// the form of its local declarations.
 func (schema *Schema) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var tns string
+	var namespaces map[string]string
+	form := &schemaForm{}
 	for _, attr := range start.Attr {
-		if attr.Name.Space == "xmlns" {
+		if prefix, ok := declaration(attr); ok {
 			if schema.Namespaces == nil {
 				schema.Namespaces = make(map[string]string)
 			}
-			schema.Namespaces[attr.Name.Local] = attr.Value
+			if namespaces == nil {
+				namespaces = make(map[string]string)
+			}
+			schema.Namespaces[prefix] = attr.Value
+			namespaces[prefix] = attr.Value
+		}
+		if attr.Name.Space != "" {
+			continue
+		}
+		switch attr.Name.Local {
+		case "targetNamespace":
+			tns = attr.Value
+		case "elementFormDefault":
+			form.element = attr.Value
+		case "attributeFormDefault":
+			form.attribute = attr.Value
+		}
+	}
+	nst, nct, nel := len(schema.SimpleTypes), len(schema.ComplexTypes), len(schema.Elements)
+	ng, nag := len(schema.Groups), len(schema.AttributeGroups)
+	nimp, ninc := len(schema.Imports), len(schema.Includes)
+	err := d.DecodeElement((*schemaDup)(schema), &start)
+	if err != nil {
+		return err
+	}
+	for _, v := range schema.SimpleTypes[nst:] {
+		v.TargetNamespace, v.Namespaces = stamp(v.TargetNamespace, v.Namespaces, tns, namespaces)
+		if v.form == nil {
+			v.form = form
+		}
+	}
+	for _, v := range schema.ComplexTypes[nct:] {
+		v.TargetNamespace, v.Namespaces = stamp(v.TargetNamespace, v.Namespaces, tns, namespaces)
+		if v.form == nil {
+			v.form = form
+		}
+	}
+	for _, v := range schema.Elements[nel:] {
+		v.TargetNamespace, v.Namespaces = stamp(v.TargetNamespace, v.Namespaces, tns, namespaces)
+		if v.form == nil {
+			v.form = form
+		}
+	}
+	for _, v := range schema.Groups[ng:] {
+		v.TargetNamespace, v.Namespaces = stamp(v.TargetNamespace, v.Namespaces, tns, namespaces)
+		if v.form == nil {
+			v.form = form
+		}
+	}
+	for _, v := range schema.AttributeGroups[nag:] {
+		v.TargetNamespace, v.Namespaces = stamp(v.TargetNamespace, v.Namespaces, tns, namespaces)
+		if v.form == nil {
+			v.form = form
+		}
+	}
+	for _, v := range schema.Imports[nimp:] {
+		if v.TargetNamespace == "" {
+			v.TargetNamespace = tns
 		}
 	}
-	return d.DecodeElement((*schemaDup)(schema), &start)
+	for _, v := range schema.Includes[ninc:] {
+		if v.TargetNamespace == "" {
+			v.TargetNamespace = tns
+		}
+	}
+	return nil
+}
+
+// declaration returns the prefix that attr declares a namespace for,
+// or an empty string for the default namespace, and whether attr is a
+// namespace declaration.
+func declaration(attr xml.Attr) (string, bool) {
+	switch {
+	case attr.Name.Space == "xmlns":
+		return attr.Name.Local, true
+	case attr.Name.Space == "" && attr.Name.Local == "xmlns":
+		return "", true
+	}
+	return "", false
+}
+
+// schemaForm is the elementFormDefault and attributeFormDefault of the
+// schema of global definitions.
+type schemaForm struct {
+	element, attribute string
+}
+
+// stamp returns the target namespace and namespace declarations of a
+// global definition, those of its schema unless already set.
+func stamp(tns string, namespaces map[string]string, schemaTNS string, schemaNamespaces map[string]string) (string, map[string]string) {
+	if tns == "" {
+		tns = schemaTNS
+	}
+	if namespaces == nil {
+		namespaces = schemaNamespaces
+	}
+	return tns, namespaces
 }
 
 // SimpleType describes a simple type, such as string.
 type SimpleType struct {
-	XMLName         xml.Name     `xml:"simpleType"`
-	Name            string       `xml:"name,attr"`
-	Union           *Union       `xml:"union"`
-	Restriction     *Restriction `xml:"restriction"`
-	TargetNamespace string
+	XMLName         xml.Name          `xml:"simpleType"`
+	Name            string            `xml:"name,attr,omitempty"`
+	Docs            Docs              `xml:"annotation>documentation"`
+	Union           *Union            `xml:"union"`
+	List            *List             `xml:"list"`
+	Restriction     *Restriction      `xml:"restriction"`
+	TargetNamespace string            `xml:"-"`
+	Namespaces      map[string]string `xml:"-"`
+	form            *schemaForm
 }
 
 // Union is a mix of multiple types in a union.
 type Union struct {
-	XMLName     xml.Name `xml:"union"`
-	MemberTypes string   `xml:"memberTypes,attr"`
+	XMLName     xml.Name      `xml:"union"`
+	MemberTypes string        `xml:"memberTypes,attr,omitempty"`
+	SimpleTypes []*SimpleType `xml:"simpleType"` // anonymous member types
+}
+
+// List describes a whitespace separated list of values of a simple
+// type.
+type List struct {
+	XMLName    xml.Name    `xml:"list"`
+	ItemType   string      `xml:"itemType,attr,omitempty"`
+	SimpleType *SimpleType `xml:"simpleType"` // anonymous item type
 }
 
 // Restriction describes the WSDL type of the simple type and
 // optionally its allowed values.
 type Restriction struct {
 	XMLName    xml.Name     `xml:"restriction"`
-	Base       string       `xml:"base,attr"`
+	Base       string       `xml:"base,attr,omitempty"`
 	Enum       []*Enum      `xml:"enumeration"`
 	Attributes []*Attribute `xml:"attribute"`
 }
@@ -115,21 +247,40 @@ type Restriction struct {
 type Enum struct {
 	XMLName xml.Name `xml:"enumeration"`
 	Value   string   `xml:"value,attr"`
+	Docs    Docs     `xml:"annotation>documentation"`
 }
 
 // ComplexType describes a complex type, such as a struct.
 type ComplexType struct {
-	XMLName         xml.Name        `xml:"complexType"`
-	Name            string          `xml:"name,attr"`
-	Abstract        bool            `xml:"abstract,attr"`
-	Doc             string          `xml:"annotation>documentation"`
-	AllElements     []*Element      `xml:"all>element"`
-	ComplexContent  *ComplexContent `xml:"complexContent"`
-	SimpleContent   *SimpleContent  `xml:"simpleContent"`
-	Sequence        *Sequence       `xml:"sequence"`
-	Choice          *Choice         `xml:"choice"`
-	Attributes      []*Attribute    `xml:"attribute"`
-	TargetNamespace string
+	XMLName         xml.Name          `xml:"complexType"`
+	Name            string            `xml:"name,attr,omitempty"`
+	Abstract        bool              `xml:"abstract,attr,omitempty"`
+	Mixed           bool              `xml:"mixed,attr,omitempty"`
+	Doc             string            `xml:"-"` // Docs in the default language
+	Docs            Docs              `xml:"annotation>documentation"`
+	AllElements     []*Element        `xml:"all>element"`
+	ComplexContent  *ComplexContent   `xml:"complexContent"`
+	SimpleContent   *SimpleContent    `xml:"simpleContent"`
+	Sequence        *Sequence         `xml:"sequence"`
+	Choice          *Choice           `xml:"choice"`
+	Group           *Group            `xml:"group"`
+	Attributes      []*Attribute      `xml:"attribute"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup"`
+	TargetNamespace string            `xml:"-"`
+	Namespaces      map[string]string `xml:"-"`
+	Pos             Position          `xml:"-"`
+	form            *schemaForm
+}
+
+type complexTypeDup ComplexType
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (ct *ComplexType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	pos := offset(d)
+	err := d.DecodeElement((*complexTypeDup)(ct), &start)
+	ct.Doc = ct.Docs.Text("")
+	ct.Pos = pos
+	return err
 }
 
 // SimpleContent describes simple content within a complex type.
@@ -143,17 +294,20 @@ type SimpleContent struct {
 // for extending the complex type with fields from the complex content.
 type ComplexContent struct {
 	XMLName     xml.Name     `xml:"complexContent"`
+	Mixed       bool         `xml:"mixed,attr,omitempty"`
 	Extension   *Extension   `xml:"extension"`
 	Restriction *Restriction `xml:"restriction"`
 }
 
 // Extension describes a complex content extension.
 type Extension struct {
-	XMLName    xml.Name     `xml:"extension"`
-	Base       string       `xml:"base,attr"`
-	Sequence   *Sequence    `xml:"sequence"`
-	Choice     *Choice      `xml:"choice"`
-	Attributes []*Attribute `xml:"attribute"`
+	XMLName         xml.Name          `xml:"extension"`
+	Base            string            `xml:"base,attr"`
+	Sequence        *Sequence         `xml:"sequence"`
+	Choice          *Choice           `xml:"choice"`
+	Group           *Group            `xml:"group"`
+	Attributes      []*Attribute      `xml:"attribute"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup"`
 }
 
 // Sequence describes a list of elements (parameters) of a type.
@@ -163,66 +317,276 @@ type Sequence struct {
 	Elements     []*Element     `xml:"element"`
 	Any          []*AnyElement  `xml:"any"`
 	Choices      []*Choice      `xml:"choice"`
+	Groups       []*Group       `xml:"group"`
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. It decodes
+// the particles of the sequence in document order, so that group
+// references know their position among the elements.
+func (seq *Sequence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	seq.XMLName = start.Name
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "complexType":
+				ct := &ComplexType{}
+				err = d.DecodeElement(ct, &t)
+				seq.ComplexTypes = append(seq.ComplexTypes, ct)
+			case "element":
+				el := &Element{}
+				err = d.DecodeElement(el, &t)
+				seq.Elements = append(seq.Elements, el)
+			case "any":
+				any := &AnyElement{}
+				err = d.DecodeElement(any, &t)
+				any.Position = len(seq.Elements)
+				seq.Any = append(seq.Any, any)
+			case "choice":
+				c := &Choice{}
+				err = d.DecodeElement(c, &t)
+				c.Position = len(seq.Elements)
+				seq.Choices = append(seq.Choices, c)
+			case "group":
+				g := &Group{}
+				err = d.DecodeElement(g, &t)
+				g.Position = len(seq.Elements)
+				seq.Groups = append(seq.Groups, g)
+			default:
+				err = d.Skip()
+			}
+			if err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
 }
 
 // Choice describes a list of elements (parameters) of a type.
 type Choice struct {
 	XMLName      xml.Name       `xml:"choice"`
+	Min          string         `xml:"minOccurs,attr,omitempty"`
+	Max          string         `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
 	ComplexTypes []*ComplexType `xml:"complexType"`
 	Elements     []*Element     `xml:"element"`
 	Any          []*AnyElement  `xml:"any"`
+	Groups       []*Group       `xml:"group"`
+
+	// Position is the number of elements that precede the choice in
+	// its enclosing sequence.
+	Position int `xml:"-"`
+}
+
+// Group describes a named model group, or a reference to one.
+type Group struct {
+	XMLName     xml.Name   `xml:"group"`
+	Name        string     `xml:"name,attr,omitempty"`
+	Ref         string     `xml:"ref,attr,omitempty"`
+	Min         string     `xml:"minOccurs,attr,omitempty"`
+	Max         string     `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
+	Docs        Docs       `xml:"annotation>documentation"`
+	Sequence    *Sequence  `xml:"sequence"`
+	Choice      *Choice    `xml:"choice"`
+	AllElements []*Element `xml:"all>element"`
+
+	// Position is the number of elements that precede a reference
+	// in its enclosing sequence.
+	Position int `xml:"-"`
+
+	// TargetNamespace and Namespaces are those of the schema of
+	// global groups.
+	TargetNamespace string            `xml:"-"`
+	Namespaces      map[string]string `xml:"-"`
+	form            *schemaForm
+}
+
+// AttributeGroup describes a named group of attributes, or a reference
+// to one.
+type AttributeGroup struct {
+	XMLName         xml.Name          `xml:"attributeGroup"`
+	Name            string            `xml:"name,attr,omitempty"`
+	Ref             string            `xml:"ref,attr,omitempty"`
+	Docs            Docs              `xml:"annotation>documentation"`
+	Attributes      []*Attribute      `xml:"attribute"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup"`
+
+	// TargetNamespace and Namespaces are those of the schema of
+	// global attribute groups.
+	TargetNamespace string            `xml:"-"`
+	Namespaces      map[string]string `xml:"-"`
+	form            *schemaForm
 }
 
 // Attribute describes an attribute of a given type.
 type Attribute struct {
 	XMLName   xml.Name `xml:"attribute"`
-	Name      string   `xml:"name,attr"`
-	Ref       string   `xml:"ref,attr"`
-	Type      string   `xml:"type,attr"`
-	ArrayType string   `xml:"arrayType,attr"`
-	Min       int      `xml:"minOccurs,attr"`
-	Max       string   `xml:"maxOccurs,attr"` // can be # or unbounded
-	Nillable  bool     `xml:"nillable,attr"`
+	Name      string   `xml:"name,attr,omitempty"`
+	Ref       string   `xml:"ref,attr,omitempty"`
+	Type      string   `xml:"type,attr,omitempty"`
+	Use       string   `xml:"use,attr,omitempty"`       // optional, required or prohibited
+	ArrayType string   `xml:"arrayType,attr,omitempty"` // wsdl:arrayType of SOAP-ENC arrays
+	Min       int      `xml:"minOccurs,attr,omitempty"`
+	Max       string   `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
+	Nillable  bool     `xml:"nillable,attr,omitempty"`
+	Default   string   `xml:"default,attr,omitempty"`
+	Fixed     string   `xml:"fixed,attr,omitempty"`
+	Docs      Docs     `xml:"annotation>documentation"`
+}
+
+type attributeDup Attribute
+
+// MarshalXML implements the xml.Marshaler interface. The arrayType
+// attribute is written in the WSDL namespace.
+func (a *Attribute) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		*attributeDup
+		ArrayType string `xml:"http://schemas.xmlsoap.org/wsdl/ arrayType,attr,omitempty"`
+	}{(*attributeDup)(a), a.ArrayType}, start)
 }
 
 // Element describes an element of a given type.
 type Element struct {
 	XMLName     xml.Name     `xml:"element"`
-	Name        string       `xml:"name,attr"`
-	Ref         string       `xml:"ref,attr"`
-	Type        string       `xml:"type,attr"`
+	Name        string       `xml:"name,attr,omitempty"`
+	Ref         string       `xml:"ref,attr,omitempty"`
+	Type        string       `xml:"type,attr,omitempty"`
 	Min         int          `xml:"minOccurs,attr"`
-	Max         string       `xml:"maxOccurs,attr"` // can be # or unbounded
-	Nillable    bool         `xml:"nillable,attr"`
+	Max         string       `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
+	Nillable    bool         `xml:"nillable,attr,omitempty"`
+	Default     string       `xml:"default,attr,omitempty"`
+	Fixed       string       `xml:"fixed,attr,omitempty"`
+	Docs        Docs         `xml:"annotation>documentation"`
 	ComplexType *ComplexType `xml:"complexType"`
+
+	// Abstract elements cannot appear in instances, only the members
+	// of their substitution group.
+	Abstract          bool   `xml:"abstract,attr,omitempty"`
+	SubstitutionGroup string `xml:"substitutionGroup,attr,omitempty"`
+
+	// TargetNamespace and Namespaces are those of the schema of
+	// global elements.
+	TargetNamespace string            `xml:"-"`
+	Namespaces      map[string]string `xml:"-"`
+	form            *schemaForm
+
+	// Pos is the position of the element in its document.
+	Pos Position `xml:"-"`
+
+	// minOccurs records whether Min is in the document, where its
+	// default is 1.
+	minOccurs bool
+}
+
+// MinOccurs returns the minimum number of occurrences of el, which is
+// 1 if minOccurs is not in the document.
+func (el *Element) MinOccurs() int {
+	if el.Min == 0 && !el.minOccurs {
+		return 1
+	}
+	return el.Min
+}
+
+type elementDup Element
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (el *Element) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	pos := offset(d)
+	err := d.DecodeElement((*elementDup)(el), &start)
+	el.Pos = pos
+	el.minOccurs = hasAttr(start, "minOccurs")
+	return err
+}
+
+// MarshalXML implements the xml.Marshaler interface. The minOccurs
+// attribute is written if it was in the document or is not zero.
+func (el *Element) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		*elementDup
+		Min string `xml:"minOccurs,attr,omitempty"`
+	}{(*elementDup)(el), occurs(el.Min, el.minOccurs)}, start)
 }
 
 // AnyElement describes an element of an undefined type.
 type AnyElement struct {
 	XMLName xml.Name `xml:"any"`
 	Min     int      `xml:"minOccurs,attr"`
-	Max     string   `xml:"maxOccurs,attr"` // can be # or unbounded
+	Max     string   `xml:"maxOccurs,attr,omitempty"` // can be # or unbounded
+
+	// Position is the number of elements that precede the wildcard in
+	// its enclosing sequence.
+	Position int `xml:"-"`
+
+	minOccurs bool // see Element
+}
+
+type anyElementDup AnyElement
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (any *AnyElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	err := d.DecodeElement((*anyElementDup)(any), &start)
+	any.minOccurs = hasAttr(start, "minOccurs")
+	return err
+}
+
+// MarshalXML implements the xml.Marshaler interface, see Element.
+func (any *AnyElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(struct {
+		*anyElementDup
+		Min string `xml:"minOccurs,attr,omitempty"`
+	}{(*anyElementDup)(any), occurs(any.Min, any.minOccurs)}, start)
+}
+
+// hasAttr reports whether start has the attribute local, without
+// namespace.
+func hasAttr(start xml.StartElement, local string) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return true
+		}
+	}
+	return false
+}
+
+// occurs returns the minOccurs attribute of min, or an empty string to
+// omit it.
+func occurs(min int, set bool) string {
+	if min == 0 && !set {
+		return ""
+	}
+	return strconv.Itoa(min)
 }
 
 // Import points to another WSDL to be imported at root level.
 type Import struct {
 	XMLName   xml.Name `xml:"import"`
-	Namespace string   `xml:"namespace,attr"`
-	Location  string   `xml:"location,attr"`
+	Namespace string   `xml:"namespace,attr,omitempty"`
+	Location  string   `xml:"location,attr,omitempty"`
 }
 
 // ImportSchema points to another WSDL to be imported at schema level.
 type ImportSchema struct {
 	XMLName   xml.Name `xml:"import"`
-	Namespace string   `xml:"namespace,attr"`
-	Location  string   `xml:"schemaLocation,attr"`
+	Namespace string   `xml:"namespace,attr,omitempty"`
+	Location  string   `xml:"schemaLocation,attr,omitempty"`
+
+	// TargetNamespace is that of the importing schema.
+	TargetNamespace string `xml:"-"`
 }
 
 // IncludeSchema points to another WSDL to be imported at schema level.
 type IncludeSchema struct {
 	XMLName   xml.Name `xml:"include"`
-	Namespace string   `xml:"namespace,attr"`
-	Location  string   `xml:"schemaLocation,attr"`
+	Namespace string   `xml:"namespace,attr,omitempty"`
+	Location  string   `xml:"schemaLocation,attr,omitempty"`
+
+	// TargetNamespace is that of the including schema.
+	TargetNamespace string `xml:"-"`
 }
 
 // Message describes the data being communicated, such as functions
@@ -230,7 +594,19 @@ type IncludeSchema struct {
 type Message struct {
 	XMLName xml.Name `xml:"message"`
 	Name    string   `xml:"name,attr"`
+	Docs    Docs     `xml:"documentation"`
 	Parts   []*Part  `xml:"part"`
+	Pos     Position `xml:"-"`
+}
+
+type messageDup Message
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (m *Message) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	pos := offset(d)
+	err := d.DecodeElement((*messageDup)(m), &start)
+	m.Pos = pos
+	return err
 }
 
 // Part describes what Type or Element to use from the PortType.
@@ -238,7 +614,7 @@ type Part struct {
 	XMLName xml.Name `xml:"part"`
 	Name    string   `xml:"name,attr"`
 	Type    string   `xml:"type,attr,omitempty"`
-	Element string   `xml:"element,attr,omitempty"` // TODO: not sure omitempty
+	Element string   `xml:"element,attr,omitempty"`
 }
 
 // PortType describes a set of operations.
@@ -252,9 +628,22 @@ type PortType struct {
 type Operation struct {
 	XMLName xml.Name `xml:"operation"`
 	Name    string   `xml:"name,attr"`
-	Doc     string   `xml:"documentation"`
+	Doc     string   `xml:"-"` // Docs in the default language
+	Docs    Docs     `xml:"documentation"`
 	Input   *IO      `xml:"input"`
 	Output  *IO      `xml:"output"`
+	Pos     Position `xml:"-"`
+}
+
+type operationDup Operation
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (op *Operation) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	pos := offset(d)
+	err := d.DecodeElement((*operationDup)(op), &start)
+	op.Doc = op.Docs.Text("")
+	op.Pos = pos
+	return err
 }
 
 // IO describes which message is linked to an operation, for input
@@ -275,8 +664,9 @@ type Binding struct {
 
 // BindingType contains additional meta data on how to implement the binding.
 type BindingType struct {
-	Style     string `xml:"style,attr"`
-	Transport string `xml:"transport,attr"`
+	XMLName   xml.Name // soap:binding or soap12:binding
+	Style     string   `xml:"style,attr,omitempty"`
+	Transport string   `xml:"transport,attr,omitempty"`
 }
 
 // BindingOperation describes the requirement for binding SOAP to WSDL
@@ -308,6 +698,6 @@ type SOAP11Operation struct {
 
 // BindingIO describes the IO binding of SOAP operations. See IO for details.
 type BindingIO struct {
-	Parts string `xml:"parts,attr"`
-	Use   string `xml:"use,attr"`
+	Parts string `xml:"parts,attr,omitempty"`
+	Use   string `xml:"use,attr,omitempty"`
 }
@@ -0,0 +1,83 @@
+package wsdl
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestSequenceGroups(t *testing.T) {
+	data := `<complexType name="Person">
+		<sequence>
+			<element name="ID" type="xsd:int"/>
+			<group ref="tns:Name" minOccurs="0"/>
+			<element name="Notes" type="xsd:string"/>
+			<group ref="tns:Phone"/>
+		</sequence>
+		<attributeGroup ref="tns:Audit"/>
+	</complexType>`
+	var ct ComplexType
+	if err := xml.Unmarshal([]byte(data), &ct); err != nil {
+		t.Fatal(err)
+	}
+	seq := ct.Sequence
+	if seq == nil || len(seq.Elements) != 2 || len(seq.Groups) != 2 {
+		t.Fatalf("unexpected sequence: %#v", seq)
+	}
+	if g := seq.Groups[0]; g.Ref != "tns:Name" || g.Min != "0" || g.Position != 1 {
+		t.Fatalf("unexpected group: %#v", g)
+	}
+	if g := seq.Groups[1]; g.Ref != "tns:Phone" || g.Position != 2 {
+		t.Fatalf("unexpected group: %#v", g)
+	}
+	if len(ct.AttributeGroups) != 1 || ct.AttributeGroups[0].Ref != "tns:Audit" {
+		t.Fatalf("unexpected attribute groups: %#v", ct.AttributeGroups)
+	}
+}
+
+func TestSimpleTypeListAndUnion(t *testing.T) {
+	data := `<schema>
+		<simpleType name="Sizes">
+			<list itemType="xsd:int"/>
+		</simpleType>
+		<simpleType name="Size">
+			<union memberTypes="xsd:int tns:SizeName">
+				<simpleType>
+					<restriction base="xsd:string"/>
+				</simpleType>
+			</union>
+		</simpleType>
+	</schema>`
+	var s Schema
+	if err := xml.Unmarshal([]byte(data), &s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.SimpleTypes) != 2 {
+		t.Fatalf("unexpected simple types: %#v", s.SimpleTypes)
+	}
+	if l := s.SimpleTypes[0].List; l == nil || l.ItemType != "xsd:int" {
+		t.Fatalf("unexpected list: %#v", l)
+	}
+	u := s.SimpleTypes[1].Union
+	if u == nil || u.MemberTypes != "xsd:int tns:SizeName" || len(u.SimpleTypes) != 1 {
+		t.Fatalf("unexpected union: %#v", u)
+	}
+}
+
+func TestDefaultAndFixed(t *testing.T) {
+	data := `<complexType name="Order">
+		<sequence>
+			<element name="Currency" type="xsd:string" default="EUR"/>
+		</sequence>
+		<attribute name="version" type="xsd:string" fixed="2.1"/>
+	</complexType>`
+	var ct ComplexType
+	if err := xml.Unmarshal([]byte(data), &ct); err != nil {
+		t.Fatal(err)
+	}
+	if el := ct.Sequence.Elements[0]; el.Default != "EUR" || el.Fixed != "" {
+		t.Fatalf("unexpected element: %#v", el)
+	}
+	if attr := ct.Attributes[0]; attr.Fixed != "2.1" || attr.Default != "" {
+		t.Fatalf("unexpected attribute: %#v", attr)
+	}
+}
@@ -0,0 +1,268 @@
+package wsdl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// WSDL20Namespace is the namespace of WSDL 2.0 documents.
+const WSDL20Namespace = "http://www.w3.org/ns/wsdl"
+
+// WSOAPNamespace is the namespace of the WSDL 2.0 SOAP binding
+// extensions.
+const WSOAPNamespace = "http://www.w3.org/ns/wsdl/soap"
+
+// Message exchange patterns of WSDL 2.0 operations.
+const (
+	PatternInOnly       = "http://www.w3.org/ns/wsdl/in-only"
+	PatternRobustInOnly = "http://www.w3.org/ns/wsdl/robust-in-only"
+	PatternInOut        = "http://www.w3.org/ns/wsdl/in-out"
+)
+
+// Description is the root element of a WSDL 2.0 document.
+type Description struct {
+	XMLName         xml.Name              `xml:"http://www.w3.org/ns/wsdl description"`
+	TargetNamespace string                `xml:"targetNamespace,attr"`
+	Namespaces      map[string]string     `xml:"-"`
+	Doc             string                `xml:"documentation"`
+	Imports         []*Import             `xml:"import"`
+	Includes        []*Import             `xml:"include"`
+	Schema          Schema                `xml:"types>schema"`
+	Interfaces      []*Interface          `xml:"interface"`
+	Bindings        []*DescriptionBinding `xml:"binding"`
+	Services        []*DescriptionService `xml:"service"`
+}
+
+type descriptionDup Description
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (desc *Description) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if prefix, ok := declaration(attr); ok {
+			if desc.Namespaces == nil {
+				desc.Namespaces = make(map[string]string)
+			}
+			desc.Namespaces[prefix] = attr.Value
+		}
+	}
+	return d.DecodeElement((*descriptionDup)(desc), &start)
+}
+
+// Interface describes a set of operations, the WSDL 2.0 port type.
+type Interface struct {
+	XMLName    xml.Name              `xml:"interface"`
+	Name       string                `xml:"name,attr"`
+	Extends    string                `xml:"extends,attr"` // list of interfaces
+	Operations []*InterfaceOperation `xml:"operation"`
+}
+
+// InterfaceOperation describes an operation of an interface.
+type InterfaceOperation struct {
+	XMLName xml.Name            `xml:"operation"`
+	Name    string              `xml:"name,attr"`
+	Pattern string              `xml:"pattern,attr"`
+	Doc     string              `xml:"documentation"`
+	Input   []*MessageReference `xml:"input"`
+	Output  []*MessageReference `xml:"output"`
+}
+
+// MessageReference links an operation message to the element of its
+// content, or one of the #any, #none and #other tokens.
+type MessageReference struct {
+	MessageLabel string `xml:"messageLabel,attr"`
+	Element      string `xml:"element,attr"`
+}
+
+// DescriptionBinding describes the SOAP binding of an interface.
+type DescriptionBinding struct {
+	XMLName    xml.Name                       `xml:"binding"`
+	Name       string                         `xml:"name,attr"`
+	Interface  string                         `xml:"interface,attr"`
+	Type       string                         `xml:"type,attr"`
+	Version    string                         `xml:"http://www.w3.org/ns/wsdl/soap version,attr"`
+	Protocol   string                         `xml:"http://www.w3.org/ns/wsdl/soap protocol,attr"`
+	Operations []*DescriptionBindingOperation `xml:"operation"`
+}
+
+// DescriptionBindingOperation describes the SOAP binding of an
+// interface operation.
+type DescriptionBindingOperation struct {
+	XMLName xml.Name `xml:"operation"`
+	Ref     string   `xml:"ref,attr"`
+	Action  string   `xml:"http://www.w3.org/ns/wsdl/soap action,attr"`
+}
+
+// DescriptionService describes the endpoints of an interface.
+type DescriptionService struct {
+	XMLName   xml.Name    `xml:"service"`
+	Name      string      `xml:"name,attr"`
+	Interface string      `xml:"interface,attr"`
+	Doc       string      `xml:"documentation"`
+	Endpoints []*Endpoint `xml:"endpoint"`
+}
+
+// Endpoint is the address of a binding, the WSDL 2.0 port.
+type Endpoint struct {
+	XMLName xml.Name `xml:"endpoint"`
+	Name    string   `xml:"name,attr"`
+	Binding string   `xml:"binding,attr"`
+	Address string   `xml:"address,attr"`
+}
+
+// Definitions converts the WSDL 2.0 description to the WSDL 1.1 model
+// that the code generator consumes. Like Definitions, it holds a single
+// port type and binding: the binding of the first endpoint, or the first
+// binding, and its interface. Each operation gets Request and Response
+// messages with a single part for the element of its input and output,
+// and in-only operations have no output. Faults are not converted.
+func (desc *Description) Definitions() (*Definitions, error) {
+	d := &Definitions{
+		TargetNamespace: desc.TargetNamespace,
+		Namespaces:      desc.Namespaces,
+		Imports:         append(desc.Imports, desc.Includes...),
+		Schema:          desc.Schema,
+	}
+	binding := desc.binding()
+	if binding == nil {
+		return d, nil
+	}
+	d.Name = binding.Name
+	d.Binding = Binding{
+		Name: binding.Name,
+		Type: binding.Interface,
+		BindingType: &BindingType{
+			Style:     "document",
+			Transport: "http://schemas.xmlsoap.org/soap/http",
+		},
+	}
+	for _, s := range desc.Services {
+		d.Service.Name = s.Name
+		d.Service.Doc = s.Doc
+		d.Service.Docs = textDocs(s.Doc)
+		for _, e := range s.Endpoints {
+			if trimPrefix(e.Binding) != binding.Name {
+				continue
+			}
+			d.Service.Ports = append(d.Service.Ports, &Port{
+				Name:    e.Name,
+				Binding: e.Binding,
+				Address: Address{Location: e.Address},
+			})
+		}
+	}
+	iface := desc.iface(binding.Interface)
+	if iface == nil {
+		return nil, fmt.Errorf("wsdl: interface %q of binding %q is not defined", binding.Interface, binding.Name)
+	}
+	d.PortType.Name = iface.Name
+	ops, err := desc.operations(iface, make(map[*Interface]bool))
+	if err != nil {
+		return nil, err
+	}
+	actions := make(map[string]string)
+	for _, bo := range binding.Operations {
+		actions[trimPrefix(bo.Ref)] = bo.Action
+	}
+	for _, op := range ops {
+		pop := &Operation{Name: op.Name, Doc: op.Doc, Docs: textDocs(op.Doc)}
+		bop := &BindingOperation{Name: op.Name}
+		if binding.Version == "1.1" {
+			bop.Operation11.Action = actions[op.Name]
+		} else {
+			bop.Operation.Action = actions[op.Name]
+		}
+		pop.Input = &IO{Message: d.addMessage(op.Name+"Request", op.Input)}
+		bop.Input = &BindingIO{Use: "literal"}
+		switch op.Pattern {
+		case "", PatternInOut:
+			pop.Output = &IO{Message: d.addMessage(op.Name+"Response", op.Output)}
+			bop.Output = &BindingIO{Use: "literal"}
+		case PatternInOnly, PatternRobustInOnly:
+		default:
+			return nil, fmt.Errorf("wsdl: operation %q: unsupported message exchange pattern %q", op.Name, op.Pattern)
+		}
+		d.PortType.Operations = append(d.PortType.Operations, pop)
+		d.Binding.Operations = append(d.Binding.Operations, bop)
+	}
+	return d, nil
+}
+
+// binding returns the binding of the first endpoint, or the first
+// binding of the description.
+func (desc *Description) binding() *DescriptionBinding {
+	for _, s := range desc.Services {
+		for _, e := range s.Endpoints {
+			for _, b := range desc.Bindings {
+				if b.Name == trimPrefix(e.Binding) {
+					return b
+				}
+			}
+		}
+	}
+	if len(desc.Bindings) > 0 {
+		return desc.Bindings[0]
+	}
+	return nil
+}
+
+func (desc *Description) iface(name string) *Interface {
+	for _, i := range desc.Interfaces {
+		if i.Name == trimPrefix(name) {
+			return i
+		}
+	}
+	return nil
+}
+
+// operations returns the operations of iface and the interfaces it
+// extends, in order.
+func (desc *Description) operations(iface *Interface, seen map[*Interface]bool) ([]*InterfaceOperation, error) {
+	if seen[iface] {
+		return nil, nil
+	}
+	seen[iface] = true
+	var ops []*InterfaceOperation
+	for _, name := range strings.Fields(iface.Extends) {
+		base := desc.iface(name)
+		if base == nil {
+			return nil, fmt.Errorf("wsdl: interface %q extended by %q is not defined", name, iface.Name)
+		}
+		l, err := desc.operations(base, seen)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, l...)
+	}
+	return append(ops, iface.Operations...), nil
+}
+
+// addMessage adds a message for the content of the message references
+// refs, and returns its name. Messages of the #any, #none and #other
+// tokens have no parts.
+func (d *Definitions) addMessage(name string, refs []*MessageReference) string {
+	m := &Message{Name: name}
+	if len(refs) > 0 && !strings.HasPrefix(refs[0].Element, "#") && refs[0].Element != "" {
+		m.Parts = []*Part{{Name: "parameters", Element: refs[0].Element}}
+	}
+	d.Messages = append(d.Messages, m)
+	// qualified like the messages of WSDL 1.1 documents, with the
+	// prefix of the target namespace in SOAP envelopes
+	return "tns:" + name
+}
+
+// trimPrefix returns the local part of a QName.
+func trimPrefix(s string) string {
+	if n := strings.Index(s, ":"); n >= 0 {
+		return s[n+1:]
+	}
+	return s
+}
+
+// textDocs returns the documentation of the text s, without language.
+func textDocs(s string) Docs {
+	if s == "" {
+		return nil
+	}
+	return Docs{{Text: s}}
+}
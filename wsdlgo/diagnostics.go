@@ -0,0 +1,100 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// Diagnostic is a warning about a schema construct that the encoder
+// dropped or approximated in the generated code.
+type Diagnostic struct {
+	// Path is the schema path of the offending node, e.g.
+	// complexType[@name='Item']/element[@name='Name'].
+	Path string
+
+	// Message describes what was dropped or approximated.
+	Message string
+
+	// Pos is the position of the nearest enclosing node of the path
+	// whose position is known, if any.
+	Pos wsdl.Position
+}
+
+func (d Diagnostic) String() string {
+	if d.Pos.IsValid() {
+		return d.Pos.String() + ": " + d.Path + ": " + d.Message
+	}
+	return d.Path + ": " + d.Message
+}
+
+// StrictError is returned by Encode in strict mode when any construct
+// was dropped or approximated.
+type StrictError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *StrictError) Error() string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "strict mode: %d schema constructs were dropped or approximated:", len(e.Diagnostics))
+	for _, d := range e.Diagnostics {
+		fmt.Fprintf(&b, "\n\t%s", d)
+	}
+	return b.String()
+}
+
+// enter pushes the node kind[@name='name'] to the current schema path.
+func (ge *goEncoder) enter(kind, name string) {
+	ge.enterAt(kind, name, wsdl.Position{})
+}
+
+// enterAt pushes the node kind[@name='name'] at pos in its document to
+// the current schema path.
+func (ge *goEncoder) enterAt(kind, name string, pos wsdl.Position) {
+	if pos.IsValid() || len(ge.positions) == 0 {
+		ge.positions = append(ge.positions, pos)
+	} else {
+		ge.positions = append(ge.positions, ge.positions[len(ge.positions)-1])
+	}
+	if name == "" {
+		ge.path = append(ge.path, kind)
+		return
+	}
+	ge.path = append(ge.path, fmt.Sprintf("%s[@name='%s']", kind, name))
+}
+
+// leave pops the last node of the current schema path.
+func (ge *goEncoder) leave() {
+	ge.path = ge.path[:len(ge.path)-1]
+	ge.positions = ge.positions[:len(ge.positions)-1]
+}
+
+// warn records a diagnostic for the current schema path. The same
+// diagnostic is only recorded once.
+func (ge *goEncoder) warn(format string, args ...interface{}) {
+	d := Diagnostic{
+		Path:    strings.Join(ge.path, "/"),
+		Message: fmt.Sprintf(format, args...),
+	}
+	if len(ge.positions) > 0 {
+		d.Pos = ge.positions[len(ge.positions)-1]
+	}
+	if ge.seenDiags[d] {
+		return
+	}
+	ge.seenDiags[d] = true
+	ge.diags = append(ge.diags, d)
+}
+
+// Diagnostics returns the constructs dropped or approximated by Encode.
+func (ge *goEncoder) Diagnostics() []Diagnostic {
+	return ge.diags
+}
+
+// SetStrict makes Encode fail when any construct was dropped or
+// approximated.
+func (ge *goEncoder) SetStrict(enabled bool) {
+	ge.strict = enabled
+}
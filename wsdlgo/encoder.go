@@ -8,13 +8,11 @@ package wsdlgo
 import (
 	"bufio"
 	"bytes"
-	"encoding/xml"
 	"fmt"
 	"go/parser"
 	"go/token"
 	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -23,9 +21,12 @@ import (
 	"strconv"
 	"strings"
 	"text/template"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/fiorix/wsdl2go/model"
+	"github.com/fiorix/wsdl2go/resolver"
 	"github.com/fiorix/wsdl2go/wsdl"
-	"golang.org/x/net/html/charset"
 )
 
 // An Encoder generates Go code from WSDL definitions.
@@ -38,40 +39,102 @@ type Encoder interface {
 
 	// SetClient records the given http client that
 	// is used when fetching remote parts of WSDL
-	// and WSDL schemas.
+	// and WSDL schemas. Credentials, headers and time limits of
+	// the requests are set up in the client.
 	SetClient(c *http.Client)
 
+	// SetLocation records the location of the WSDL document, a file
+	// path or URL, which relative locations of its imports and
+	// includes are resolved against.
+	SetLocation(loc string)
+
+	// SetResolver records the resolver that reads imports and
+	// includes: files, URLs, documents in archives, and the local
+	// copies that its catalog maps their locations to.
+	SetResolver(r *resolver.Resolver)
+
 	// SetLocalNamespace allows overriding of the Namespace in XMLName instead
 	// of the one specified in wsdl
 	SetLocalNamespace(namespace string)
+
+	// SetGenerateMocks enables generation of a mock implementation
+	// of the port type interface, for use in tests.
+	SetGenerateMocks(enabled bool)
+
+	// SetUnwrap makes the methods of document/literal wrapped
+	// operations take and return the child elements of the wrapper
+	// elements, instead of the wrapper elements.
+	SetUnwrap(enabled bool)
+
+	// SetNillable generates nillable elements as wrappers of their
+	// types that tell xsi:nil="true" from an absent element, e.g.
+	// NillableString, instead of pointers to their types.
+	SetNillable(enabled bool)
+
+	// SetDocLanguage selects the language of the documentation that
+	// is emitted as Go comments, such as "en" or "pt-BR", for WSDL
+	// documents documented in several languages with xml:lang.
+	SetDocLanguage(lang string)
+
+	// SetStrict makes Encode fail, without generating code, when any
+	// schema construct was dropped or approximated.
+	SetStrict(enabled bool)
+
+	// Diagnostics returns the schema constructs that were dropped or
+	// approximated by Encode.
+	Diagnostics() []Diagnostic
 }
 
 type goEncoder struct {
 	// where to write Go code
 	w io.Writer
 
-	// http client
-	http *http.Client
+	// reader of imported and included documents
+	resolver *resolver.Resolver
 
 	// some mechanism to name package
 	packageName fmt.Stringer
 
-	// types cache
-	stypes map[string]*wsdl.SimpleType
-	ctypes map[string]*wsdl.ComplexType
+	// Go names of the types
+	goNames map[model.Name]string
 
-	// elements cache
-	elements map[string]*wsdl.Element
+	// members of substitution groups, by head element, and the Go
+	// types of the fields of substitution groups, by name
+	substitutes map[model.Name][]*wsdl.Element
+	substGroups map[string]model.Name
 
-	// funcs cache
-	funcs     map[string]*wsdl.Operation
-	funcnames []string
+	// namespace context of the names being resolved, and the target
+	// namespace of the document
+	scope           model.Scope
+	targetNamespace string
 
-	// messages cache
-	messages map[string]*wsdl.Message
+	// whether the schema has abstract types or substitution groups,
+	// which require the type registry
+	polymorphic bool
 
-	// soap operations cache
-	soapOps map[string]*wsdl.BindingOperation
+	// whether the struct being generated has a field tagged ",any",
+	// and whether it is the Any field of xs:any wildcards
+	structAny      bool
+	structWildcard bool
+
+	// choices of the struct being generated, validated on marshal
+	structChoices []*choiceFields
+
+	// wrappers of the Go types of nillable elements, by name
+	nillables map[string]string
+
+	// default and fixed values of the fields of the struct being
+	// generated
+	structValues []*fieldValue
+	structFixed  []*fieldValue
+
+	// model and attribute groups being expanded
+	expanding map[interface{}]bool
+
+	// resolved model of the document, and its port type and binding
+	model    *model.Definitions
+	portType *model.PortType
+	binding  *model.Binding
 
 	// whether to add supporting types
 	needsDateType     bool
@@ -82,27 +145,52 @@ type goEncoder struct {
 	needsStdPkg       map[string]bool
 	needsExtPkg       map[string]bool
 	importedSchemas   map[string]bool
-	usedNamespaces    map[string]string
+
+	// location of the document, the base of relative imports
+	location string
+
+	// function called with the content of each document loaded
+	loaded func(loc string, data []byte) error
 
 	// localNamespace allows overriding of namespace in XMLName
 	localNamespace string
+
+	// whether to generate a mock of the port type interface
+	genMocks bool
+
+	// language of the documentation emitted as comments
+	docLang string
+
+	// whether to unwrap the parameters of wrapped operations, and
+	// whether the binding is rpc style, which has no wrapped operations
+	unwrap bool
+	rpc    bool
+
+	// whether to generate the wrappers of nillable elements
+	nillable bool
+
+	// dropped or approximated constructs, and the current schema path
+	diags     []Diagnostic
+	seenDiags map[Diagnostic]bool
+	path      []string
+	positions []wsdl.Position
+	strict    bool
 }
 
 // NewEncoder creates and initializes an Encoder that generates code to w.
 func NewEncoder(w io.Writer) Encoder {
 	return &goEncoder{
 		w:               w,
-		http:            http.DefaultClient,
-		stypes:          make(map[string]*wsdl.SimpleType),
-		ctypes:          make(map[string]*wsdl.ComplexType),
-		elements:        make(map[string]*wsdl.Element),
-		funcs:           make(map[string]*wsdl.Operation),
-		messages:        make(map[string]*wsdl.Message),
-		soapOps:         make(map[string]*wsdl.BindingOperation),
+		resolver:        &resolver.Resolver{},
+		substitutes:     make(map[model.Name][]*wsdl.Element),
+		substGroups:     make(map[string]model.Name),
+		expanding:       make(map[interface{}]bool),
+		nillables:       make(map[string]string),
 		needsTag:        make(map[string]string),
 		needsStdPkg:     make(map[string]bool),
 		needsExtPkg:     make(map[string]bool),
 		importedSchemas: make(map[string]bool),
+		seenDiags:       make(map[Diagnostic]bool),
 	}
 }
 
@@ -111,7 +199,7 @@ func (ge *goEncoder) SetPackageName(name fmt.Stringer) {
 }
 
 func (ge *goEncoder) SetClient(c *http.Client) {
-	ge.http = c
+	ge.resolver.Client = c
 }
 
 func gofmtPath() (string, error) {
@@ -150,6 +238,9 @@ func (ge *goEncoder) Encode(d *wsdl.Definitions) error {
 	if b.Len() == 0 {
 		return nil
 	}
+	if ge.strict && len(ge.diags) > 0 {
+		return &StrictError{Diagnostics: ge.diags}
+	}
 	var errb bytes.Buffer
 	input := b.String()
 
@@ -192,20 +283,28 @@ func (ge *goEncoder) Encode(d *wsdl.Definitions) error {
 func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
 	ge.unionSchemasData(d, &d.Schema)
 	err := ge.importParts(d)
-	ge.usedNamespaces = d.Namespaces
 	if err != nil {
 		return fmt.Errorf("wsdl import: %v", err)
 	}
-	ge.cacheTypes(d)
-	ge.cacheFuncs(d)
-	ge.cacheMessages(d)
-	ge.cacheSOAPOperations(d)
+	ge.model = model.Build(d)
+	ge.portType = ge.model.PortTypes[0]
+	if len(ge.model.Bindings) > 0 {
+		ge.binding = ge.model.Bindings[0]
+		ge.rpc = ge.binding.Style == "rpc"
+	}
+	// names in messages are resolved in the scope of the document
+	ge.targetNamespace = d.TargetNamespace
+	ge.scope = model.Scope{Namespaces: d.Namespaces, Document: d.Namespaces, Target: d.TargetNamespace}
+	ge.prepareTypes(d)
 
 	var b bytes.Buffer
 	var ff []func(io.Writer, *wsdl.Definitions) error
-	if len(ge.soapOps) > 0 {
+	if ge.binding != nil && len(ge.binding.Operations) > 0 {
+		ff = append(ff, ge.writeInterfaceFuncs)
+		if ge.genMocks {
+			ff = append(ff, ge.writeMockType)
+		}
 		ff = append(ff,
-			ge.writeInterfaceFuncs,
 			ge.writeGoTypes,
 			ge.writePortType,
 			ge.writeGoFuncs,
@@ -217,6 +316,9 @@ func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
 			ge.writeGoTypes,
 		)
 	}
+	if ge.polymorphic {
+		ff = append(ff, ge.writeTypeRegistry)
+	}
 	for _, f := range ff {
 		err := f(&b, d)
 		if err != nil {
@@ -243,152 +345,244 @@ func (ge *goEncoder) encode(w io.Writer, d *wsdl.Definitions) error {
 	return err
 }
 
-func (ge *goEncoder) importParts(d *wsdl.Definitions) error {
-	err := ge.importRoot(d)
-	if err != nil {
-		return err
+// prepareTypes expands the model and attribute groups of the complex
+// types and global elements in place, names the element references of
+// complex types after the elements they refer to, and collects the
+// substitution groups.
+func (ge *goEncoder) prepareTypes(d *wsdl.Definitions) {
+	ge.assignGoNames()
+	for _, name := range ge.sortedComplexTypes() {
+		ct := ge.model.ComplexType(name)
+		leave := ge.enterScope(ct.Namespaces, name.Space)
+		ge.expandGroups(ct)
+		leave()
 	}
-	return ge.importSchema(d)
-}
-
-func (ge *goEncoder) importRoot(d *wsdl.Definitions) error {
-	for _, imp := range d.Imports {
-		if imp.Location == "" {
-			continue
+	for _, v := range d.Schema.Elements {
+		if v.ComplexType != nil {
+			leave := ge.enterScope(v.Namespaces, v.TargetNamespace)
+			ge.enterAt("element", v.Name, v.Pos)
+			ge.expandGroups(v.ComplexType)
+			ge.leave()
+			leave()
 		}
-		err := ge.importRemote(imp.Location, &d)
-		if err != nil {
-			return err
+	}
+	for _, name := range ge.sortedComplexTypes() {
+		ct := ge.model.ComplexType(name)
+		if ct.Abstract {
+			ge.polymorphic = true
+		}
+		ge.nameComplexTypeRefs(ct)
+	}
+	// substitution groups and abstract types are decoded using the
+	// type registry
+	for _, v := range d.Schema.Elements {
+		if v.SubstitutionGroup != "" {
+			leave := ge.enterScope(v.Namespaces, v.TargetNamespace)
+			head, _, _ := ge.lookupElement(v.SubstitutionGroup)
+			leave()
+			if !containsElement(ge.substitutes[head], v.Name) {
+				ge.substitutes[head] = append(ge.substitutes[head], v)
+			}
+			ge.polymorphic = true
 		}
 	}
-	return nil
 }
 
-func (ge *goEncoder) importSchema(d *wsdl.Definitions) error {
-	for _, imp := range d.Schema.Imports {
-		if imp.Location == "" {
-			continue
-		}
-		schema := &wsdl.Schema{}
-		err := ge.importRemote(imp.Location, schema)
-		if err != nil {
-			return err
+// expandGroups replaces the group and attribute group references of ct
+// with the elements and attributes of the groups.
+func (ge *goEncoder) expandGroups(ct *wsdl.ComplexType) {
+	ge.enterAt("complexType", ct.Name, ct.Pos)
+	defer ge.leave()
+	if ct.Group != nil {
+		if ct.Sequence == nil {
+			ct.Sequence = &wsdl.Sequence{}
 		}
-		ge.unionSchemasData(d, schema)
-		for _, item := range schema.Imports {
-			schema = &wsdl.Schema{}
-			err := ge.importRemote(item.Location, schema)
-			if err != nil {
-				return err
-			}
-			ge.unionSchemasData(d, schema)
+		ct.Sequence.Groups = append(ct.Sequence.Groups, ct.Group)
+		ct.Group = nil
+	}
+	ge.expandSequence(ct.Sequence)
+	ge.expandChoice(ct.Choice)
+	ct.Attributes = ge.expandAttributeGroups(ct.Attributes, ct.AttributeGroups)
+	ct.AttributeGroups = nil
+	for _, el := range ct.AllElements {
+		if el.ComplexType != nil {
+			ge.expandGroups(el.ComplexType)
 		}
-		for _, item := range schema.Includes {
-			schema = &wsdl.Schema{}
-			err := ge.importRemote(item.Location, schema)
-			if err != nil {
-				return err
+	}
+	exts := make([]*wsdl.Extension, 0, 2)
+	if ct.ComplexContent != nil && ct.ComplexContent.Extension != nil {
+		exts = append(exts, ct.ComplexContent.Extension)
+	}
+	if ct.SimpleContent != nil && ct.SimpleContent.Extension != nil {
+		exts = append(exts, ct.SimpleContent.Extension)
+	}
+	for _, ext := range exts {
+		if ext.Group != nil {
+			if ext.Sequence == nil {
+				ext.Sequence = &wsdl.Sequence{}
 			}
-			ge.unionSchemasData(d, schema)
+			ext.Sequence.Groups = append(ext.Sequence.Groups, ext.Group)
+			ext.Group = nil
 		}
+		ge.expandSequence(ext.Sequence)
+		ge.expandChoice(ext.Choice)
+		ext.Attributes = ge.expandAttributeGroups(ext.Attributes, ext.AttributeGroups)
+		ext.AttributeGroups = nil
 	}
-	return nil
 }
 
-func (ge *goEncoder) unionSchemasData(d *wsdl.Definitions, s *wsdl.Schema) {
-	for ns := range s.Namespaces {
-		d.Namespaces[ns] = s.Namespaces[ns]
+// expandSequence inserts the elements of the groups referenced by seq
+// at the position of the references.
+func (ge *goEncoder) expandSequence(seq *wsdl.Sequence) {
+	if seq == nil {
+		return
 	}
-	for _, ct := range s.ComplexTypes {
-		ct.TargetNamespace = s.TargetNamespace
+	if len(seq.Groups) > 0 {
+		var elements []*wsdl.Element
+		i := 0
+		for _, ref := range seq.Groups {
+			elements = append(elements, seq.Elements[i:ref.Position]...)
+			i = ref.Position
+			els, choices := ge.groupParticles(ref)
+			elements = append(elements, els...)
+			seq.Choices = append(seq.Choices, choices...)
+		}
+		seq.Elements = append(elements, seq.Elements[i:]...)
+		seq.Groups = nil
 	}
-	for _, st := range s.SimpleTypes {
-		st.TargetNamespace = s.TargetNamespace
+	for _, c := range seq.Choices {
+		ge.expandChoice(c)
 	}
-	d.Schema.ComplexTypes = append(d.Schema.ComplexTypes, s.ComplexTypes...)
-	d.Schema.SimpleTypes = append(d.Schema.SimpleTypes, s.SimpleTypes...)
-	d.Schema.Elements = append(d.Schema.Elements, s.Elements...)
-}
-
-// download xml from url, decode in v.
-func (ge *goEncoder) importRemote(loc string, v interface{}) error {
-	_, alreadyImported := ge.importedSchemas[loc]
-	if alreadyImported {
-		return nil
+	for _, el := range seq.Elements {
+		if el.ComplexType != nil {
+			ge.expandGroups(el.ComplexType)
+		}
 	}
-
-	u, err := url.Parse(loc)
-	if err != nil {
-		return err
+	for _, ct := range seq.ComplexTypes {
+		ge.expandGroups(ct)
 	}
+}
 
-	var r io.Reader
-	switch u.Scheme {
-	case "http", "https":
-		resp, err := ge.http.Get(loc)
-		if err != nil {
-			return err
+// expandChoice adds the elements of the groups referenced by c to its
+// alternatives.
+func (ge *goEncoder) expandChoice(c *wsdl.Choice) {
+	if c == nil {
+		return
+	}
+	for _, ref := range c.Groups {
+		els, choices := ge.groupParticles(ref)
+		c.Elements = append(c.Elements, els...)
+		for _, nc := range choices {
+			c.Elements = append(c.Elements, nc.Elements...)
 		}
-		ge.importedSchemas[loc] = true
-		defer resp.Body.Close()
-		r = resp.Body
-	default:
-		file, err := os.Open(u.Path)
-		if err != nil {
-			return fmt.Errorf("could not open file raw: %s path: %s escaped: %s : %v", u.RawPath, u.Path, u.EscapedPath(), err)
+	}
+	c.Groups = nil
+	for _, el := range c.Elements {
+		if el.ComplexType != nil {
+			ge.expandGroups(el.ComplexType)
 		}
-
-		r = bufio.NewReader(file)
 	}
-	decoder := xml.NewDecoder(r)
-	decoder.CharsetReader = charset.NewReaderLabel
-	return decoder.Decode(&v)
-
 }
 
-func (ge *goEncoder) cacheTypes(d *wsdl.Definitions) {
-	// operation types are declared as go struct types
-	for _, v := range d.Schema.Elements {
-		if v.Type == "" && v.ComplexType != nil {
-			ct := *v.ComplexType
-			ct.Name = v.Name
-			ge.ctypes[v.Name] = &ct
+// groupParticles returns the elements and choices of the group
+// referenced by ref. The occurrence constraints of the reference apply
+// to the elements and choices.
+func (ge *goEncoder) groupParticles(ref *wsdl.Group) ([]*wsdl.Element, []*wsdl.Choice) {
+	g, ok := ge.lookupGroup(ref.Ref)
+	if !ok {
+		ge.warn("group reference %q cannot be resolved, its elements are dropped", ref.Ref)
+		return nil, nil
+	}
+	if ge.expanding[g] {
+		ge.warn("group %q refers to itself, the reference is dropped", g.Name)
+		return nil, nil
+	}
+	ge.expanding[g] = true
+	defer delete(ge.expanding, g)
+	defer ge.enterScope(g.Namespaces, g.TargetNamespace)()
+	ge.enter("group", g.Name)
+	defer ge.leave()
+
+	var elements []*wsdl.Element
+	var choices []*wsdl.Choice
+	if g.Sequence != nil {
+		ge.expandSequence(g.Sequence)
+		elements = append(elements, g.Sequence.Elements...)
+		choices = append(choices, g.Sequence.Choices...)
+	}
+	if g.Choice != nil {
+		ge.expandChoice(g.Choice)
+		choices = append(choices, g.Choice)
+	}
+	elements = append(elements, g.AllElements...)
+
+	optional := ref.Min == "0"
+	repeated := ref.Max != "" && ref.Max != "1"
+	if !optional && !repeated {
+		return elements, choices
+	}
+	for i, el := range elements {
+		nel := *el
+		if optional {
+			nel.Min = 0
 		}
+		if repeated {
+			nel.Max = ref.Max
+		}
+		elements[i] = &nel
 	}
-	// simple types map 1:1 to go basic types
-	for _, v := range d.Schema.SimpleTypes {
-		ge.stypes[v.Name] = v
-	}
-	// complex types are declared as go struct types
-	for _, v := range d.Schema.ComplexTypes {
-		ge.ctypes[v.Name] = v
+	for i, c := range choices {
+		nc := *c
+		if optional {
+			nc.Min = "0"
+		}
+		if repeated {
+			nc.Max = ref.Max
+		}
+		choices[i] = &nc
 	}
-	// cache elements from schema
-	ge.cacheElements(d.Schema.Elements)
-	// cache elements from complex types
-	for _, ct := range ge.ctypes {
-		ge.cacheComplexTypeElements(ct)
+	return elements, choices
+}
+
+// expandAttributeGroups returns attrs with the attributes of the
+// referenced attribute groups.
+func (ge *goEncoder) expandAttributeGroups(attrs []*wsdl.Attribute, refs []*wsdl.AttributeGroup) []*wsdl.Attribute {
+	for _, ref := range refs {
+		ag, ok := ge.lookupAttributeGroup(ref.Ref)
+		if !ok {
+			ge.warn("attribute group reference %q cannot be resolved, its attributes are dropped", ref.Ref)
+			continue
+		}
+		if ge.expanding[ag] {
+			ge.warn("attribute group %q refers to itself, the reference is dropped", ag.Name)
+			continue
+		}
+		ge.expanding[ag] = true
+		leave := ge.enterScope(ag.Namespaces, ag.TargetNamespace)
+		nested := append([]*wsdl.Attribute(nil), ag.Attributes...)
+		attrs = append(attrs, ge.expandAttributeGroups(nested, ag.AttributeGroups)...)
+		leave()
+		delete(ge.expanding, ag)
 	}
+	return attrs
 }
 
-func (ge *goEncoder) cacheChoiceTypeElements(choice *wsdl.Choice) {
+func (ge *goEncoder) nameChoiceRefs(choice *wsdl.Choice) {
 	if choice != nil {
 		for _, cct := range choice.ComplexTypes {
-			ge.cacheComplexTypeElements(cct)
+			ge.nameComplexTypeRefs(cct)
 		}
-		ge.cacheElements(choice.Elements)
+		nameRefs(choice.Elements)
 	}
 }
 
-func (ge *goEncoder) cacheComplexTypeElements(ct *wsdl.ComplexType) {
-	if ct.AllElements != nil {
-		ge.cacheElements(ct.AllElements)
-	}
+func (ge *goEncoder) nameComplexTypeRefs(ct *wsdl.ComplexType) {
+	nameRefs(ct.AllElements)
 	if ct.Sequence != nil {
-		ge.cacheElements(ct.Sequence.Elements)
+		nameRefs(ct.Sequence.Elements)
 	}
 	if ct.Choice != nil {
-		ge.cacheElements(ct.Choice.Elements)
+		nameRefs(ct.Choice.Elements)
 	}
 
 	cc := ct.ComplexContent
@@ -397,72 +591,53 @@ func (ge *goEncoder) cacheComplexTypeElements(ct *wsdl.ComplexType) {
 		if cce != nil && cce.Sequence != nil {
 			seq := cce.Sequence
 			for _, cct := range seq.ComplexTypes {
-				ge.cacheComplexTypeElements(cct)
+				ge.nameComplexTypeRefs(cct)
 			}
-			ge.cacheElements(seq.Elements)
+			nameRefs(seq.Elements)
 
 			//Add in Choice elements
 			for _, choice := range seq.Choices {
-				ge.cacheChoiceTypeElements(choice)
+				ge.nameChoiceRefs(choice)
 			}
 		}
 		if cce != nil && cce.Choice != nil {
-			ge.cacheChoiceTypeElements(cce.Choice)
+			ge.nameChoiceRefs(cce.Choice)
 		}
 	}
 }
 
-func (ge *goEncoder) cacheElements(ct []*wsdl.Element) {
-	for _, el := range ct {
-		if el.Name == "" || el.Type == "" {
-			if el.Ref == "" {
-				continue
-			}
+// nameRefs names the element references of l, which have no name or
+// type, after the element they refer to.
+func nameRefs(l []*wsdl.Element) {
+	for _, el := range l {
+		if el.Ref != "" && (el.Name == "" || el.Type == "") {
 			el.Name = trimns(el.Ref)
-			el.Type = el.Name
-		}
-		name := trimns(el.Name)
-		if _, exists := ge.elements[name]; exists {
-			continue
-		}
-		ge.elements[name] = el
-		ct := el.ComplexType
-		if ct != nil {
-			ge.cacheElements(ct.AllElements)
-			if ct.Sequence != nil {
-				ge.cacheElements(ct.Sequence.Elements)
-			}
-			if ct.Choice != nil {
-				ge.cacheElements(ct.Choice.Elements)
-			}
+			el.Type = el.Ref
 		}
 	}
 }
 
-func (ge *goEncoder) cacheFuncs(d *wsdl.Definitions) {
-	// operations are declared as boilerplate go functions
-	for _, v := range d.PortType.Operations {
-		ge.funcs[v.Name] = v
-	}
-	ge.funcnames = make([]string, len(ge.funcs))
-	i := 0
-	for k := range ge.funcs {
-		ge.funcnames[i] = k
-		i++
-	}
-	sort.Strings(ge.funcnames)
-}
-
-func (ge *goEncoder) cacheMessages(d *wsdl.Definitions) {
-	for _, v := range d.Messages {
-		ge.messages[v.Name] = v
+// operations returns the operations of the port type sorted by name,
+// which are declared as boilerplate go functions. Of operations with the
+// same name, the last one is kept.
+func (ge *goEncoder) operations() []*model.Operation {
+	var ops []*model.Operation
+	for _, op := range ge.portType.SortedOperations() {
+		if n := len(ops); n > 0 && ops[n-1].Name == op.Name {
+			ops[n-1] = op
+			continue
+		}
+		ops = append(ops, op)
 	}
+	return ops
 }
 
-func (ge *goEncoder) cacheSOAPOperations(d *wsdl.Definitions) {
-	for _, v := range d.Binding.Operations {
-		ge.soapOps[v.Name] = v
+// bindingOp returns the SOAP binding of the operation named name, or nil.
+func (ge *goEncoder) bindingOp(name string) *model.BindingOperation {
+	if ge.binding == nil {
+		return nil
 	}
+	return ge.binding.Operation(name)
 }
 
 var interfaceTypeT = template.Must(template.New("interfaceType").Parse(`
@@ -473,7 +648,7 @@ func New{{.Name}}(cli *soap.Client) {{.Name}} {
 
 // {{.Name}} was auto-generated from WSDL
 // and defines interface for the remote service. Useful for testing.
-type {{.Name}} interface {
+{{.Doc}}type {{.Name}} interface {
 {{- range .Funcs }}
 {{.Doc}}{{.Name}}({{.Input}}) ({{.Output}})
 {{ end }}
@@ -485,16 +660,17 @@ type interfaceTypeFunc struct{ Doc, Name, Input, Output string }
 // writeInterfaceFuncs writes Go interface definitions from WSDL types to w.
 // Functions are written in the same order of the WSDL document.
 func (ge *goEncoder) writeInterfaceFuncs(w io.Writer, d *wsdl.Definitions) error {
-	funcs := make([]*interfaceTypeFunc, len(ge.funcs))
+	ops := ge.operations()
+	funcs := make([]*interfaceTypeFunc, len(ops))
 	// Looping over the operations to determine what are the interface
 	// functions.
 	i := 0
-	for _, fn := range ge.funcnames {
-		op := ge.funcs[fn]
-		if _, exists := ge.soapOps[op.Name]; !exists {
+	for _, op := range ops {
+		if ge.bindingOp(op.Name) == nil {
 			// TODO: probably faulty wsdl?
 			continue
 		}
+		ge.enterAt("operation", op.Name, op.WSDL.Pos)
 		inParams, err := ge.inputParams(op)
 		if err != nil {
 			return err
@@ -503,10 +679,11 @@ func (ge *goEncoder) writeInterfaceFuncs(w io.Writer, d *wsdl.Definitions) error
 		if err != nil {
 			return err
 		}
+		ge.leave()
 		in, out := code(inParams), codeParams(outParams)
 		name := goSymbol(op.Name)
 		var doc bytes.Buffer
-		ge.writeComments(&doc, name, op.Doc)
+		ge.writeComments(&doc, name, ge.doc(op.WSDL.Docs))
 		funcs[i] = &interfaceTypeFunc{
 			Doc:    doc.String(),
 			Name:   name,
@@ -515,14 +692,23 @@ func (ge *goEncoder) writeInterfaceFuncs(w io.Writer, d *wsdl.Definitions) error
 		}
 		i++
 	}
+	// the documentation of the service is a paragraph of the
+	// documentation of the interface
+	var doc bytes.Buffer
+	if v := ge.doc(d.Service.Docs); v != "" {
+		doc.WriteString("//\n")
+		ge.writeComments(&doc, "", v)
+	}
 	n := d.PortType.Name
 	return interfaceTypeT.Execute(w, &struct {
 		Name  string
 		Impl  string // private type that implements the interface
+		Doc   string
 		Funcs []*interfaceTypeFunc
 	}{
 		goSymbol(n),
 		strings.ToLower(n)[:1] + n[1:],
+		doc.String(),
 		funcs[:i],
 	})
 }
@@ -536,7 +722,7 @@ type {{.Name}} struct {
 `))
 
 func (ge *goEncoder) writePortType(w io.Writer, d *wsdl.Definitions) error {
-	if len(ge.funcs) == 0 {
+	if len(ge.portType.Operations) == 0 {
 		return nil
 	}
 	n := d.PortType.Name
@@ -549,6 +735,141 @@ func (ge *goEncoder) writePortType(w io.Writer, d *wsdl.Definitions) error {
 	})
 }
 
+var mockTypeT = template.Must(template.New("mockType").Parse(`
+// {{.Name}} is a mock implementation of the {{.Interface}}
+// interface. Calls are recorded and delegated to the function fields,
+// so tests don't require a SOAP server.
+type {{.Name}} struct {
+	mu sync.Mutex
+{{range .Funcs}}
+	// {{.Name}}Func is called by {{.Name}}.
+	{{.Name}}Func func({{.Input}}) ({{.Output}})
+	// {{.Name}}Calls records the arguments of every call to {{.Name}}.
+	{{.Name}}Calls []{{$.Name}}{{.Name}}Call
+{{end}}
+}
+
+var _ {{.Interface}} = (*{{.Name}})(nil)
+{{range .Funcs}}
+// {{$.Name}}{{.Name}}Call records the arguments of a call to {{.Name}}.
+type {{$.Name}}{{.Name}}Call struct {
+{{- range .Args}}
+	{{.Field}} {{.Type}}
+{{- end}}
+}
+
+// {{.Name}} implements the {{$.Interface}} interface.
+func (m *{{$.Name}}) {{.Name}}({{.Input}}) ({{.Output}}) {
+	m.mu.Lock()
+	m.{{.Name}}Calls = append(m.{{.Name}}Calls, {{$.Name}}{{.Name}}Call{
+		{{- range .Args}}{{.Param}},{{end -}}
+	})
+	fn := m.{{.Name}}Func
+	m.mu.Unlock()
+	if fn == nil {
+		return {{.RetDef}}errors.New("{{$.Name}}: {{.Name}} not implemented")
+	}
+	return fn({{range .Args}}{{.Param}},{{end}})
+}
+
+// {{.Name}}CallCount returns the number of calls to {{.Name}}.
+func (m *{{$.Name}}) {{.Name}}CallCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.{{.Name}}Calls)
+}
+
+// Return{{.Name}} makes {{.Name}} return the given canned response.
+func (m *{{$.Name}}) Return{{.Name}}({{.Results}}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.{{.Name}}Func = func({{.Input}}) ({{.Output}}) {
+		return {{.ResultNames}}
+	}
+}
+
+// Fault{{.Name}} makes {{.Name}} fail with the given SOAP fault, or
+// return zero values and no error if fault is nil.
+func (m *{{$.Name}}) Fault{{.Name}}(fault *soap.Fault) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.{{.Name}}Func = func({{.Input}}) ({{.Output}}) {
+		if fault == nil {
+			return {{.RetDef}}nil
+		}
+		return {{.RetDef}}fault
+	}
+}
+{{end}}
+`))
+
+type mockTypeArg struct{ Field, Param, Type string }
+
+type mockTypeFunc struct {
+	Name, Input, Output, RetDef, Results, ResultNames string
+	Args                                              []*mockTypeArg
+}
+
+// writeMockType writes a mock implementation of the port type
+// interface to w.
+func (ge *goEncoder) writeMockType(w io.Writer, d *wsdl.Definitions) error {
+	var funcs []*mockTypeFunc
+	for _, op := range ge.operations() {
+		if ge.bindingOp(op.Name) == nil {
+			continue
+		}
+		inParams, err := ge.inputParams(op)
+		if err != nil {
+			return err
+		}
+		outParams, err := ge.outputParams(op)
+		if err != nil {
+			return err
+		}
+		args := make([]*mockTypeArg, len(inParams))
+		for i, p := range inParams {
+			args[i] = &mockTypeArg{
+				Field: goSymbol(p.code),
+				Param: maskKeywordUsage(p.code),
+				Type:  p.dataType,
+			}
+		}
+		// the last output parameter is always the error
+		var retDef string
+		results := make([]string, len(outParams))
+		names := make([]string, len(outParams))
+		for i, p := range outParams[:len(outParams)-1] {
+			retDef += ge.wsdl2goZero(p.dataType) + ", "
+			names[i] = fmt.Sprintf("r%d", i)
+			results[i] = names[i] + " " + p.dataType
+		}
+		names[len(names)-1] = "err"
+		results[len(results)-1] = "err error"
+		funcs = append(funcs, &mockTypeFunc{
+			Name:        goSymbol(op.Name),
+			Input:       strings.Join(code(inParams), ","),
+			Output:      strings.Join(codeParams(outParams), ","),
+			RetDef:      retDef,
+			Results:     strings.Join(results, ","),
+			ResultNames: strings.Join(names, ","),
+			Args:        args,
+		})
+	}
+	ge.needsStdPkg["errors"] = true
+	ge.needsStdPkg["sync"] = true
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	n := goSymbol(d.PortType.Name)
+	return mockTypeT.Execute(w, &struct {
+		Name      string
+		Interface string
+		Funcs     []*mockTypeFunc
+	}{
+		"Mock" + n,
+		n,
+		funcs,
+	})
+}
+
 // writeGoFuncs writes Go function definitions from WSDL types to w.
 // Functions are written in the same order of the WSDL document.
 func (ge *goEncoder) writeGoFuncs(w io.Writer, d *wsdl.Definitions) error {
@@ -560,12 +881,12 @@ func (ge *goEncoder) writeGoFuncs(w io.Writer, d *wsdl.Definitions) error {
 				d.Binding.Name, d.Binding.Type)
 		}
 	}
-	if len(ge.funcs) == 0 {
+	if len(ge.portType.Operations) == 0 {
 		return nil
 	}
-	for _, fn := range ge.funcnames {
-		op := ge.funcs[fn]
-		ge.writeComments(w, op.Name, op.Doc)
+	for _, op := range ge.operations() {
+		ge.writeComments(w, op.Name, ge.doc(op.WSDL.Docs))
+		ge.enterAt("operation", op.Name, op.WSDL.Pos)
 		inParams, err := ge.inputParams(op)
 		if err != nil {
 			return err
@@ -595,6 +916,7 @@ func (ge *goEncoder) writeGoFuncs(w io.Writer, d *wsdl.Definitions) error {
 				strings.Join(ret, ","),
 			)
 		}
+		ge.leave()
 	}
 	return nil
 }
@@ -611,18 +933,24 @@ var soapFuncT = template.Must(template.New("soapFunc").Parse(
 			{{end}}
 		},{{end}}
 	}
-
+	{{if .OneWay}}
+	return p.cli.{{.RoundTripType}}("{{.Name}}", α, nil)
+}
+{{else}}
 	γ := struct {
 		{{if .OpResponseDataType}}
 			{{if .RPCStyle}}M {{end}}{{.OpResponseDataType}} ` + "`xml:\"{{.OpResponseName}}\"`" + `
 		{{end}}
 	}{}
-	if err := p.cli.RoundTripWithAction("{{.Name}}", α, &γ); err != nil {
+	if err := p.cli.{{.RoundTripType}}("{{.Name}}", α, &γ); err != nil {
 		return {{.RetDef}}
 	}
-	return {{range $index, $element := .OpOutputNames}}{{index $.OpOutputPrefixes $index}}γ.{{if $.RPCStyle}}M.{{end}}{{$element}}, {{end}}nil
+	{{if .OutputWrapper}}if γ.{{.OutputWrapper}} == nil {
+		return {{.MissingWrapperRetDef}}
+	}
+	{{end}}return {{range $index, $element := .OpOutputNames}}{{index $.OpOutputPrefixes $index}}γ.{{if $.RPCStyle}}M.{{end}}{{$element}}, {{end}}nil
 }
-`))
+{{end}}`))
 
 var soapActionFuncT = template.Must(template.New("soapActionFunc").Parse(
 	`func (p *{{.PortType}}) {{.Name}}({{.Input}}) ({{.Output}}) {
@@ -636,7 +964,10 @@ var soapActionFuncT = template.Must(template.New("soapActionFunc").Parse(
 			{{end}}
 		},{{end}}
 	}
-
+	{{if .OneWay}}
+	return p.cli.{{.RoundTripType}}("{{.Action}}", α, nil)
+}
+{{else}}
 	γ := struct {
 		{{if .OpResponseDataType}}
 			{{if .RPCStyle}}M {{end}}{{.OpResponseDataType}} ` + "`xml:\"{{.OpResponseName}}\"`" + `
@@ -645,22 +976,22 @@ var soapActionFuncT = template.Must(template.New("soapActionFunc").Parse(
 	if err := p.cli.{{.RoundTripType}}("{{.Action}}", α, &γ); err != nil {
 		return {{.RetDef}}
 	}
-	return {{range $index, $element := .OpOutputNames}}{{index $.OpOutputPrefixes $index}}γ.{{if $.RPCStyle}}M.{{end}}{{$element}}, {{end}}nil
+	{{if .OutputWrapper}}if γ.{{.OutputWrapper}} == nil {
+		return {{.MissingWrapperRetDef}}
+	}
+	{{end}}return {{range $index, $element := .OpOutputNames}}{{index $.OpOutputPrefixes $index}}γ.{{if $.RPCStyle}}M.{{end}}{{$element}}, {{end}}nil
 }
-`))
+{{end}}`))
 
-func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Operation, in, out []*parameter) bool {
-	if _, exists := ge.soapOps[op.Name]; !exists {
+func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *model.Operation, in, out []*parameter) bool {
+	bindingOp := ge.bindingOp(op.Name)
+	if bindingOp == nil {
 		// TODO: probably faulty wsdl?
 		return false
 	}
 
 	// Do we need to wrap into a operation element?
-	rpcStyle := false
-
-	if d.Binding.BindingType != nil {
-		rpcStyle = d.Binding.BindingType.Style == "rpc"
-	}
+	rpcStyle := ge.rpc
 
 	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
 
@@ -676,6 +1007,26 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 		inputNames[index] = returnVal
 	}
 
+	// unwrapped parameters are the fields of the wrapper elements
+	var inWrapper, outWrapper *wrapperElement
+	if op.Input != nil {
+		inWrapper = ge.wrapper(op, op.Input, true)
+	}
+	if op.Output != nil {
+		outWrapper = ge.wrapper(op, op.Output, false)
+	}
+	if inWrapper != nil {
+		fields := make([]string, len(in))
+		for index, name := range in {
+			fields[index] = name.field + ": " + maskKeywordUsage(name.code) + ",\n"
+		}
+		inputNames = []string{"&" + inWrapper.typ + "{\n" + strings.Join(fields, "") + "}"}
+	}
+	var outputWrapper string
+	if outWrapper != nil {
+		outputWrapper = outWrapper.field
+	}
+
 	// outputDataTypes describe the data types which are returned by the func
 	outputDataTypes := make([]string, len(out))
 
@@ -699,6 +1050,12 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 		operationOutputPrefixes[index] = ""
 		retDefaults[index] = "nil"
 
+		if outWrapper != nil {
+			operationOutputNames[index] = outWrapper.field + "." + name.field
+			retDefaults[index] = ge.wsdl2goZero(name.dataType)
+			continue
+		}
+
 		// If the output is >not< a pointer, we need to return the value of the response
 		if !strings.HasPrefix(name.dataType, "*") {
 			operationOutputPrefixes[index] = "*"
@@ -709,9 +1066,18 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 	}
 	retDefaults[len(retDefaults)-1] = "err"
 
+	// the response of unwrapped operations must have the wrapper
+	var missingWrapperRetDef string
+	if outWrapper != nil {
+		ge.needsStdPkg["errors"] = true
+		missing := append([]string{}, retDefaults[:len(retDefaults)-1]...)
+		missing = append(missing, fmt.Sprintf("errors.New(%q)", "soap: response has no "+outWrapper.name+" element"))
+		missingWrapperRetDef = strings.Join(missing, ",")
+	}
+
 	// Check if we need to prefix the op with a namespace
 	namespacedOpName := op.Name
-	nsSplit := strings.Split(ge.funcs[op.Name].Input.Message, ":")
+	nsSplit := strings.Split(op.WSDL.Input.Message, ":")
 	if len(nsSplit) > 1 {
 		namespacedOpName = nsSplit[0] + ":" + namespacedOpName
 	}
@@ -724,47 +1090,51 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 	// No-input operations can be inlined into an anonymous struct on rpc, and omitted otherwise
 	operationInputDataType := ""
 
-	if len(in) > 0 && op.Input != nil {
-		operationInputDataType = ge.sanitizedOperationsType(ge.messages[trimns(op.Input.Message)].Name)
+	if (len(in) > 0 || inWrapper != nil) && op.Input != nil {
+		operationInputDataType = ge.sanitizedOperationsType(op.Input.WSDL.Name)
 	} else if rpcStyle {
 		operationInputDataType = "struct{}"
 	}
 
-	// No-output operations can be inlined into an anonymous struct on rpc, and omitted otherwise
+	// One-way operations have no output. Unlike their input, they need
+	// no placeholder on rpc, as the response is not decoded.
 	operationOutputDataType := ""
 
 	if len(out) > 0 && op.Output != nil {
-		operationOutputDataType = ge.sanitizedOperationsType(ge.messages[trimns(op.Output.Message)].Name)
-	} else if rpcStyle {
-		operationInputDataType = "struct{}"
+		operationOutputDataType = ge.sanitizedOperationsType(op.Output.WSDL.Name)
 	}
 
 	soapFunctionName := "RoundTripSoap12"
-	soapAction := ""
-	if bindingOp, exists := ge.soapOps[op.Name]; exists {
-		soapAction = bindingOp.Operation.Action
-		if soapAction == "" {
-			soapFunctionName = "RoundTripWithAction"
-			soapAction = bindingOp.Operation11.Action
+	encoded := bindingOp.Encoded()
+	soapAction := bindingOp.Action
+	if !bindingOp.SOAP12 {
+		soapFunctionName = "RoundTripWithAction"
+		if encoded {
+			soapFunctionName = "RoundTripEncoded"
 		}
+	} else if encoded {
+		ge.warn("SOAP encoding is only supported for SOAP 1.1, operation is encoded as literal")
 	}
 	if soapAction != "" {
 		soapActionFuncT.Execute(w, &struct {
-			RoundTripType      string
-			Action             string
-			PortType           string
-			Name               string
-			OpName             string
-			OpInputDataType    string
-			InputNames         []string
-			OpResponseName     string
-			OpResponseDataType string
-			OpOutputNames      []string
-			OpOutputPrefixes   []string
-			Input              string
-			Output             string
-			RetDef             string
-			RPCStyle           bool
+			RoundTripType        string
+			Action               string
+			PortType             string
+			Name                 string
+			OpName               string
+			OpInputDataType      string
+			InputNames           []string
+			OpResponseName       string
+			OpResponseDataType   string
+			OpOutputNames        []string
+			OpOutputPrefixes     []string
+			Input                string
+			Output               string
+			RetDef               string
+			RPCStyle             bool
+			OutputWrapper        string
+			MissingWrapperRetDef string
+			OneWay               bool
 		}{
 			soapFunctionName,
 			soapAction,
@@ -781,24 +1151,32 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 			strings.Join(outputDataTypes, ","),
 			strings.Join(retDefaults, ","),
 			rpcStyle,
+			outputWrapper,
+			missingWrapperRetDef,
+			op.Output == nil,
 		})
 		return true
 	}
 	soapFuncT.Execute(w, &struct {
-		PortType           string
-		Name               string
-		OpName             string
-		OpInputDataType    string
-		InputNames         []string
-		OpResponseName     string
-		OpResponseDataType string
-		OpOutputNames      []string
-		OpOutputPrefixes   []string
-		Input              string
-		Output             string
-		RetDef             string
-		RPCStyle           bool
+		RoundTripType        string
+		PortType             string
+		Name                 string
+		OpName               string
+		OpInputDataType      string
+		InputNames           []string
+		OpResponseName       string
+		OpResponseDataType   string
+		OpOutputNames        []string
+		OpOutputPrefixes     []string
+		Input                string
+		Output               string
+		RetDef               string
+		RPCStyle             bool
+		OutputWrapper        string
+		MissingWrapperRetDef string
+		OneWay               bool
 	}{
+		soapFunctionName,
 		strings.ToLower(d.PortType.Name[:1]) + d.PortType.Name[1:],
 		goSymbol(op.Name),
 		namespacedOpName,
@@ -812,6 +1190,9 @@ func (ge *goEncoder) writeSOAPFunc(w io.Writer, d *wsdl.Definitions, op *wsdl.Op
 		strings.Join(outputDataTypes, ","),
 		strings.Join(retDefaults, ","),
 		rpcStyle,
+		outputWrapper,
+		missingWrapperRetDef,
+		op.Output == nil,
 	})
 	return true
 }
@@ -825,33 +1206,142 @@ func renameParam(p, name string) string {
 }
 
 // returns list of function input parameters.
-func (ge *goEncoder) inputParams(op *wsdl.Operation) ([]*parameter, error) {
-	if op.Input == nil {
+func (ge *goEncoder) inputParams(op *model.Operation) ([]*parameter, error) {
+	if op.WSDL.Input == nil {
 		return []*parameter{}, nil
 	}
-	im := trimns(op.Input.Message)
-	req, ok := ge.messages[im]
-	if !ok {
-		return nil, fmt.Errorf("operation %q wants input message %q but it's not defined", op.Name, im)
+	if op.Input == nil {
+		return nil, fmt.Errorf("operation %q wants input message %q but it's not defined", op.Name, trimns(op.WSDL.Input.Message))
 	}
 
+	if w := ge.wrapper(op, op.Input, true); w != nil {
+		return w.params, nil
+	}
 	// TODO: I had to disable this for my use case - do other use cases still work with false?
-	return ge.genParams(req, false), nil
+	return ge.genParams(op.Input.WSDL, false), nil
 }
 
 // returns list of function output parameters plus error.
-func (ge *goEncoder) outputParams(op *wsdl.Operation) ([]*parameter, error) {
+func (ge *goEncoder) outputParams(op *model.Operation) ([]*parameter, error) {
 	out := []*parameter{{code: "err", dataType: "error"}}
 
-	if op.Output == nil {
+	if op.WSDL.Output == nil {
 		return out, nil
 	}
-	om := trimns(op.Output.Message)
-	resp, ok := ge.messages[om]
-	if !ok {
-		return nil, fmt.Errorf("operation %q wants output message %q but it's not defined", op.Name, om)
+	if op.Output == nil {
+		return nil, fmt.Errorf("operation %q wants output message %q but it's not defined", op.Name, trimns(op.WSDL.Output.Message))
+	}
+	if w := ge.wrapper(op, op.Output, false); w != nil {
+		return append(w.params, out[0]), nil
+	}
+	return append(ge.genParams(op.Output.WSDL, false), out[0]), nil
+}
+
+// wrapperElement is the wrapper element of the input or output message
+// of a document/literal wrapped operation.
+type wrapperElement struct {
+	name   string       // name of the element
+	field  string       // field of the message part
+	typ    string       // Go type of the element, without pointer
+	params []*parameter // child elements
+}
+
+// wrapper returns the wrapper element of the message m of op, if
+// unwrapping is enabled and the operation follows the wrapped
+// convention: the message has a single element part, the input element
+// is named after the operation, and its type is a sequence of named
+// child elements.
+func (ge *goEncoder) wrapper(op *model.Operation, msg *model.Message, input bool) *wrapperElement {
+	m := msg.WSDL
+	if !ge.unwrap || len(m.Parts) != 1 || m.Parts[0].Element == "" {
+		return nil
+	}
+	if bo := ge.bindingOp(op.Name); bo != nil && bo.Encoded() {
+		return nil
+	}
+	if ge.rpc {
+		return nil
+	}
+	name := trimns(m.Parts[0].Element)
+	if input && name != op.Name {
+		return nil
+	}
+	// the anonymous types of elements are named after the element
+	key, ct, _ := ge.lookupComplexType(m.Parts[0].Element)
+	if _, el, ok := ge.lookupElement(m.Parts[0].Element); ok && el.Type != "" {
+		leave := ge.enterScope(el.Namespaces, el.TargetNamespace)
+		key, ct, _ = ge.lookupComplexType(el.Type)
+		leave()
+	}
+	if ct == nil || ct.Abstract || ct.Mixed || ct.Sequence == nil || ct.Choice != nil || ct.Group != nil ||
+		ct.ComplexContent != nil || ct.SimpleContent != nil || len(ct.AllElements) > 0 ||
+		len(ct.Attributes) > 0 || len(ct.AttributeGroups) > 0 {
+		return nil
 	}
-	return append(ge.genParams(resp, false), out[0]), nil
+	seq := ct.Sequence
+	if len(seq.Any) > 0 || len(seq.Choices) > 0 || len(seq.Groups) > 0 || len(seq.ComplexTypes) > 0 {
+		return nil
+	}
+	part := ge.genParams(m, false)[0]
+	defer ge.enterScope(ct.Namespaces, key.Space)()
+	for _, c := range seq.Elements {
+		if c.Name == "" || c.Ref != "" || c.ComplexType != nil || len(ge.substitutes[ge.scope.Name(c.Name)]) > 0 {
+			return nil
+		}
+	}
+	w := &wrapperElement{
+		name:  trimns(m.Parts[0].Element),
+		field: strings.ToUpper(part.code[:1]) + part.code[1:],
+		typ:   strings.TrimPrefix(part.dataType, "*"),
+	}
+	used := make(map[string]bool)
+	for _, c := range seq.Elements {
+		ge.enterAt("element", c.Name, c.Pos)
+		typ, _ := ge.elementGoType(c)
+		ge.leave()
+		if c.Max != "" && c.Max != "1" {
+			typ = "[]" + typ
+		}
+		w.params = append(w.params, &parameter{
+			code:     paramName(c.Name, used),
+			dataType: typ,
+			xmlToken: c.Name,
+			field:    goSymbol(c.Name),
+		})
+	}
+	return w
+}
+
+// reservedParams are the names that generated methods use besides
+// their parameters.
+var reservedParams = map[string]bool{
+	"p":    true,
+	"α":    true,
+	"γ":    true,
+	"soap": true,
+	"ctx":  true,
+	"err":  true,
+}
+
+// paramName returns the name of the parameter of the child element
+// name of a wrapper element: a Go symbol that starts with a lower case
+// letter, is not a keyword and doesn't collide with the reserved names
+// or the names in used, to which it is added.
+func paramName(name string, used map[string]bool) string {
+	s := goSymbol(name)
+	if s == "" {
+		s = "v"
+	}
+	r, n := utf8.DecodeRuneInString(s)
+	s = maskKeywordUsage(string(unicode.ToLower(r)) + s[n:])
+	if reservedParams[s] {
+		s = "_" + s
+	}
+	for base, i := s, 2; used[s]; i++ {
+		s = base + strconv.Itoa(i)
+	}
+	used[s] = true
+	return s
 }
 
 var isGoKeyword = map[string]bool{
@@ -886,6 +1376,7 @@ type parameter struct {
 	code     string
 	dataType string
 	xmlToken string
+	field    string // field of the wrapper element, for unwrapped parameters
 }
 
 func code(list []*parameter) []string {
@@ -927,8 +1418,10 @@ func (ge *goEncoder) genParams(m *wsdl.Message, needsTag bool) []*parameter {
 		case param.Element != "":
 			elName = trimns(param.Element)
 			code = goSymbol(param.Element)
-			if el, ok := ge.elements[elName]; ok {
-				t = ge.wsdl2goType(trimns(el.Type))
+			if _, el, ok := ge.lookupElement(param.Element); ok && el.Type != "" {
+				leave := ge.enterScope(el.Namespaces, el.TargetNamespace)
+				t = ge.wsdl2goType(el.Type)
+				leave()
 			} else {
 				t = ge.wsdl2goType(param.Element)
 			}
@@ -945,11 +1438,7 @@ func (ge *goEncoder) genParams(m *wsdl.Message, needsTag bool) []*parameter {
 
 // Fixes conflicts between function and type names.
 func (ge *goEncoder) fixFuncNameConflicts(name string) string {
-	if _, exists := ge.stypes[name]; exists {
-		name += "Func"
-		return ge.fixFuncNameConflicts(name)
-	}
-	if _, exists := ge.ctypes[name]; exists {
+	if ge.isGoType(name) {
 		name += "Func"
 		return ge.fixFuncNameConflicts(name)
 	}
@@ -983,55 +1472,84 @@ func (ge *goEncoder) sanitizedOperationsType(opName string) string {
 	return "Operation" + goSymbol(opName)
 }
 
-// Converts types from wsdl type to Go type.
+// Converts types from wsdl type to Go type. Names are resolved in the
+// current scope: types of XML Schema are built-in types, and others are
+// the types of the schema, or else built-in types of the same name.
 func (ge *goEncoder) wsdl2goType(t string) string {
-	// TODO: support other types.
-	v := trimns(t)
-	if _, exists := ge.stypes[v]; exists {
-		return goSymbol(v)
+	name := ge.scope.Name(t)
+	if name.Space == model.XSDNamespace {
+		if v, ok := ge.builtinType(name.Local); ok {
+			return v
+		}
+	}
+	if ge.model.SimpleType(name) != nil {
+		return ge.goName(name)
+	}
+	if ge.model.ComplexType(name) != nil {
+		return "*" + ge.goName(name)
 	}
+	if key, _, ok := ge.lookupSimpleType(t); ok {
+		return ge.goName(key)
+	}
+	if v, ok := ge.builtinType(name.Local); ok {
+		return v
+	}
+	if key, _, ok := ge.lookupComplexType(t); ok {
+		return "*" + ge.goName(key)
+	}
+	if !ge.isGoType(goSymbol(name.Local)) {
+		ge.warn("unknown type %q, generated as *%s which is not declared", t, goSymbol(name.Local))
+	}
+	return "*" + goSymbol(name.Local)
+}
+
+// builtinType returns the Go type of the built-in type named v.
+func (ge *goEncoder) builtinType(v string) (string, bool) {
+	// TODO: support other types.
 	switch strings.ToLower(v) {
 	case "byte", "unsignedbyte":
-		return "byte"
+		return "byte", true
 	case "int":
-		return "int"
+		return "int", true
 	case "integer":
-		return "int64" // todo: replace this with math/big since integer is infinite set
+		return "int64", true // todo: replace this with math/big since integer is infinite set
 	case "long":
-		return "int64"
+		return "int64", true
 	case "float", "double", "decimal":
-		return "float64"
+		return "float64", true
 	case "boolean":
-		return "bool"
+		return "bool", true
 	case "hexbinary", "base64binary":
-		return "[]byte"
+		return "[]byte", true
 	case "string", "anyuri", "token", "nmtoken", "qname", "language", "id":
-		return "string"
+		return "string", true
 	case "date":
 		ge.needsDateType = true
-		return "Date"
+		return "Date", true
 	case "time":
 		ge.needsTimeType = true
-		return "Time"
+		return "Time", true
 	case "nonnegativeinteger":
-		return "uint"
+		return "uint", true
 	case "positiveinteger":
-		return "uint64"
+		return "uint64", true
 	case "normalizedstring":
-		return "string"
+		return "string", true
 	case "unsignedint":
-		return "uint"
+		return "uint", true
 	case "datetime":
 		ge.needsDateTimeType = true
-		return "DateTime"
+		return "DateTime", true
 	case "duration":
 		ge.needsDurationType = true
-		return "Duration"
+		return "Duration", true
+	case "anyelement":
+		ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+		return "*soap.AnyElement", true
 	case "anysequence", "anytype", "anysimpletype":
-		return "interface{}"
-	default:
-		return "*" + goSymbol(v)
+		return "interface{}", true
 	}
+	return "", false
 }
 
 // Returns the default Go type for the given wsdl type.
@@ -1056,106 +1574,149 @@ func (ge *goEncoder) wsdl2goDefault(t string) string {
 	}
 }
 
-func (ge *goEncoder) renameType(old, name string) {
-	// TODO: rename Elements that point to this type also?
-	ct, exists := ge.ctypes[old]
-	if !exists {
-		old = trimns(old)
-		ct, exists = ge.ctypes[old]
-		if !exists {
-			return
-		}
-		name = trimns(name)
+// Returns the zero value of the given Go type.
+func (ge *goEncoder) wsdl2goZero(t string) string {
+	switch {
+	case strings.HasPrefix(t, "*"), strings.HasPrefix(t, "[]"), t == "interface{}":
+		return "nil"
+	}
+	switch t {
+	case "bool":
+		return "false"
+	case "string":
+		return `""`
+	case "byte", "uint", "uint64", "int", "int64", "float64":
+		return "0"
+	}
+	switch ge.basicType(t) {
+	case "interface{}":
+		return "nil"
+	case "bool":
+		return "false"
+	case "string":
+		return t + `("")`
+	case "byte", "uint", "uint64", "int", "int64", "float64":
+		return t + "(0)"
 	}
-	ct.Name = name
-	delete(ge.ctypes, old)
-	ge.ctypes[name] = ct
+	return t + "{}"
 }
 
-// writeGoTypes writes Go types from WSDL types to w.
-//
-// Types are written in this order, alphabetically: date types that we
-// generate, simple types, then complex types.
-func (ge *goEncoder) writeGoTypes(w io.Writer, d *wsdl.Definitions) error {
-	var b bytes.Buffer
+// Returns the underlying Go type of a generated simple type, or t
+// itself.
+func (ge *goEncoder) basicType(t string) string {
+	switch t {
+	case "Date", "Time", "DateTime", "Duration":
+		return "string"
+	}
 	for _, name := range ge.sortedSimpleTypes() {
-		st := ge.stypes[name]
-		stname := goSymbol(st.Name)
+		if ge.goName(name) != t {
+			continue
+		}
+		st := ge.model.SimpleType(name)
 		if st.Restriction != nil {
-			ge.writeComments(&b, stname, "")
-			fmt.Fprintf(&b, "type %s %s\n\n", stname, ge.wsdl2goType(st.Restriction.Base))
-			ge.genValidator(&b, stname, st.Restriction)
-		} else if st.Union != nil {
-			types := strings.Split(st.Union.MemberTypes, " ")
-			ntypes := make([]string, len(types))
-			for i, t := range types {
-				t = strings.TrimSpace(t)
-				if t == "" {
-					continue
-				}
-				ntypes[i] = ge.wsdl2goType(t)
-			}
-			doc := stname + " is a union of: " + strings.Join(ntypes, ", ")
-			ge.writeComments(&b, stname, doc)
-			fmt.Fprintf(&b, "type %s interface{}\n\n", stname)
+			leave := ge.enterScope(st.Namespaces, name.Space)
+			base := ge.wsdl2goType(st.Restriction.Base)
+			leave()
+			return ge.basicType(base)
 		}
 	}
-	var err error
-	for _, name := range ge.sortedComplexTypes() {
-		ct := ge.ctypes[name]
-		err = ge.genGoStruct(&b, d, ct)
+	return t
+}
+
+// enumerates reports whether v is a value of the Go type t, a simple
+// type, when its restriction has enumerations.
+func (ge *goEncoder) enumerates(t, v string) bool {
+	for _, name := range ge.sortedSimpleTypes() {
+		st := ge.model.SimpleType(name)
+		if ge.goName(name) != t || st.Restriction == nil || len(st.Restriction.Enum) == 0 {
+			continue
+		}
+		for _, e := range st.Restriction.Enum {
+			if e.Value == v {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// writeGoTypes writes Go types from WSDL types to w.
+//
+// Types are written in this order, alphabetically: date types that we
+// generate, simple types, then complex types.
+func (ge *goEncoder) writeGoTypes(w io.Writer, d *wsdl.Definitions) error {
+	var b bytes.Buffer
+	for _, name := range ge.sortedSimpleTypes() {
+		st := ge.model.SimpleType(name)
+		stname := ge.goName(name)
+		leave := ge.enterScope(st.Namespaces, name.Space)
+		if st.Restriction != nil {
+			ge.writeComments(&b, stname, ge.doc(st.Docs))
+			ge.writeEnumDocs(&b, st.Restriction.Enum)
+			fmt.Fprintf(&b, "type %s %s\n\n", stname, ge.wsdl2goType(st.Restriction.Base))
+			ge.genValidator(&b, stname, st.Restriction)
+		} else if st.List != nil {
+			ge.enter("simpleType", st.Name)
+			ge.genList(&b, stname, ge.doc(st.Docs), st.List)
+			ge.leave()
+		} else if st.Union != nil {
+			ge.enter("simpleType", st.Name)
+			ge.genUnion(&b, stname, ge.doc(st.Docs), st.Union)
+			ge.leave()
+		}
+		leave()
+	}
+	var err error
+	for _, name := range ge.sortedComplexTypes() {
+		ct := ge.model.ComplexType(name)
+		leave := ge.enterScope(ct.Namespaces, name.Space)
+		err = ge.genGoStruct(&b, d, name, ct)
 		if err != nil {
 			return err
 		}
-		ge.genGoXMLTypeFunction(&b, ct)
+		ge.genGoXMLTypeFunction(&b, name, ct)
+		leave()
 	}
 
 	// Operation wrappers - mainly used for rpc, not exclusively
-	for _, name := range ge.sortedOperations() {
-		ct := ge.soapOps[name]
-
-		err = ge.genGoOpStruct(&b, d, ct)
-		if err != nil {
-			return err
+	if ge.binding != nil {
+		for _, bo := range ge.sortedOperations() {
+			err = ge.genGoOpStruct(&b, d, bo)
+			if err != nil {
+				return err
+			}
 		}
 	}
+	if err = ge.genNillableTypes(&b); err != nil {
+		return err
+	}
+	if err = ge.genSubstGroupTypes(&b); err != nil {
+		return err
+	}
 
 	ge.genDateTypes(w) // must be called last
 	_, err = io.Copy(w, &b)
 	return err
 }
 
-func (ge *goEncoder) sortedSimpleTypes() []string {
-	keys := make([]string, len(ge.stypes))
-	i := 0
-	for k := range ge.stypes {
-		keys[i] = k
-		i++
+// sortedOperations returns the operations of the binding sorted by
+// name. Of operations with the same name, the last one is kept.
+func (ge *goEncoder) sortedOperations() []*model.BindingOperation {
+	byName := make(map[string]*model.BindingOperation)
+	var names []string
+	for _, bo := range ge.binding.Operations {
+		if _, exists := byName[bo.Operation.Name]; !exists {
+			names = append(names, bo.Operation.Name)
+		}
+		byName[bo.Operation.Name] = bo
 	}
-	sort.Strings(keys)
-	return keys
-}
-
-func (ge *goEncoder) sortedComplexTypes() []string {
-	keys := make([]string, len(ge.ctypes))
-	i := 0
-	for k := range ge.ctypes {
-		keys[i] = k
-		i++
+	sort.Strings(names)
+	ops := make([]*model.BindingOperation, len(names))
+	for i, name := range names {
+		ops[i] = byName[name]
 	}
-	sort.Strings(keys)
-	return keys
-}
-
-func (ge *goEncoder) sortedOperations() []string {
-	keys := make([]string, len(ge.soapOps))
-	i := 0
-	for k := range ge.soapOps {
-		keys[i] = k
-		i++
-	}
-	sort.Strings(keys)
-	return keys
+	return ops
 }
 
 func (ge *goEncoder) genDateTypes(w io.Writer) {
@@ -1194,10 +1755,121 @@ func (ge *goEncoder) genDateTypes(w io.Writer) {
 	}
 }
 
+var listT = template.Must(template.New("list").Parse(`
+// MarshalText implements the encoding.TextMarshaler interface.
+func (v {{.Name}}) MarshalText() ([]byte, error) {
+	return soap.MarshalList(v)
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (v *{{.Name}}) UnmarshalText(text []byte) error {
+	return soap.UnmarshalList(text, v)
+}
+
+`))
+
+// genList generates the xs:list type name as a slice of its item type.
+func (ge *goEncoder) genList(w io.Writer, name, doc string, l *wsdl.List) {
+	var item string
+	if l.ItemType != "" {
+		item = ge.wsdl2goType(l.ItemType)
+	} else {
+		item = ge.anonymousType(l.SimpleType, "item type of list")
+	}
+	ge.writeComments(w, name, joinDoc(doc, name+" is a whitespace separated list of "+item+"."))
+	fmt.Fprintf(w, "type %s []%s\n", name, item)
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	listT.Execute(w, &struct{ Name string }{name})
+}
+
+var unionT = template.Must(template.New("union").Parse(`type {{.Name}} struct {
+{{- range .Members}}
+	{{.Field}} *{{.Type}}
+{{- end}}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (v {{.Name}}) MarshalText() ([]byte, error) {
+	return soap.MarshalUnion({{range $i, $m := .Members}}{{if $i}}, {{end}}v.{{$m.Field}}{{end}})
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface. The
+// member types are tried in order, string types without enumerations last.
+func (v *{{.Name}}) UnmarshalText(text []byte) error {
+	*v = {{.Name}}{}
+	return soap.UnmarshalUnion(text{{range .Members}}, &v.{{.Field}}{{end}})
+}
+
+`))
+
+type unionMember struct {
+	Field string
+	Type  string
+}
+
+// genUnion generates the xs:union type name as a struct with a field
+// per member type, of which one is set.
+func (ge *goEncoder) genUnion(w io.Writer, name, doc string, u *wsdl.Union) {
+	var types []string
+	for _, t := range strings.Fields(u.MemberTypes) {
+		types = append(types, ge.wsdl2goType(t))
+	}
+	for _, st := range u.SimpleTypes {
+		types = append(types, ge.anonymousType(st, "member type of union"))
+	}
+	var members []*unionMember
+	seen := make(map[string]bool)
+	for _, t := range types {
+		if t == "interface{}" {
+			t = "string"
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		members = append(members, &unionMember{typeSymbol(t), strings.TrimPrefix(t, "*")})
+	}
+	if len(members) == 0 {
+		ge.warn("union without member types approximated as string")
+		ge.writeComments(w, name, "")
+		fmt.Fprintf(w, "type %s string\n\n", name)
+		return
+	}
+	names := make([]string, len(members))
+	for i, m := range members {
+		names[i] = m.Type
+	}
+	ge.writeComments(w, name, joinDoc(doc, name+" is a union of "+strings.Join(names, ", ")+". One of its fields is set."))
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	unionT.Execute(w, &struct {
+		Name    string
+		Members []*unionMember
+	}{name, members})
+}
+
+// typeSymbol returns a symbol for the Go type t, e.g. Bytes for []byte.
+func typeSymbol(t string) string {
+	t = strings.TrimPrefix(t, "*")
+	if strings.HasPrefix(t, "[]") {
+		t = t[2:] + "s"
+	}
+	return goSymbol(t)
+}
+
+// anonymousType returns the Go type of the anonymous simple type st,
+// the base type of its restriction.
+func (ge *goEncoder) anonymousType(st *wsdl.SimpleType, what string) string {
+	if st == nil || st.Restriction == nil {
+		ge.warn("anonymous %s approximated as string", what)
+		return "string"
+	}
+	return ge.wsdl2goType(st.Restriction.Base)
+}
+
 var validatorT = template.Must(template.New("validator").Parse(`
 // Validate validates {{.TypeName}}.
 func (v {{.TypeName}}) Validate() bool {
-	for _, vv := range []{{.Type}} {
+	for _, vv := range []{{.TypeName}} {
 		{{range .Args}}{{.}},{{"\n"}}{{end}}
 	}{
 		if reflect.DeepEqual(v, vv) {
@@ -1215,7 +1887,7 @@ func (ge *goEncoder) genValidator(w io.Writer, typeName string, r *wsdl.Restrict
 	args := make([]string, len(r.Enum))
 	t := ge.wsdl2goType(r.Base)
 	for i, v := range r.Enum {
-		if t == "string" {
+		if ge.basicType(t) == "string" {
 			args[i] = strconv.Quote(v.Value)
 		} else {
 			args[i] = v.Value
@@ -1224,16 +1896,14 @@ func (ge *goEncoder) genValidator(w io.Writer, typeName string, r *wsdl.Restrict
 	ge.needsStdPkg["reflect"] = true
 	validatorT.Execute(w, &struct {
 		TypeName string
-		Type     string
 		Args     []string
 	}{
 		typeName,
-		t,
 		args,
 	})
 }
 
-func (ge *goEncoder) genGoXMLTypeFunction(w io.Writer, ct *wsdl.ComplexType) {
+func (ge *goEncoder) genGoXMLTypeFunction(w io.Writer, key model.Name, ct *wsdl.ComplexType) {
 	if ct.ComplexContent == nil || ct.ComplexContent.Extension == nil || ct.TargetNamespace == "" {
 		return
 	}
@@ -1241,7 +1911,7 @@ func (ge *goEncoder) genGoXMLTypeFunction(w io.Writer, ct *wsdl.ComplexType) {
 	ext := ct.ComplexContent.Extension
 	if ext.Base != "" && !ct.Abstract {
 		ge.writeComments(w, "SetXMLType", "")
-		fmt.Fprintf(w, "func (t *%s) SetXMLType() {\n", goSymbol(ct.Name))
+		fmt.Fprintf(w, "func (t *%s) SetXMLType() {\n", ge.goName(key))
 		fmt.Fprintf(w, "if t.OverrideTypeAttrXSI != nil {\n")
 		fmt.Fprintf(w, "    t.TypeAttrXSI = *t.OverrideTypeAttrXSI\n")
 		fmt.Fprintf(w, "} else {\n")
@@ -1287,7 +1957,7 @@ func trimns(s string) string {
 	return s
 }
 
-func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
+func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, key model.Name, ct *wsdl.ComplexType) error {
 	c := 0
 	if len(ct.AllElements) == 0 {
 		c++
@@ -1298,38 +1968,51 @@ func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, ct *wsdl.Comp
 	if ct.Sequence == nil && ct.Choice == nil {
 		c++
 	} else if ct.Sequence != nil &&
-		(len(ct.Sequence.ComplexTypes) == 0 && len(ct.Sequence.Elements) == 0 && len(ct.Sequence.Choices) == 0) {
+		(len(ct.Sequence.ComplexTypes) == 0 && len(ct.Sequence.Elements) == 0 && len(ct.Sequence.Choices) == 0 && len(ct.Sequence.Any) == 0) {
 		c++
-	} else if ct.Choice != nil && (len(ct.Choice.ComplexTypes) == 0 && len(ct.Choice.Elements) == 0) {
+	} else if ct.Choice != nil && (len(ct.Choice.ComplexTypes) == 0 && len(ct.Choice.Elements) == 0 && len(ct.Choice.Any) == 0) {
 		c++
 	}
 
-	name := goSymbol(ct.Name)
-	ge.writeComments(w, name, ct.Doc)
+	ge.enterAt("complexType", ct.Name, ct.Pos)
+	defer ge.leave()
+	ge.structAny = false
+	ge.structWildcard = false
+	ge.structChoices = nil
+	ge.structValues = nil
+	ge.structFixed = nil
+
+	name := ge.goName(key)
+	ge.writeComments(w, name, ge.doc(ct.Docs))
 	if ct.Abstract {
-		fmt.Fprintf(w, "type %s interface{}\n\n", name)
-		return nil
-	}
-	if ct.Sequence != nil && ct.Sequence.Any != nil {
-		if len(ct.Sequence.Elements) == 0 {
-			fmt.Fprintf(w, "type %s []interface{}\n\n", name)
-			return nil
-		}
+		ge.needsStdPkg["encoding/xml"] = true
+		ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+		return abstractTypeT.Execute(w, &struct {
+			Name     string
+			Subtypes string
+		}{
+			name,
+			strings.Join(ge.subtypes(key), ", "),
+		})
 	}
-	if ct.Choice != nil && ct.Choice.Any != nil {
-		if len(ct.Choice.Elements) == 0 {
-			fmt.Fprintf(w, "type %s []interface{}\n\n", name)
-			return nil
-		}
+	if ct.Mixed || (ct.ComplexContent != nil && ct.ComplexContent.Mixed) {
+		ge.warn("mixed content is generated as soap.AnyElement, child elements are not typed")
+		ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+		fmt.Fprintf(w, "type %s struct {\nsoap.AnyElement\n}\n\n", name)
+		return nil
 	}
 	if ct.ComplexContent != nil {
 		restr := ct.ComplexContent.Restriction
 		if restr != nil && len(restr.Attributes) == 1 && restr.Attributes[0].ArrayType != "" {
 			fmt.Fprintf(w, "type %s struct {\n", name)
-			typ := strings.SplitN(trimns(restr.Attributes[0].ArrayType), "[", 2)[0]
+			typ := strings.SplitN(restr.Attributes[0].ArrayType, "[", 2)[0]
 			fmt.Fprintf(w, "Items []%s `xml:\"item,omitempty\" json:\"item,omitempty\" yaml:\"item,omitempty\"`\n", ge.wsdl2goType(typ))
 			fmt.Fprintf(w, "}\n\n")
-			return nil
+			ge.needsStdPkg["encoding/xml"] = true
+			space, local := ge.schemaTypeName(d, typ)
+			return arrayT.Execute(w, &struct {
+				Name, Space, Local string
+			}{name, space, local})
 		}
 	}
 
@@ -1355,22 +2038,255 @@ func (ge *goEncoder) genGoStruct(w io.Writer, d *wsdl.Definitions, ct *wsdl.Comp
 		return err
 	}
 	fmt.Fprintf(w, "}\n\n")
+	return ge.genStructMethods(w, name)
+}
+
+var arrayT = template.Must(template.New("array").Parse(`// MarshalXML implements the xml.Marshaler interface. It encodes the
+// items as a SOAP encoded array.
+func (t {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeArray(e, start, xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}}, t.Items)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. Items are
+// decoded regardless of their element name.
+func (t *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return soap.DecodeArray(d, start, &t.Items)
+}
+
+`))
+
+// schemaTypeName returns the namespace and local name of the schema
+// type t, for xsi:type and arrayType attributes. Types that are not
+// declared in the schema are built-in types.
+func (ge *goEncoder) schemaTypeName(d *wsdl.Definitions, t string) (string, string) {
+	name := ge.scope.Name(t)
+	if name.Space == model.XSDNamespace {
+		return name.Space, name.Local
+	}
+	if key, _, ok := ge.lookupComplexType(t); ok {
+		name.Space = key.Space
+	} else if key, _, ok := ge.lookupSimpleType(t); ok {
+		name.Space = key.Space
+	} else if ge.isGoType(goSymbol(name.Local)) {
+		name.Space = ""
+	} else {
+		return model.XSDNamespace, name.Local
+	}
+	if name.Space == "" {
+		name.Space = d.TargetNamespace
+	}
+	return name.Space, name.Local
+}
+
+var abstractTypeT = template.Must(template.New("abstractType").Parse(`type {{.Name}} struct {
+	// Value is the concrete value{{if .Subtypes}}, one of {{.Subtypes}}{{end}}.
+	Value interface{}
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface. The concrete
+// type is determined by xsi:type, or the substitution group element.
+func (t *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v, err := TypeRegistry.Decode(d, start)
+	if err != nil {
+		return err
+	}
+	t.Value = v
+	return nil
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (t {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeElement(e, start, t.Value)
+}
+
+`))
+
+// subtypes returns the Go types of the concrete types derived from the
+// complex type name.
+func (ge *goEncoder) subtypes(name model.Name) []string {
+	var types []string
+	for _, n := range ge.sortedComplexTypes() {
+		ct := ge.model.ComplexType(n)
+		if ct.ComplexContent == nil || ct.ComplexContent.Extension == nil {
+			continue
+		}
+		leave := ge.enterScope(ct.Namespaces, n.Space)
+		base, _, ok := ge.lookupComplexType(ct.ComplexContent.Extension.Base)
+		leave()
+		if !ok || base != name {
+			continue
+		}
+		if !ct.Abstract {
+			types = append(types, "*"+ge.goName(n))
+		}
+		types = append(types, ge.subtypes(n)...)
+	}
+	return types
+}
+
+// abstract reports whether t is an abstract complex type.
+func (ge *goEncoder) abstract(t string) bool {
+	_, ct, ok := ge.lookupComplexType(t)
+	return ok && ct.Abstract
+}
+
+var substGroupT = template.Must(template.New("substGroup").Parse(`
+// {{.Name}} is the elements of the substitution group of {{.Head}}.
+// The name of each member element is kept, and encoded.
+type {{.Name}} []*soap.Substitute
+
+// UnmarshalXML implements the xml.Unmarshaler interface. Members of the
+// substitution group are decoded by the type registry, and other
+// elements are skipped.
+func (g *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	v, err := TypeRegistry.DecodeSubstitute(d, start, xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Head}}})
+	if v != nil {
+		*g = append(*g, v)
+	}
+	return err
+}
+`))
+
+// substGroupType returns the Go type of the fields of the substitution
+// group of the head element.
+func (ge *goEncoder) substGroupType(head model.Name) string {
+	for name, v := range ge.substGroups {
+		if v == head {
+			return name
+		}
+	}
+	name := goSymbol(head.Local) + "Group"
+	for ge.isGoType(name) || ge.substGroups[name] != (model.Name{}) {
+		name += "Group"
+	}
+	ge.substGroups[name] = head
+	return name
+}
+
+// genSubstGroupTypes generates the Go types of the fields of
+// substitution groups.
+func (ge *goEncoder) genSubstGroupTypes(w io.Writer) error {
+	names := make([]string, 0, len(ge.substGroups))
+	for name := range ge.substGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		head := ge.substGroups[name]
+		space := head.Space
+		if space == "" {
+			space = ge.targetNamespace
+		}
+		err := substGroupT.Execute(w, &struct {
+			Name, Head, Space string
+		}{name, head.Local, space})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (ge *goEncoder) genGoOpStruct(w io.Writer, d *wsdl.Definitions, bo *wsdl.BindingOperation) error {
-	name := goSymbol(bo.Name)
+var typeRegistryT = template.Must(template.New("typeRegistry").Parse(`
+// TypeRegistry maps XML type and element names to the Go types of
+// this package, for decoding abstract types and substitution groups.
+var TypeRegistry = soap.NewTypeRegistry()
 
-	inputMessage := ge.messages[trimns(ge.funcs[bo.Name].Input.Message)]
+func init() {
+{{- range .Types }}
+	TypeRegistry.RegisterType(xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}}, func() interface{} { return &{{.Type}}{} })
+{{- end }}
+{{- range .Elements }}
+	TypeRegistry.RegisterSubstitute(xml.Name{Space: {{printf "%q" .HeadSpace}}, Local: {{printf "%q" .Head}}}, xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}}, func() interface{} { return &{{.Type}}{} })
+{{- end }}
+}
+`))
+
+type typeRegistryEntry struct{ Space, Local, Type, HeadSpace, Head string }
+
+// writeTypeRegistry writes the registry of the concrete complex types,
+// and of the members of substitution groups, to w.
+func (ge *goEncoder) writeTypeRegistry(w io.Writer, d *wsdl.Definitions) error {
+	ns := d.Schema.TargetNamespace
+	if ns == "" {
+		ns = d.TargetNamespace
+	}
+	var types, elements []*typeRegistryEntry
+	seen := make(map[model.Name]bool)
+	for _, ct := range d.Schema.ComplexTypes {
+		key := model.Name{Space: ct.TargetNamespace, Local: ct.Name}
+		if ct.Abstract || seen[key] {
+			continue
+		}
+		seen[key] = true
+		space := ct.TargetNamespace
+		if space == "" {
+			space = ns
+		}
+		types = append(types, &typeRegistryEntry{Space: space, Local: ct.Name, Type: ge.goName(key)})
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].Local < types[j].Local })
+	heads := make([]model.Name, 0, len(ge.substitutes))
+	for head := range ge.substitutes {
+		heads = append(heads, head)
+	}
+	sortLocalNames(heads)
+	for _, head := range heads {
+		for _, el := range ge.substitutes[head] {
+			key := model.Name{Space: el.TargetNamespace, Local: el.Name}
+			if el.Type != "" {
+				leave := ge.enterScope(el.Namespaces, el.TargetNamespace)
+				key, _, _ = ge.lookupComplexType(el.Type)
+				leave()
+			}
+			if ct := ge.model.ComplexType(key); ct == nil || ct.Abstract {
+				ge.enterAt("element", el.Name, el.Pos)
+				ge.warn("member of substitution group %q has no concrete complex type, not registered", head.Local)
+				ge.leave()
+				continue
+			}
+			space := el.TargetNamespace
+			if space == "" {
+				space = ns
+			}
+			headSpace := head.Space
+			if headSpace == "" {
+				headSpace = ns
+			}
+			elements = append(elements, &typeRegistryEntry{space, el.Name, ge.goName(key), headSpace, head.Local})
+		}
+	}
+	ge.needsStdPkg["encoding/xml"] = true
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	return typeRegistryT.Execute(w, &struct {
+		Types    []*typeRegistryEntry
+		Elements []*typeRegistryEntry
+	}{types, elements})
+}
+
+func containsElement(l []*wsdl.Element, name string) bool {
+	for _, el := range l {
+		if el.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (ge *goEncoder) genGoOpStruct(w io.Writer, d *wsdl.Definitions, bo *model.BindingOperation) error {
+	op := bo.Operation
+	name := goSymbol(op.Name)
 
 	// No-Op on operations which don't take arguments
 	// (These can be inlined, and don't need to pollute the file)
-	if len(inputMessage.Parts) > 0 {
-		ge.genOpStructMessage(w, d, name, inputMessage)
+	if op.Input != nil && len(op.Input.WSDL.Parts) > 0 {
+		ge.genOpStructMessage(w, d, name, op.Input.WSDL, bo.InputUse == "encoded")
 	}
 
-	// Output messages are always required
-	ge.genOpStructMessage(w, d, name, ge.messages[trimns(ge.funcs[bo.Name].Output.Message)])
+	// Output messages are required, except for one-way operations
+	if op.Output != nil {
+		ge.genOpStructMessage(w, d, name, op.Output.WSDL, false)
+	}
 
 	return nil
 }
@@ -1389,17 +2305,32 @@ func (ge *goEncoder) genStructFields(w io.Writer, d *wsdl.Definitions, ct *wsdl.
 	return ge.genElements(w, ct)
 }
 
-func (ge *goEncoder) genOpStructMessage(w io.Writer, d *wsdl.Definitions, name string, message *wsdl.Message) {
+// genOpStructMessage generates the operation wrapper of message. The
+// parts of encoded messages are encoded with their xsi:type.
+func (ge *goEncoder) genOpStructMessage(w io.Writer, d *wsdl.Definitions, name string, message *wsdl.Message, encoded bool) {
+	ge.enterAt("message", message.Name, message.Pos)
+	defer ge.leave()
+	ge.structAny = false
+	ge.structWildcard = false
+	ge.structChoices = nil
+	ge.structValues = nil
+	ge.structFixed = nil
+
 	sanitizedMessageName := ge.sanitizedOperationsType(message.Name)
 
-	ge.writeComments(w, sanitizedMessageName, "Operation wrapper for "+name+".")
-	ge.writeComments(w, sanitizedMessageName, "")
+	if doc := ge.doc(message.Docs); doc != "" {
+		ge.writeComments(w, sanitizedMessageName, "Operation wrapper for "+name+".\n\n"+doc)
+	} else {
+		ge.writeComments(w, sanitizedMessageName, "Operation wrapper for "+name+".")
+		ge.writeComments(w, sanitizedMessageName, "")
+	}
 	fmt.Fprintf(w, "type %s struct {\n", sanitizedMessageName)
 	if elName, ok := ge.needsTag[sanitizedMessageName]; ok {
 		fmt.Fprintf(w, "XMLName xml.Name `xml:\"%s %s\" json:\"-\" yaml:\"-\"`\n",
 			d.TargetNamespace, elName)
 	}
 
+	var parts []*encodedPart
 	for _, part := range message.Parts {
 		wsdlType := part.Type
 
@@ -1410,39 +2341,77 @@ func (ge *goEncoder) genOpStructMessage(w io.Writer, d *wsdl.Definitions, name s
 
 		partName := part.Name
 		if part.Element != "" {
-			elName := trimns(part.Element)
-			if el, ok := ge.elements[elName]; ok {
+			if _, el, ok := ge.lookupElement(part.Element); ok {
 				partName = trimns(el.Name)
-			} else if el, ok := ge.ctypes[elName]; ok {
+			} else if _, el, ok := ge.lookupComplexType(part.Element); ok {
 				partName = trimns(el.Name)
-			} else if el, ok := ge.stypes[elName]; ok {
+			} else if _, el, ok := ge.lookupSimpleType(part.Element); ok {
 				partName = trimns(el.Name)
 			}
 		}
 
-		ge.genElementField(w, &wsdl.Element{
+		field, _ := ge.genElementField(w, &wsdl.Element{
 			XMLName: part.XMLName,
 			Name:    partName,
 			Type:    wsdlType,
 			// TODO: Maybe one could make guesses about nillable?
 		})
+		if field != "" {
+			space, local := ge.schemaTypeName(d, wsdlType)
+			if _, el, ok := ge.lookupElement(part.Element); ok && part.Type == "" && el.Type != "" {
+				leave := ge.enterScope(el.Namespaces, el.TargetNamespace)
+				space, local = ge.schemaTypeName(d, el.Type)
+				leave()
+			}
+			parts = append(parts, &encodedPart{field, partName, space, local})
+		}
 	}
 
 	fmt.Fprintf(w, "}\n\n")
+	if encoded {
+		ge.needsStdPkg["encoding/xml"] = true
+		encodedMessageT.Execute(w, &struct {
+			Name  string
+			Parts []*encodedPart
+		}{sanitizedMessageName, parts})
+	}
+}
+
+// encodedPart is a part of an encoded message.
+type encodedPart struct {
+	Field, Name, Space, Local string
+}
+
+var encodedMessageT = template.Must(template.New("encodedMessage").Parse(`// MarshalXML implements the xml.Marshaler interface. The parts are
+// encoded with their xsi:type, as SOAP encoding requires.
+func (t {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+{{range .Parts}}	if err := soap.EncodeTyped(e, xml.StartElement{Name: xml.Name{Local: {{printf "%q" .Name}}}}, xml.Name{Space: {{printf "%q" .Space}}, Local: {{printf "%q" .Local}}}, t.{{.Field}}); err != nil {
+		return err
+	}
+{{end}}	return e.EncodeToken(start.End())
 }
 
+`))
+
 func (ge *goEncoder) genComplexContent(w io.Writer, d *wsdl.Definitions, ct *wsdl.ComplexType) error {
 	if ct.ComplexContent == nil || ct.ComplexContent.Extension == nil {
 		return nil
 	}
 	ext := ct.ComplexContent.Extension
 	if ext.Base != "" {
-		base, exists := ge.ctypes[trimns(ext.Base)]
+		key, base, exists := ge.lookupComplexType(ext.Base)
 		if exists {
+			leave := ge.enterScope(base.Namespaces, key.Space)
 			err := ge.genStructFields(w, d, base)
+			leave()
 			if err != nil {
 				return err
 			}
+		} else {
+			ge.warn("base type %q cannot be resolved, its fields are dropped", ext.Base)
 		}
 	}
 
@@ -1450,25 +2419,8 @@ func (ge *goEncoder) genComplexContent(w io.Writer, d *wsdl.Definitions, ct *wsd
 		ge.genAttributeField(w, attr)
 	}
 
-	sequences := make([]*wsdl.Sequence, 0)
-	if ext.Sequence != nil {
-		sequences = append(sequences, ext.Sequence)
-		for _, choice := range ext.Sequence.Choices {
-			tmpSeq := &wsdl.Sequence{
-				ComplexTypes: choice.ComplexTypes,
-				Elements:     choice.Elements,
-				Any:          choice.Any}
-			sequences = append(sequences, tmpSeq)
-		}
-	}
-	if ext.Choice != nil {
-		tmpSeq := &wsdl.Sequence{
-			ComplexTypes: ext.Choice.ComplexTypes,
-			Elements:     ext.Choice.Elements,
-			Any:          ext.Choice.Any}
-		sequences = append(sequences, tmpSeq)
-	}
-	for _, seq := range sequences {
+	choices := make([]*wsdl.Choice, 0)
+	if seq := ext.Sequence; seq != nil {
 		for _, v := range seq.ComplexTypes {
 			err := ge.genElements(w, v)
 			if err != nil {
@@ -1478,7 +2430,22 @@ func (ge *goEncoder) genComplexContent(w io.Writer, d *wsdl.Definitions, ct *wsd
 		for _, v := range seq.Elements {
 			ge.genElementField(w, v)
 		}
-
+		if len(seq.Any) > 0 {
+			ge.genAnyField(w)
+		}
+		choices = append(choices, seq.Choices...)
+	}
+	if ext.Choice != nil {
+		choices = append(choices, ext.Choice)
+	}
+	for _, choice := range choices {
+		for _, v := range choice.ComplexTypes {
+			err := ge.genElements(w, v)
+			if err != nil {
+				return err
+			}
+		}
+		ge.genChoiceFields(w, choice)
 	}
 	return nil
 }
@@ -1490,16 +2457,18 @@ func (ge *goEncoder) genSimpleContent(w io.Writer, d *wsdl.Definitions, ct *wsdl
 
 	ext := ct.SimpleContent.Extension
 	if ext.Base != "" {
-		baseComplex, exists := ge.ctypes[trimns(ext.Base)]
+		key, baseComplex, exists := ge.lookupComplexType(ext.Base)
 		if exists {
+			leave := ge.enterScope(baseComplex.Namespaces, key.Space)
 			err := ge.genStructFields(w, d, baseComplex)
+			leave()
 			if err != nil {
 				return err
 			}
 		} else {
 			// otherwise it's a simple type
 			ge.genElementField(w, &wsdl.Element{
-				Type: trimns(ext.Base),
+				Type: ext.Base,
 				Name: "Content",
 			})
 		}
@@ -1522,15 +2491,14 @@ func (ge *goEncoder) genElements(w io.Writer, ct *wsdl.ComplexType) error {
 			ge.genElementField(w, el)
 		}
 		for _, choice := range ct.Sequence.Choices {
-			for _, el := range choice.Elements {
-				ge.genElementField(w, el)
-			}
+			ge.genChoiceFields(w, choice)
+		}
+		if len(ct.Sequence.Any) > 0 {
+			ge.genAnyField(w)
 		}
 	}
 	if ct.Choice != nil {
-		for _, el := range ct.Choice.Elements {
-			ge.genElementField(w, el)
-		}
+		ge.genChoiceFields(w, ct.Choice)
 	}
 	for _, attr := range ct.Attributes {
 		ge.genAttributeField(w, attr)
@@ -1538,15 +2506,88 @@ func (ge *goEncoder) genElements(w io.Writer, ct *wsdl.ComplexType) error {
 	return nil
 }
 
-func (ge *goEncoder) genElementField(w io.Writer, el *wsdl.Element) {
+// elementGoType returns the Go type of the field of el, without the
+// slice of repeated elements, and whether the element is optional.
+// Optional elements are pointers, so that the XML encoder can tell
+// absent elements from zero values.
+func (ge *goEncoder) elementGoType(el *wsdl.Element) (string, bool) {
+	et := el.Type
+	if et == "" {
+		et = "string"
+	}
+	typ := ge.wsdl2goType(et)
+	nillable := el.Nillable
+	if nillable && ge.nillable && typ != "interface{}" {
+		// the wrapper tells xsi:nil from an absent element
+		typ = ge.nillableType(typ)
+		nillable = false
+	}
+	optional := nillable || el.Min == 0
+	if optional && !strings.HasPrefix(typ, "*") {
+		typ = "*" + typ
+	}
+	return typ, optional
+}
+
+// genAnyField generates the field that captures the elements matched by
+// xs:any, unless the struct has one already. All the wildcards of a
+// struct share the field.
+func (ge *goEncoder) genAnyField(w io.Writer) {
+	if ge.structAny {
+		if !ge.structWildcard {
+			ge.warn("xs:any dropped, another field takes any element")
+		}
+		return
+	}
+	ge.structAny = true
+	ge.structWildcard = true
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	fmt.Fprint(w, "Any []*soap.AnyElement `xml:\",any\" json:\"Any,omitempty\" yaml:\"Any,omitempty\"`\n")
+}
+
+// genElementField generates the struct field of el, and returns its name
+// and whether it is a slice. The name is empty if the field is dropped.
+func (ge *goEncoder) genElementField(w io.Writer, el *wsdl.Element) (string, bool) {
+	// members of a substitution group are decoded by the type
+	// registry, from the elements that are not fields
+	var group string
+	if key, head, _ := ge.lookupElement(el.Ref); el.Ref != "" && len(ge.substitutes[key]) > 0 {
+		switch {
+		case head == nil || !ge.abstract(head.Type):
+			ge.warn("substitution group of %q is decoded as its head, the type is not abstract", el.Ref)
+		case ge.structAny:
+			ge.warn("substitution group of %q is decoded as its head, another field takes any element", el.Ref)
+		default:
+			group = ge.substGroupType(key)
+			ge.structAny = true
+		}
+	}
+	docs := el.Docs
 	if el.Ref != "" {
-		ref := trimns(el.Ref)
-		nel, ok := ge.elements[ref]
-		if !ok {
-			return
+		_, nel, ok := ge.lookupElement(el.Ref)
+		switch {
+		case ok:
+			// occurrence constraints are those of the reference, and
+			// anonymous types are named after the element
+			cp := *nel
+			cp.Min, cp.Max = el.Min, el.Max
+			if cp.Type == "" && cp.ComplexType != nil {
+				cp.Type, cp.ComplexType = el.Ref, nil
+			}
+			el = &cp
+			if len(docs) == 0 {
+				docs = nel.Docs
+			}
+		case el.Name != "":
+			// nameRefs named the reference after the element
+			ge.warn("element reference %q cannot be resolved", el.Ref)
+		default:
+			ge.warn("element reference %q cannot be resolved, field dropped", el.Ref)
+			return "", false
 		}
-		el = nel
 	}
+	ge.enterAt("element", el.Name, el.Pos)
+	defer ge.leave()
 	var slicetype string
 	if el.Type == "" && el.ComplexType != nil {
 		seq := el.ComplexType.Sequence
@@ -1564,41 +2605,353 @@ func (ge *goEncoder) genElementField(w io.Writer, el *wsdl.Element) {
 				*el = *seqel
 				slicetype = seqel.Name
 				el.Name = n
-			} else if len(seq.Any) == 1 {
+			} else if len(seq.Any) == 1 && len(seq.Elements) == 0 {
+				// the element itself is captured, with its
+				// content preserved
 				el = &wsdl.Element{
 					Name: el.Name,
-					Type: "anysequence",
-					Min:  seq.Any[0].Min,
-					Max:  seq.Any[0].Max,
+					Type: "anyelement",
+					Min:  el.Min,
+					Max:  el.Max,
 				}
-				slicetype = el.Name
 			}
 		}
 	}
-	et := el.Type
-	if et == "" {
-		et = "string"
-	}
 	tag := el.Name
-	fmt.Fprintf(w, "%s ", goSymbol(el.Name))
-	if el.Max != "" && el.Max != "1" {
+	field := goSymbol(el.Name)
+	ge.writeFieldDoc(w, docs)
+	if group != "" {
+		fmt.Fprintf(w, "%s %s `xml:\",any,omitempty\" json:\"%s,omitempty\" yaml:\"%s,omitempty\"`\n",
+			field, group, tag, tag)
+		return field, true
+	}
+	fmt.Fprintf(w, "%s ", field)
+	slice := el.Max != "" && el.Max != "1"
+	if slice {
 		fmt.Fprintf(w, "[]")
 		if slicetype != "" {
 			tag = el.Name + ">" + slicetype
 		}
 	}
-	typ := ge.wsdl2goType(et)
-	if el.Nillable || el.Min == 0 {
+	typ, optional := ge.elementGoType(el)
+	if optional {
 		tag += ",omitempty"
-		//since we add omitempty tag, we should add pointer to type.
-		//thus xmlencoder can differ not-initialized fields from zero-initialized values
-		if !strings.HasPrefix(typ, "*") {
-			typ = "*" + typ
-		}
 	}
 	fmt.Fprintf(w, "%s `xml:\"%s\" json:\"%s\" yaml:\"%s\"`\n",
 		typ, tag, tag, tag)
+	if slice && (el.Default != "" || el.Fixed != "") {
+		ge.warn("default and fixed values of repeated elements are not generated")
+	} else {
+		ge.recordValues(field, typ, el.Default, el.Fixed)
+	}
+	return field, slice
+}
+
+// nillableType returns the name of the wrapper of the Go type typ for
+// nillable elements.
+func (ge *goEncoder) nillableType(typ string) string {
+	name := "Nillable" + typeSymbol(typ)
+	ge.nillables[name] = strings.TrimPrefix(typ, "*")
+	return name
+}
+
+var nillableT = template.Must(template.New("nillable").Parse(`
+// {{.Name}} is a nillable {{.Type}}. Nil is encoded as xsi:nil="true" in
+// XML and as null in JSON, where values are encoded as plain values.
+type {{.Name}} struct {
+	Value {{.Type}}
+	Nil   bool
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+func (v {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if v.Nil {
+		return soap.EncodeNil(e, start)
+	}
+	return e.EncodeElement(v.Value, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface.
+func (v *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*v = {{.Name}}{}
+	if soap.IsNil(start) {
+		v.Nil = true
+		return d.Skip()
+	}
+	return d.DecodeElement(&v.Value, &start)
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (v {{.Name}}) MarshalJSON() ([]byte, error) {
+	if v.Nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(v.Value)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (v *{{.Name}}) UnmarshalJSON(data []byte) error {
+	*v = {{.Name}}{}
+	if string(data) == "null" {
+		v.Nil = true
+		return nil
+	}
+	return json.Unmarshal(data, &v.Value)
+}
+`))
+
+// genNillableTypes generates the wrappers of nillable elements.
+func (ge *goEncoder) genNillableTypes(w io.Writer) error {
+	if len(ge.nillables) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(ge.nillables))
+	for name := range ge.nillables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ge.needsStdPkg["encoding/json"] = true
+	ge.needsStdPkg["encoding/xml"] = true
+	ge.needsExtPkg["github.com/fiorix/wsdl2go/soap"] = true
+	for _, name := range names {
+		err := nillableT.Execute(w, &struct {
+			Name string
+			Type string
+		}{name, ge.nillables[name]})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// choiceFields are the struct fields of the alternatives of a choice.
+type choiceFields struct {
+	Optional bool
+	Fields   []choiceField
+}
+
+type choiceField struct {
+	Name  string
+	Slice bool
+}
+
+// Names returns the names of the fields, for error messages.
+func (c *choiceFields) Names() string {
+	names := make([]string, len(c.Fields))
+	for i, f := range c.Fields {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// genChoiceFields generates the alternatives of c as optional fields,
+// so that only the selected one is encoded. Unless c repeats, the
+// alternatives are recorded for genStructMethods.
+func (ge *goEncoder) genChoiceFields(w io.Writer, c *wsdl.Choice) {
+	repeated := c.Max != "" && c.Max != "1"
+	choice := &choiceFields{Optional: c.Min == "0"}
+	for _, el := range c.Elements {
+		nel := *el
+		nel.Min = 0
+		if repeated && (nel.Max == "" || nel.Max == "1") {
+			nel.Max = c.Max
+		}
+		name, slice := ge.genElementField(w, &nel)
+		if name != "" {
+			choice.Fields = append(choice.Fields, choiceField{name, slice})
+		}
+	}
+	if len(c.Any) > 0 {
+		// the Any field may hold other wildcard elements, so only
+		// the named alternatives are validated
+		ge.genAnyField(w)
+		choice.Optional = true
+	}
+	if !repeated && (len(choice.Fields) > 1 || len(choice.Fields) == 1 && !choice.Optional) {
+		ge.structChoices = append(ge.structChoices, choice)
+	}
+}
+
+// fieldValue is the default or fixed value of a struct field.
+type fieldValue struct {
+	Field string
+	Type  string // without pointer
+	Ptr   bool
+	Value string // Go literal
+	Zero  string // zero value of the field
+}
+
+// recordValues records the default or fixed value of the field of Go
+// type typ, for genStructMethods.
+func (ge *goEncoder) recordValues(field, typ, def, fixed string) {
+	v := def
+	if fixed != "" {
+		v = fixed
+	}
+	if v == "" {
+		return
+	}
+	t := strings.TrimPrefix(typ, "*")
+	inner, nillable := ge.nillables[t]
+	if !nillable {
+		inner = t
+	}
+	lit, ok := ge.goLiteral(inner, v)
+	if !ok {
+		ge.warn("value %q cannot be represented as %s, not generated", v, inner)
+		return
+	}
+	if !ge.enumerates(inner, v) {
+		ge.warn("value %q is not an enumerated value of %s, not generated", v, inner)
+		return
+	}
+	fv := &fieldValue{
+		Field: field,
+		Type:  t,
+		Ptr:   strings.HasPrefix(typ, "*"),
+		Value: lit,
+		Zero:  ge.wsdl2goZero(typ),
+	}
+	if nillable {
+		// parenthesized, as they are compared in if statements
+		fv.Value = "(" + t + "{Value: " + lit + "})"
+		if !fv.Ptr {
+			fv.Zero = "(" + fv.Zero + ")"
+		}
+	}
+	if fixed != "" {
+		ge.structFixed = append(ge.structFixed, fv)
+	}
+	ge.structValues = append(ge.structValues, fv)
+}
+
+// goLiteral returns the Go literal of the schema value v of the simple
+// Go type typ.
+func (ge *goEncoder) goLiteral(typ, v string) (string, bool) {
+	switch ge.basicType(typ) {
+	case "string":
+		return strconv.Quote(v), true
+	case "bool":
+		switch strings.TrimSpace(v) {
+		case "true", "1":
+			return "true", true
+		case "false", "0":
+			return "false", true
+		}
+	case "int", "int64":
+		v = strings.TrimSpace(v)
+		_, err := strconv.ParseInt(v, 10, 64)
+		return v, err == nil
+	case "byte", "uint", "uint64":
+		v = strings.TrimSpace(v)
+		_, err := strconv.ParseUint(v, 10, 64)
+		return v, err == nil
+	case "float64":
+		v = strings.TrimSpace(v)
+		_, err := strconv.ParseFloat(v, 64)
+		return v, err == nil
+	}
+	return "", false
+}
+
+// genStructMethods generates the methods of the struct name that
+// enforce its choices and its default and fixed values: Validate,
+// New<Name>, MarshalXML and UnmarshalXML.
+func (ge *goEncoder) genStructMethods(w io.Writer, name string) error {
+	if len(ge.structChoices) == 0 && len(ge.structValues) == 0 {
+		return nil
+	}
+	var doc []string
+	if len(ge.structChoices) > 0 {
+		ge.needsStdPkg["errors"] = true
+		doc = append(doc, "It only encodes valid choices.")
+	}
+	if len(ge.structFixed) > 0 {
+		ge.needsStdPkg["fmt"] = true
+		doc = append(doc, "Fixed values are always encoded.")
+	}
+	if len(doc) > 0 {
+		ge.needsStdPkg["encoding/xml"] = true
+	}
+	var b bytes.Buffer
+	ge.writeComments(&b, "MarshalXML", "MarshalXML implements the xml.Marshaler interface. "+strings.Join(doc, " "))
+	return structMethodsT.Execute(w, &struct {
+		Name       string
+		Choices    []*choiceFields
+		Values     []*fieldValue
+		Fixed      []*fieldValue
+		MarshalDoc string
+	}{name, ge.structChoices, ge.structValues, ge.structFixed, b.String()})
+}
+
+var structMethodsT = template.Must(template.New("structMethods").Parse(`
+{{- define "set"}}
+{{- if .Ptr}}
+	t.{{.Field}} = new({{.Type}})
+	*t.{{.Field}} = {{.Value}}
+{{- else}}
+	t.{{.Field}} = {{.Value}}
+{{- end}}
+{{- end}}
+{{- if .Choices}}
+// Validate checks that one alternative of each choice of {{.Name}} is set.
+func (t *{{.Name}}) Validate() error {
+	var n int
+{{- range .Choices}}
+	n = 0
+{{- range .Fields}}
+	if {{if .Slice}}len(t.{{.Name}}) > 0{{else}}t.{{.Name}} != nil{{end}} {
+		n++
+	}
+{{- end}}
+	if n {{if .Optional}}> 1{{else}}!= 1{{end}} {
+		return errors.New("{{$.Name}}: {{if .Optional}}at most{{else}}exactly{{end}} one of {{.Names}} must be set")
+	}
+{{- end}}
+	return nil
+}
+{{end}}
+{{- if .Values}}
+// New{{.Name}} returns a new {{.Name}} set to the default and fixed
+// values of the schema.
+func New{{.Name}}() *{{.Name}} {
+	t := &{{.Name}}{}
+{{- range .Values}}{{template "set" .}}{{end}}
+	return t
+}
+{{end}}
+{{- if or .Choices .Fixed}}
+{{.MarshalDoc}}func (t {{.Name}}) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+{{- if .Choices}}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+{{- end}}
+{{- range .Fixed}}{{template "set" .}}{{end}}
+	type plain {{.Name}}
+	return e.EncodeElement(plain(t), start)
+}
+{{end}}
+{{- if .Fixed}}
+// UnmarshalXML implements the xml.Unmarshaler interface. It fails if a
+// fixed value differs from the schema.
+func (t *{{.Name}}) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain {{.Name}}
+	if err := d.DecodeElement((*plain)(t), &start); err != nil {
+		return err
+	}
+{{- range .Fixed}}
+	if t.{{.Field}} == {{.Zero}} {
+	{{- template "set" .}}
+	} else if {{if .Ptr}}*{{end}}t.{{.Field}} != {{.Value}} {
+		return fmt.Errorf("{{$.Name}}: {{.Field}} is %v, want fixed value %v", {{if .Ptr}}*{{end}}t.{{.Field}}, {{.Value}})
+	}
+{{- end}}
+	return nil
 }
+{{end}}
+`))
 
 func (ge *goEncoder) genAttributeField(w io.Writer, attr *wsdl.Attribute) {
 	if attr.Name == "" && attr.Ref != "" {
@@ -1607,46 +2960,144 @@ func (ge *goEncoder) genAttributeField(w io.Writer, attr *wsdl.Attribute) {
 	if attr.Type == "" {
 		attr.Type = "string"
 	}
+	ge.enter("attribute", attr.Name)
+	defer ge.leave()
 
 	tag := fmt.Sprintf("%s,attr", attr.Name)
+	ge.writeFieldDoc(w, attr.Docs)
 	fmt.Fprintf(w, "%s ", goSymbol(attr.Name))
 	typ := ge.wsdl2goType(attr.Type)
 	if attr.Nillable || attr.Min == 0 {
 		tag += ",omitempty"
+		// unions are structs, which are never omitted
+		if _, st, ok := ge.lookupSimpleType(attr.Type); ok && st.Union != nil {
+			typ = "*" + typ
+		}
 	}
 	fmt.Fprintf(w, "%s `xml:\"%s\" json:\"%s\" yaml:\"%s\"`\n",
 		typ, tag, tag, tag)
+	ge.recordValues(goSymbol(attr.Name), typ, attr.Default, attr.Fixed)
 }
 
-// writeComments writes comments to w, capped at ~80 columns.
+// commentWidth is the width of the text of comments, after "// ", so
+// that lines of comments are at most 80 columns.
+const commentWidth = 77
+
+var paragraphSep = regexp.MustCompile(`\n[ \t\r]*\n`)
+
+// writeComments writes comment to w as Go comments, wrapped at 80
+// columns. Paragraphs, separated by blank lines, are kept. An empty
+// comment is replaced by a note that typeName was auto-generated.
 func (ge *goEncoder) writeComments(w io.Writer, typeName, comment string) {
-	comment = strings.Trim(strings.Replace(comment, "\n", " ", -1), " ")
+	comment = strings.TrimSpace(comment)
 	if comment == "" {
 		comment = goSymbol(typeName) + " was auto-generated from WSDL."
 	}
-	count, line := 0, ""
-	words := strings.Split(comment, " ")
-	for _, word := range words {
-		if line == "" {
-			count, line = 2, "//"
+	for i, para := range paragraphSep.Split(comment, -1) {
+		if i > 0 {
+			io.WriteString(w, "//\n")
+		}
+		for _, line := range wrap(para, commentWidth) {
+			fmt.Fprintf(w, "// %s\n", line)
 		}
+	}
+}
 
-		count += len(word)
-		if count > 60 {
-			fmt.Fprintf(w, "%s %s\n", line, word)
-			count, line = 0, ""
-			continue
+// wrap splits the words of text into lines of at most width bytes,
+// unless a word is longer.
+func wrap(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line == "" {
+			line = word
+		} else {
+			line += " " + word
 		}
-		line = line + " " + word
-		count++
 	}
 	if line != "" {
-		fmt.Fprintf(w, "%s\n", line)
+		lines = append(lines, line)
 	}
-	return
+	return lines
+}
+
+// joinDoc returns the documentation doc, if any, followed by the
+// paragraph text.
+func joinDoc(doc, text string) string {
+	if doc == "" {
+		return text
+	}
+	return doc + "\n\n" + text
+}
+
+// doc returns the documentation docs in the language of SetDocLanguage.
+func (ge *goEncoder) doc(docs wsdl.Docs) string {
+	return docs.Text(ge.docLang)
+}
+
+// writeFieldDoc writes the documentation docs of a struct field to w,
+// if any.
+func (ge *goEncoder) writeFieldDoc(w io.Writer, docs wsdl.Docs) {
+	if doc := ge.doc(docs); doc != "" {
+		ge.writeComments(w, "", doc)
+	}
+}
+
+// writeEnumDocs writes the documented values of the enumeration enum to
+// w, as a list that continues the doc comment of its type.
+func (ge *goEncoder) writeEnumDocs(w io.Writer, enum []*wsdl.Enum) {
+	documented := false
+	for _, v := range enum {
+		if ge.doc(v.Docs) != "" {
+			documented = true
+			break
+		}
+	}
+	if !documented {
+		return
+	}
+	io.WriteString(w, "//\n// Values:\n//\n")
+	for _, v := range enum {
+		text := strconv.Quote(v.Value)
+		if doc := ge.doc(v.Docs); doc != "" {
+			text += ": " + doc
+		}
+		for i, line := range wrap(text, commentWidth-4) {
+			if i == 0 {
+				fmt.Fprintf(w, "//   - %s\n", line)
+			} else {
+				fmt.Fprintf(w, "//     %s\n", line)
+			}
+		}
+	}
+}
+
+// SetDocLanguage selects the language of the documentation of the WSDL
+// that is emitted as Go comments, of its xml:lang attributes.
+func (ge *goEncoder) SetDocLanguage(lang string) {
+	ge.docLang = lang
 }
 
 // SetLocalNamespace allows overridding of namespace in XMLName
 func (ge *goEncoder) SetLocalNamespace(s string) {
 	ge.localNamespace = s
 }
+
+// SetGenerateMocks enables generation of the port type mock
+func (ge *goEncoder) SetGenerateMocks(enabled bool) {
+	ge.genMocks = enabled
+}
+
+// SetUnwrap enables unwrapped signatures of wrapped operations
+func (ge *goEncoder) SetUnwrap(enabled bool) {
+	ge.unwrap = enabled
+}
+
+// SetNillable enables the wrappers of nillable elements
+func (ge *goEncoder) SetNillable(enabled bool) {
+	ge.nillable = enabled
+}
@@ -11,9 +11,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/fiorix/wsdl2go/catalog"
+	"github.com/fiorix/wsdl2go/resolver"
 	"github.com/fiorix/wsdl2go/wsdl"
 )
 
@@ -31,7 +34,7 @@ func LoadDefinition(t *testing.T, filename string, want error) *wsdl.Definitions
 	s := strings.Replace(string(ba), "CURRENT_DIR", pwd, 1)
 
 	d, err := wsdl.Unmarshal(strings.NewReader(s))
-	if err != want {
+	if !reflect.DeepEqual(err, want) {
 		t.Errorf("%q failed: want %v, have %v", filename, want, err)
 	}
 	return d
@@ -41,8 +44,9 @@ var EncoderCases = []struct {
 	F string
 	G string
 	E error
+	O func(Encoder)
 }{
-	{F: "broken.wsdl", E: io.EOF},
+	{F: "broken.wsdl", E: &wsdl.ParseError{Pos: wsdl.Position{Line: 2, Column: 1, Offset: 8}, Err: io.EOF}},
 	{F: "w3cexample1.wsdl", G: "w3cexample1.golden", E: nil},
 	{F: "w3cexample2.wsdl", G: "w3cexample2.golden", E: nil},
 	{F: "w3example1.wsdl", G: "w3example1.golden", E: nil},
@@ -56,6 +60,33 @@ var EncoderCases = []struct {
 	{F: "localimport-url.wsdl", G: "localimport.golden", E: nil},
 	{F: "localimport_choice.wsdl", G: "localimport_choice.golden", E: nil},
 	{F: "arrayexample.wsdl", G: "arrayexample.golden", E: nil},
+	{F: "groups.wsdl", G: "groups.golden", E: nil},
+	{F: "polymorphic.wsdl", G: "polymorphic.golden", E: nil},
+	{F: "choice.wsdl", G: "choice.golden", E: nil},
+	{F: "listunion.wsdl", G: "listunion.golden", E: nil},
+	{F: "defaults.wsdl", G: "defaults.golden", E: nil},
+	{F: "nillable.wsdl", G: "nillable.golden", E: nil, O: func(enc Encoder) { enc.SetNillable(true) }},
+	{F: "anyelement.wsdl", G: "anyelement.golden", E: nil},
+	{F: "wrapped.wsdl", G: "wrapped.golden", E: nil, O: func(enc Encoder) { enc.SetUnwrap(true) }},
+	{F: "oneway.wsdl", G: "oneway.golden", E: nil},
+	{F: "oneway_rpc.wsdl", G: "oneway_rpc.golden", E: nil},
+	{F: "wsdl20.wsdl", G: "wsdl20.golden", E: nil},
+	{F: "namespaces.wsdl", G: "namespaces.golden", E: nil},
+	{F: "imports/root.wsdl", G: "imports.golden", E: nil, O: func(enc Encoder) { enc.SetLocation("testdata/imports/root.wsdl") }},
+	{F: "imports/root.wsdl", G: "imports.golden", E: nil, O: func(enc Encoder) { enc.SetLocation("http://localhost:9999/imports/root.wsdl") }},
+	{F: "imports/root.wsdl", G: "imports.golden", E: nil, O: func(enc Encoder) {
+		c, err := catalog.Load("testdata/imports/catalog.xml")
+		if err != nil {
+			panic(err)
+		}
+		enc.SetLocation("http://vendor.example.com/contracts/root.wsdl")
+		enc.SetResolver(&resolver.Resolver{Catalog: c})
+	}},
+	{F: "imports/root.wsdl", G: "imports.golden", E: nil, O: func(enc Encoder) { enc.SetLocation("testdata/imports.zip#contract/root.wsdl") }},
+	{F: "imports/root.wsdl", G: "imports.golden", E: nil, O: func(enc Encoder) { enc.SetLocation("http://localhost:9999/imports.zip#contract/root.wsdl") }},
+	{F: "memcache.wsdl", G: "memcache_mock.golden", E: nil, O: func(enc Encoder) { enc.SetGenerateMocks(true) }},
+	{F: "docs.wsdl", G: "docs.golden", E: nil},
+	{F: "docs.wsdl", G: "docs_pt.golden", E: nil, O: func(enc Encoder) { enc.SetDocLanguage("pt-BR") }},
 }
 
 func NewTestServer(t *testing.T) *httptest.Server {
@@ -80,7 +111,11 @@ func TestEncoder(t *testing.T) {
 		var err error
 		var want []byte
 		var have bytes.Buffer
-		err = NewEncoder(&have).Encode(d)
+		enc := NewEncoder(&have)
+		if tc.O != nil {
+			tc.O(enc)
+		}
+		err = enc.Encode(d)
 		if err != nil {
 			t.Errorf("test %d, encoding %q: %v", i, tc.F, err)
 		}
@@ -130,3 +165,161 @@ func Diff(prefix, ext string, a, b []byte) error {
 	}
 	return nil
 }
+
+func TestEncoderInvalidValues(t *testing.T) {
+	d := LoadDefinition(t, "badvalues.wsdl", nil)
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`17:11: complexType[@name='Order']/element[@name='Discount']: value "ten" cannot be represented as float64, not generated`,
+		`18:11: complexType[@name='Order']/element[@name='Priority']: value "urgent" is not an enumerated value of Priority, not generated`,
+		`15:7: complexType[@name='Order']/attribute[@name='retries']: value "three" cannot be represented as int, not generated`,
+	}
+	have := enc.Diagnostics()
+	if len(have) != len(want) {
+		t.Fatalf("want %d diagnostics, have %d: %v", len(want), len(have), have)
+	}
+	for i, diag := range have {
+		if diag.String() != want[i] {
+			t.Errorf("diagnostic %d:\nwant %s\nhave %s", i, want[i], diag)
+		}
+	}
+
+	enc = NewEncoder(&b)
+	enc.SetStrict(true)
+	if _, ok := enc.Encode(d).(*StrictError); !ok {
+		t.Fatal("want StrictError for invalid default and fixed values")
+	}
+}
+
+func TestEncoderDiagnostics(t *testing.T) {
+	d := LoadDefinition(t, "dropped.wsdl", nil)
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"simpleType[@name='IntOrString']: anonymous member type of union approximated as string",
+		"17:7: complexType[@name='Bag']: mixed content is generated as soap.AnyElement, child elements are not typed",
+		`22:7: complexType[@name='Item']: element reference "tns:Missing" cannot be resolved`,
+		`25:11: complexType[@name='Item']/element[@name='Missing']: unknown type "tns:Missing", generated as *Missing which is not declared`,
+		`26:11: complexType[@name='Item']/element[@name='Owner']: unknown type "tns:Person", generated as *Person which is not declared`,
+	}
+	have := enc.Diagnostics()
+	if len(have) != len(want) {
+		t.Fatalf("want %d diagnostics, have %d: %v", len(want), len(have), have)
+	}
+	for i, diag := range have {
+		if diag.String() != want[i] {
+			t.Errorf("diagnostic %d:\nwant %s\nhave %s", i, want[i], diag)
+		}
+	}
+
+	b.Reset()
+	enc = NewEncoder(&b)
+	enc.SetStrict(true)
+	err := enc.Encode(d)
+	if _, ok := err.(*StrictError); !ok {
+		t.Fatalf("want StrictError, have %v", err)
+	}
+	if b.Len() != 0 {
+		t.Fatalf("unexpected output in strict mode:\n%s", b.Bytes())
+	}
+}
+
+func TestEncoderLooseNames(t *testing.T) {
+	d := LoadDefinition(t, "loosenames.wsdl", nil)
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	if err := enc.Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"27:11: complexType[@name='Order']/element[@name='Contact']: complexType {urn:c}Contact is not defined, resolved to {urn:a}Contact by local name",
+		"28:11: complexType[@name='Order']/element[@name='ShipTo']: complexType {urn:c}Address is not defined, and its local name is ambiguous: {urn:a}Address, {urn:b}Address",
+	}
+	have := enc.Diagnostics()
+	if len(have) != len(want) {
+		t.Fatalf("want %d diagnostics, have %d: %v", len(want), len(have), have)
+	}
+	for i, diag := range have {
+		if diag.String() != want[i] {
+			t.Errorf("diagnostic %d:\nwant %s\nhave %s", i, want[i], diag)
+		}
+	}
+}
+
+func TestParamName(t *testing.T) {
+	used := make(map[string]bool)
+	for _, c := range []struct{ name, want string }{
+		{"order-id", "orderId"},
+		{"OrderId", "orderId2"},
+		{"P", "_p"},
+		{"range", "_range"},
+		{"Soap", "_soap"},
+		{"soap", "_soap2"},
+	} {
+		if have := paramName(c.name, used); have != c.want {
+			t.Errorf("%q: want %q, have %q", c.name, c.want, have)
+		}
+	}
+}
+
+// mockFaultTest exercises the Fault methods of the generated mock.
+const mockFaultTest = `package memoryservice
+
+import (
+	"testing"
+
+	"github.com/fiorix/wsdl2go/soap"
+)
+
+func TestFault(t *testing.T) {
+	m := &MockMemoryServicePortType{}
+	m.FaultGet(nil)
+	if _, err := m.Get("k"); err != nil {
+		t.Errorf("nil fault: want no error, have %#v", err)
+	}
+	m.FaultGet(&soap.Fault{String: "gone"})
+	if _, err := m.Get("k"); err == nil {
+		t.Error("want fault, have no error")
+	}
+}
+`
+
+func TestEncoderMockFault(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds generated code")
+	}
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	// the package is in the tree, so that it imports the soap package
+	dir, err := ioutil.TempDir(".", "_mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var code bytes.Buffer
+	enc := NewEncoder(&code)
+	enc.SetGenerateMocks(true)
+	if err = enc.Encode(LoadDefinition(t, "memcache.wsdl", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "memcache.go"), code.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "memcache_test.go"), []byte(mockFaultTest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(gotool, "test", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+}
@@ -0,0 +1,363 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/fiorix/wsdl2go/resolver"
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// SetLocation records the location of the document given to Encode, a
+// file path or URL, which relative locations of imports and includes
+// are resolved against.
+func (ge *goEncoder) SetLocation(loc string) {
+	ge.location = loc
+}
+
+// SetResolver records the resolver that reads the documents that are
+// imported and included. Relative imports are resolved against the
+// original locations, not those the catalog of r maps them to.
+func (ge *goEncoder) SetResolver(r *resolver.Resolver) {
+	ge.resolver = r
+}
+
+// Fetch loads the document at loc, a file path or URL, and the documents
+// it imports and includes at any depth, the same way Encode does, and
+// calls fn with the location and content of each document in the order
+// they are loaded. The resolver r, if not nil, reads the documents.
+func Fetch(loc string, r *resolver.Resolver, fn func(loc string, data []byte) error) error {
+	ge := loader(loc, r)
+	ge.loaded = fn
+	ge.importedSchemas[loc] = true
+	d, schema, err := ge.load(loc)
+	if err != nil {
+		return err
+	}
+	if schema != nil {
+		return ge.importSchemas(&wsdl.Definitions{}, schema, loc)
+	}
+	return ge.importParts(d)
+}
+
+// Import loads the documents imported and included by d, and those
+// they import and include, at any depth, the same way Encode does, and
+// merges their definitions into d. Relative locations are resolved
+// against loc, the location of d, or the working directory if loc is
+// empty. The resolver r, if not nil, reads the documents.
+func Import(d *wsdl.Definitions, loc string, r *resolver.Resolver) error {
+	return loader(loc, r).importParts(d)
+}
+
+// Flatten loads the WSDL document at loc and the documents it imports
+// and includes, at any depth, the same way Encode does, and returns a
+// single document with all their definitions. The schemas of the
+// document import the namespaces of each other, without locations, and
+// have no includes. The resolver r, if not nil, reads the documents.
+func Flatten(loc string, r *resolver.Resolver) (*wsdl.Definitions, error) {
+	ge := loader(loc, r)
+	d, schema, err := ge.load(loc)
+	if err != nil {
+		return nil, err
+	}
+	if schema != nil {
+		return nil, fmt.Errorf("%s: not a WSDL document", loc)
+	}
+	if err = ge.importParts(d); err != nil {
+		return nil, err
+	}
+	d.Imports = nil
+	d.Schema.Includes = nil
+	d.Schema.Imports = flatImports(&d.Schema)
+	return d, nil
+}
+
+// flatImports returns the imports of the schemas of the flattened
+// schema s: those of each target namespace of s from the others, and
+// the imports of other namespaces without locations, such as that of
+// SOAP encoding.
+func flatImports(s *wsdl.Schema) []*wsdl.ImportSchema {
+	var namespaces []string
+	seen := make(map[string]bool)
+	add := func(ns string) {
+		if ns == "" {
+			ns = s.TargetNamespace
+		}
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	for _, v := range s.SimpleTypes {
+		add(v.TargetNamespace)
+	}
+	for _, v := range s.ComplexTypes {
+		add(v.TargetNamespace)
+	}
+	for _, v := range s.Elements {
+		add(v.TargetNamespace)
+	}
+	for _, v := range s.Groups {
+		add(v.TargetNamespace)
+	}
+	for _, v := range s.AttributeGroups {
+		add(v.TargetNamespace)
+	}
+	var imports []*wsdl.ImportSchema
+	for _, tns := range namespaces {
+		for _, ns := range namespaces {
+			if ns != tns {
+				imports = append(imports, &wsdl.ImportSchema{Namespace: ns, TargetNamespace: tns})
+			}
+		}
+	}
+	for _, imp := range s.Imports {
+		if !seen[imp.Namespace] {
+			imp.Location = ""
+			imports = append(imports, imp)
+		}
+	}
+	return imports
+}
+
+// loader returns an encoder that loads the document at loc and the
+// documents it refers to, for Fetch, Import and Flatten.
+func loader(loc string, r *resolver.Resolver) *goEncoder {
+	ge := NewEncoder(ioutil.Discard).(*goEncoder)
+	if r != nil {
+		ge.resolver = r
+	}
+	ge.location = loc
+	return ge
+}
+
+// importParts loads the documents imported and included by d, and those
+// they import and include, at any depth, and merges them into d.
+// Relative locations are resolved against the location of the document
+// that refers to them. Each document is loaded once, which breaks
+// cycles.
+func (ge *goEncoder) importParts(d *wsdl.Definitions) error {
+	if ge.location != "" {
+		ge.importedSchemas[ge.location] = true
+	}
+	return ge.importDefinitions(d, d, ge.location)
+}
+
+// importDefinitions loads the documents imported by def, which is d or
+// a document imported by d located at base.
+func (ge *goEncoder) importDefinitions(d, def *wsdl.Definitions, base string) error {
+	for _, imp := range def.Imports {
+		if imp.Location == "" {
+			continue
+		}
+		loc := resolver.Resolve(base, imp.Location)
+		if ge.importedSchemas[loc] {
+			continue
+		}
+		ge.importedSchemas[loc] = true
+		imported, schema, err := ge.load(loc)
+		if err != nil {
+			return err
+		}
+		if schema != nil {
+			// schemas imported at root level
+			ge.unionSchemasData(d, schema)
+			err = ge.importSchemas(d, schema, loc)
+		} else {
+			mergeDefinitions(d, imported)
+			ge.unionSchemasData(d, &imported.Schema)
+			err = ge.importDefinitions(d, imported, loc)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return ge.importSchemas(d, &def.Schema, base)
+}
+
+// importSchemas loads the schemas imported and included by s, located
+// at base. Included schemas without target namespace take that of the
+// including schema, as chameleon includes.
+func (ge *goEncoder) importSchemas(d *wsdl.Definitions, s *wsdl.Schema, base string) error {
+	type ref struct {
+		location  string
+		namespace string // of chameleon includes
+	}
+	var refs []ref
+	for _, imp := range s.Imports {
+		refs = append(refs, ref{location: imp.Location})
+	}
+	for _, inc := range s.Includes {
+		ns := inc.TargetNamespace
+		if ns == "" {
+			ns = s.TargetNamespace
+		}
+		refs = append(refs, ref{inc.Location, ns})
+	}
+	for _, r := range refs {
+		if r.location == "" {
+			continue
+		}
+		loc := resolver.Resolve(base, r.location)
+		if ge.importedSchemas[loc] {
+			continue
+		}
+		ge.importedSchemas[loc] = true
+		def, schema, err := ge.load(loc)
+		if err != nil {
+			return err
+		}
+		if schema == nil {
+			schema = &def.Schema
+		}
+		if schema.TargetNamespace == "" && r.namespace != "" {
+			chameleon(schema, r.namespace)
+		}
+		ge.unionSchemasData(d, schema)
+		if err = ge.importSchemas(d, schema, loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chameleon sets the target namespace of the schema s, and of its global
+// definitions, to ns. Names without prefix in the definitions, which
+// are in no namespace, are in ns too, as the default namespace.
+func chameleon(s *wsdl.Schema, ns string) {
+	s.TargetNamespace = ns
+	namespaces := map[string]string{"": ns}
+	declare := func(m map[string]string) map[string]string {
+		if m == nil {
+			return namespaces
+		}
+		if _, ok := m[""]; !ok {
+			m[""] = ns
+		}
+		return m
+	}
+	for _, v := range s.SimpleTypes {
+		if v.TargetNamespace == "" {
+			v.TargetNamespace = ns
+			v.Namespaces = declare(v.Namespaces)
+		}
+	}
+	for _, v := range s.ComplexTypes {
+		if v.TargetNamespace == "" {
+			v.TargetNamespace = ns
+			v.Namespaces = declare(v.Namespaces)
+		}
+	}
+	for _, v := range s.Elements {
+		if v.TargetNamespace == "" {
+			v.TargetNamespace = ns
+			v.Namespaces = declare(v.Namespaces)
+		}
+	}
+	for _, v := range s.Groups {
+		if v.TargetNamespace == "" {
+			v.TargetNamespace = ns
+			v.Namespaces = declare(v.Namespaces)
+		}
+	}
+	for _, v := range s.AttributeGroups {
+		if v.TargetNamespace == "" {
+			v.TargetNamespace = ns
+			v.Namespaces = declare(v.Namespaces)
+		}
+	}
+	for _, v := range s.Includes {
+		if v.TargetNamespace == "" {
+			v.TargetNamespace = ns
+		}
+	}
+}
+
+// mergeDefinitions adds the messages, operations and ports of the
+// imported WSDL document def to d.
+func mergeDefinitions(d, def *wsdl.Definitions) {
+	if d.Namespaces == nil {
+		d.Namespaces = make(map[string]string)
+	}
+	for k, v := range def.Namespaces {
+		if _, exists := d.Namespaces[k]; !exists {
+			d.Namespaces[k] = v
+		}
+	}
+	if d.TargetNamespace == "" {
+		d.TargetNamespace = def.TargetNamespace
+	}
+	d.Messages = append(d.Messages, def.Messages...)
+	if d.PortType.Name == "" {
+		d.PortType.Name = def.PortType.Name
+	}
+	d.PortType.Operations = append(d.PortType.Operations, def.PortType.Operations...)
+	if d.Binding.Name == "" {
+		d.Binding.Name = def.Binding.Name
+		d.Binding.Type = def.Binding.Type
+	}
+	if d.Binding.BindingType == nil {
+		d.Binding.BindingType = def.Binding.BindingType
+	}
+	d.Binding.Operations = append(d.Binding.Operations, def.Binding.Operations...)
+	if d.Service.Name == "" {
+		d.Service.Name = def.Service.Name
+	}
+	if d.Service.Doc == "" && len(d.Service.Docs) == 0 {
+		d.Service.Doc = def.Service.Doc
+		d.Service.Docs = def.Service.Docs
+	}
+	d.Service.Ports = append(d.Service.Ports, def.Service.Ports...)
+}
+
+func (ge *goEncoder) unionSchemasData(d *wsdl.Definitions, s *wsdl.Schema) {
+	if d.Namespaces == nil {
+		d.Namespaces = make(map[string]string)
+	}
+	for ns := range s.Namespaces {
+		if _, exists := d.Namespaces[ns]; !exists {
+			d.Namespaces[ns] = s.Namespaces[ns]
+		}
+	}
+	for _, ct := range s.ComplexTypes {
+		if ct.TargetNamespace == "" {
+			ct.TargetNamespace = s.TargetNamespace
+		}
+	}
+	for _, st := range s.SimpleTypes {
+		if st.TargetNamespace == "" {
+			st.TargetNamespace = s.TargetNamespace
+		}
+	}
+	d.Schema.ComplexTypes = append(d.Schema.ComplexTypes, s.ComplexTypes...)
+	d.Schema.SimpleTypes = append(d.Schema.SimpleTypes, s.SimpleTypes...)
+	d.Schema.Elements = append(d.Schema.Elements, s.Elements...)
+	d.Schema.Groups = append(d.Schema.Groups, s.Groups...)
+	d.Schema.AttributeGroups = append(d.Schema.AttributeGroups, s.AttributeGroups...)
+}
+
+// load loads the document at loc, which is either a WSDL document or a
+// schema.
+func (ge *goEncoder) load(loc string) (*wsdl.Definitions, *wsdl.Schema, error) {
+	b, err := ge.fetch(loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	dec := wsdl.NewDecoder(bytes.NewReader(b))
+	dec.Name = loc
+	return dec.DecodeDocument()
+}
+
+// fetch returns the content of the document at loc, read by the
+// resolver, and passes it to the loaded hook, if any.
+func (ge *goEncoder) fetch(loc string) ([]byte, error) {
+	b, err := ge.resolver.Read(loc)
+	if err != nil {
+		return nil, err
+	}
+	if ge.loaded != nil {
+		err = ge.loaded(loc, b)
+	}
+	return b, err
+}
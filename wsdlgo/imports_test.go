@@ -0,0 +1,189 @@
+package wsdlgo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fiorix/wsdl2go/catalog"
+	"github.com/fiorix/wsdl2go/resolver"
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+func TestImportParts(t *testing.T) {
+	d := LoadDefinition(t, "imports/root.wsdl", nil)
+	ge := NewEncoder(&bytes.Buffer{}).(*goEncoder)
+	ge.SetLocation("testdata/imports/root.wsdl")
+	if err := ge.importParts(d); err != nil {
+		t.Fatal(err)
+	}
+	if d.PortType.Name != "OrdersPortType" || len(d.Messages) != 2 {
+		t.Errorf("imported definitions are not merged: %#v", d.PortType)
+	}
+	// types.xsd is imported back by units.xsd, and loaded once
+	namespaces := make(map[string]string)
+	for _, st := range d.Schema.SimpleTypes {
+		if _, dup := namespaces[st.Name]; dup {
+			t.Errorf("simple type %q is loaded twice", st.Name)
+		}
+		namespaces[st.Name] = st.TargetNamespace
+	}
+	want := map[string]string{
+		"Code":     "urn:types", // chameleon include
+		"Quantity": "urn:units",
+	}
+	for name, ns := range want {
+		if namespaces[name] != ns {
+			t.Errorf("simple type %q: want namespace %q, have %q", name, ns, namespaces[name])
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	d, err := Flatten("testdata/imports/root.wsdl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flat bytes.Buffer
+	if err = wsdl.Marshal(&flat, d); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(flat.Bytes(), []byte("schemaLocation")) || bytes.Contains(flat.Bytes(), []byte("<wsdl:import")) {
+		t.Errorf("flattened document refers to other documents:\n%s", flat.Bytes())
+	}
+	// the flattened document generates the same code as the original
+	d, err = wsdl.Unmarshal(&flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have bytes.Buffer
+	if err = NewEncoder(&have).Encode(d); err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile("testdata/imports.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have.Bytes(), want) {
+		err := Diff("_diff", "go", want, have.Bytes())
+		t.Errorf("flattened document != imports.golden: %v\ngenerated:\n%s\n", err, have.Bytes())
+	}
+}
+
+func TestFetch(t *testing.T) {
+	c, err := catalog.Load("testdata/imports/catalog.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locs []string
+	err = Fetch("http://vendor.example.com/contracts/root.wsdl", &resolver.Resolver{Catalog: c}, func(loc string, data []byte) error {
+		if len(data) == 0 {
+			t.Errorf("%s is empty", loc)
+		}
+		locs = append(locs, loc)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"http://vendor.example.com/contracts/root.wsdl",
+		"http://vendor.example.com/contracts/parts/orders.wsdl",
+		"http://vendor.example.com/contracts/schemas/types.xsd",
+		"http://vendor.example.com/contracts/schemas/units/units.xsd",
+		"http://vendor.example.com/contracts/schemas/common.xsd",
+	}
+	if !reflect.DeepEqual(locs, want) {
+		t.Errorf("want %q, have %q", want, locs)
+	}
+}
+
+// authTransport adds credentials to the requests to host.
+type authTransport struct {
+	host string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.host {
+		req = req.Clone(req.Context())
+		req.SetBasicAuth("user", "secret")
+		req.Header.Set("X-Api-Key", "key")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestImportPartsClient(t *testing.T) {
+	third := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("X-Api-Key") != "" {
+			t.Errorf("credentials sent to third party host: %v", r.Header)
+		}
+		fmt.Fprint(w, `<schema xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:common">
+  <simpleType name="Code"><restriction base="string"/></simpleType>
+</schema>`)
+	}))
+	defer third.Close()
+	docs := map[string]string{
+		"wsdl": `<definitions xmlns="http://schemas.xmlsoap.org/wsdl/" targetNamespace="urn:svc">
+  <types>
+    <schema xmlns="http://www.w3.org/2001/XMLSchema">
+      <import namespace="urn:svc" schemaLocation="Service?xsd=1"/>
+    </schema>
+  </types>
+</definitions>`,
+		"xsd=1": `<schema xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:svc">
+  <import namespace="urn:svc" schemaLocation="?xsd=2"/>
+  <import namespace="urn:common" schemaLocation="` + third.URL + `/common.xsd"/>
+  <simpleType name="First"><restriction base="string"/></simpleType>
+</schema>`,
+		"xsd=2": `<schema xmlns="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:svc">
+  <simpleType name="Second"><restriction base="string"/></simpleType>
+</schema>`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "secret" || r.Header.Get("X-Api-Key") != "key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		doc, ok := docs[r.URL.RawQuery]
+		if r.URL.Path != "/ctx/Service" || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, doc)
+	}))
+	defer srv.Close()
+
+	loc := srv.URL + "/ctx/Service?wsdl"
+	ge := NewEncoder(&bytes.Buffer{}).(*goEncoder)
+	ge.SetLocation(loc)
+	if _, _, err := ge.load(loc); err == nil {
+		t.Fatal("unauthorized request succeeded")
+	}
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ge.SetClient(&http.Client{Transport: &authTransport{u.Host}, Timeout: 5 * time.Second})
+	d, _, err := ge.load(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ge.importParts(d); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, st := range d.Schema.SimpleTypes {
+		names = append(names, st.Name)
+	}
+	want := []string{"First", "Second", "Code"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("want %q, have %q", want, names)
+	}
+}
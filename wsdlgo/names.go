@@ -0,0 +1,225 @@
+package wsdlgo
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fiorix/wsdl2go/model"
+	"github.com/fiorix/wsdl2go/wsdl"
+)
+
+// enterScope makes namespaces and target the scope of the names that
+// are resolved, and returns a function that restores the previous scope.
+// Definitions without namespace declarations or target namespace, such
+// as local elements, inherit them from the enclosing definition.
+func (ge *goEncoder) enterScope(namespaces map[string]string, target string) func() {
+	prev := ge.scope
+	if namespaces != nil {
+		ge.scope.Namespaces = namespaces
+	}
+	if target != "" {
+		ge.scope.Target = target
+	}
+	return func() { ge.scope = prev }
+}
+
+// resolve returns the name of the definition of kind k that a reference
+// to name resolves to: name if it is defined, or else, as many documents
+// use prefixes loosely, the definition with the same local name if there
+// is only one, with a warning. Otherwise the reference is unresolved.
+func (ge *goEncoder) resolve(k model.Kind, name model.Name, defined func(model.Name) bool) (model.Name, bool) {
+	if defined(name) {
+		return name, true
+	}
+	var candidates []model.Name
+	for _, n := range ge.model.Named(k, name.Local) {
+		if defined(n) {
+			candidates = append(candidates, n)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return name, false
+	case 1:
+		ge.warn("%s %s is not defined, resolved to %s by local name", k, name, candidates[0])
+		return candidates[0], true
+	}
+	names := make([]string, len(candidates))
+	for i, n := range candidates {
+		names[i] = n.String()
+	}
+	sort.Strings(names)
+	ge.warn("%s %s is not defined, and its local name is ambiguous: %s", k, name, strings.Join(names, ", "))
+	return name, false
+}
+
+func (ge *goEncoder) lookupSimpleType(s string) (model.Name, *wsdl.SimpleType, bool) {
+	name, ok := ge.resolve(model.SimpleTypeKind, ge.scope.Name(s), func(n model.Name) bool {
+		return ge.model.SimpleType(n) != nil
+	})
+	return name, ge.model.SimpleType(name), ok
+}
+
+// lookupComplexType looks up the complex type named s, which may be the
+// anonymous type of a global element.
+func (ge *goEncoder) lookupComplexType(s string) (model.Name, *wsdl.ComplexType, bool) {
+	name, ok := ge.resolve(model.ComplexTypeKind, ge.scope.Name(s), func(n model.Name) bool {
+		return ge.model.ComplexType(n) != nil
+	})
+	return name, ge.model.ComplexType(name), ok
+}
+
+// lookupElement looks up the global element named s.
+func (ge *goEncoder) lookupElement(s string) (model.Name, *wsdl.Element, bool) {
+	name, ok := ge.resolve(model.ElementKind, ge.scope.Name(s), func(n model.Name) bool {
+		return ge.model.Elements[n] != nil
+	})
+	if !ok {
+		return name, nil, false
+	}
+	return name, ge.model.Elements[name].WSDL, true
+}
+
+func (ge *goEncoder) lookupGroup(s string) (*wsdl.Group, bool) {
+	name, ok := ge.resolve(model.GroupKind, ge.scope.Name(s), func(n model.Name) bool {
+		return ge.model.Groups[n] != nil
+	})
+	return ge.model.Groups[name], ok
+}
+
+func (ge *goEncoder) lookupAttributeGroup(s string) (*wsdl.AttributeGroup, bool) {
+	name, ok := ge.resolve(model.AttributeGroupKind, ge.scope.Name(s), func(n model.Name) bool {
+		return ge.model.AttributeGroups[n] != nil
+	})
+	return ge.model.AttributeGroups[name], ok
+}
+
+// sortedSimpleTypes returns the names of the simple types sorted by
+// local name, then namespace.
+func (ge *goEncoder) sortedSimpleTypes() []model.Name {
+	var names []model.Name
+	for n, t := range ge.model.Types {
+		if t.Simple != nil {
+			names = append(names, n)
+		}
+	}
+	sortLocalNames(names)
+	return names
+}
+
+// sortedComplexTypes returns the names of the complex types, and of the
+// global elements with an anonymous type, which are declared as types of
+// the name of the element, sorted by local name, then namespace.
+func (ge *goEncoder) sortedComplexTypes() []model.Name {
+	var names []model.Name
+	for n, t := range ge.model.Types {
+		if t.Complex != nil {
+			names = append(names, n)
+		}
+	}
+	for n, el := range ge.model.Elements {
+		if t, ok := ge.model.Types[n]; el.Anonymous() && (!ok || t.Complex == nil) {
+			names = append(names, n)
+		}
+	}
+	sortLocalNames(names)
+	return names
+}
+
+// assignGoNames assigns the Go names of the simple and complex types.
+// Types whose names collide, because they have the same local name in
+// different namespaces, are named deterministically: the type in the
+// target namespace of the document keeps its name, or else the first in
+// the order of namespaces, and the others are prefixed with the prefix
+// of their namespace, or else suffixed with a number.
+func (ge *goEncoder) assignGoNames() {
+	bySymbol := make(map[string][]model.Name)
+	for _, n := range ge.sortedSimpleTypes() {
+		bySymbol[goSymbol(n.Local)] = append(bySymbol[goSymbol(n.Local)], n)
+	}
+	for _, n := range ge.sortedComplexTypes() {
+		if ge.model.SimpleType(n) != nil {
+			continue
+		}
+		bySymbol[goSymbol(n.Local)] = append(bySymbol[goSymbol(n.Local)], n)
+	}
+	taken := make(map[string]bool)
+	var symbols []string
+	for sym := range bySymbol {
+		taken[sym] = true
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	ge.goNames = make(map[model.Name]string)
+	for _, sym := range symbols {
+		names := bySymbol[sym]
+		sort.Slice(names, func(i, j int) bool {
+			a, b := names[i].Space == ge.targetNamespace, names[j].Space == ge.targetNamespace
+			if a != b {
+				return a
+			}
+			if names[i].Space != names[j].Space {
+				return names[i].Space < names[j].Space
+			}
+			return names[i].Local < names[j].Local
+		})
+		ge.goNames[names[0]] = sym
+		for _, n := range names[1:] {
+			name := sym
+			if prefix := ge.prefix(n.Space); prefix != "" {
+				name = goSymbol(prefix) + sym
+			}
+			for i := 2; taken[name]; i++ {
+				name = sym + strconv.Itoa(i)
+			}
+			taken[name] = true
+			ge.goNames[n] = name
+		}
+	}
+}
+
+// prefix returns the first of the prefixes declared for the namespace
+// ns in the document, or an empty string.
+func (ge *goEncoder) prefix(ns string) string {
+	var prefixes []string
+	for p, v := range ge.scope.Document {
+		if v == ns && p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+	if len(prefixes) == 0 {
+		return ""
+	}
+	sort.Strings(prefixes)
+	return prefixes[0]
+}
+
+// goName returns the Go name of the type named name.
+func (ge *goEncoder) goName(name model.Name) string {
+	if v, ok := ge.goNames[name]; ok {
+		return v
+	}
+	return goSymbol(name.Local)
+}
+
+// isGoType reports whether a simple or complex type generates the Go
+// type named name.
+func (ge *goEncoder) isGoType(name string) bool {
+	for _, v := range ge.goNames {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sortLocalNames sorts names by local name, then namespace.
+func sortLocalNames(names []model.Name) {
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Local != names[j].Local {
+			return names[i].Local < names[j].Local
+		}
+		return names[i].Space < names[j].Space
+	})
+}